@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/strfmt"
+)
+
+// initAdminContext loads the configuration and connects to the database.
+// Admin commands refuse to work on an outdated database schema.
+func initAdminContext() {
+	setting.NewConfigContext()
+
+	if err := models.InitEngine(); err != nil {
+		log.Fatalf("Failed to initialize ORM engine: %v", err)
+	}
+
+	if pending, err := models.Migrate(true); err != nil {
+		log.Fatalf("Can't check database version: %v", err)
+	} else if len(pending) > 0 {
+		log.Fatalf(`Database schema is outdated, run "%s migrate" first`, os.Args[0])
+	}
+}
+
+// requireArgs exits with the command's usage if "args" has less than "n" arguments.
+func requireArgs(args []string, n int, usage string) {
+	if len(args) < n {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s\n", os.Args[0], usage)
+		os.Exit(2)
+	}
+}
+
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatalf("Can't encode result: %v", err)
+	}
+	fmt.Println(string(data))
+}
+
+// runUser implements "wgplaner-api user show|delete <userID>".
+func runUser(args []string) {
+	const usage = "user show|delete <userID>"
+	requireArgs(args, 2, usage)
+	initAdminContext()
+
+	u, err := models.GetUserByUID(args[1])
+	if models.IsErrUserNotExist(err) {
+		log.Fatalf("User %q does not exist", args[1])
+	} else if err != nil {
+		log.Fatalf("Can't get user: %v", err)
+	}
+
+	switch args[0] {
+	case "show":
+		printJSON(u)
+
+	case "delete":
+		groupUID := u.GroupUID
+		if err = models.DeleteUser(u); err != nil {
+			log.Fatalf("Can't delete user: %v", err)
+		}
+		if groupUID != "" {
+			if members, err := models.GetGroupMemberUIDs(groupUID); err == nil {
+				mailer.SendPushUpdateToUserIDs(members, mailer.PushUpdateGroupMemberLeft, []string{*u.UID})
+			}
+		}
+		fmt.Printf("Deleted user %q\n", *u.UID)
+
+	default:
+		requireArgs(nil, 1, usage)
+	}
+}
+
+// runGroup implements "wgplaner-api group show|delete|add-admin".
+func runGroup(args []string) {
+	const usage = "group show <groupUID> | group delete [--purge] <groupUID> | group add-admin <groupUID> <userID>"
+	requireArgs(args, 1, usage)
+
+	purge := false
+	if args[0] == "delete" {
+		flags := flag.NewFlagSet("group delete", flag.ExitOnError)
+		flags.BoolVar(&purge, "purge", false, "delete the group immediately (skip the grace period)")
+		flags.Parse(args[1:])
+		args = append(args[:1], flags.Args()...)
+	}
+
+	requireArgs(args, 2, usage)
+	initAdminContext()
+
+	groupUID := strfmt.UUID(args[1])
+	g, err := models.GetGroupByUID(groupUID)
+	if purge && models.IsErrGroupNotExist(err) {
+		// Groups in their grace period can be purged as well
+		g, err = models.GetDeletedGroupByUID(groupUID)
+	}
+	if models.IsErrGroupNotExist(err) || models.IsErrGroupInvalidUUID(err) {
+		log.Fatalf("Group %q does not exist", groupUID)
+	} else if err != nil {
+		log.Fatalf("Can't get group: %v", err)
+	}
+
+	switch args[0] {
+	case "show":
+		printJSON(g)
+
+	case "delete":
+		if purge {
+			err = models.PurgeGroup(g.UID)
+		} else {
+			err = models.DeleteGroup(g)
+		}
+		if err != nil {
+			log.Fatalf("Can't delete group: %v", err)
+		}
+		// The members of deleted groups were notified already
+		if g.DeletedAt.IsZero() {
+			mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupDeleted, []string{string(g.UID)})
+		}
+		fmt.Printf("Deleted group %q\n", g.UID)
+
+	case "add-admin":
+		requireArgs(args, 3, usage)
+		if err = g.AddAdmin(args[2]); models.IsErrGroupNotMember(err) {
+			log.Fatalf("User %q is not a member of the group", args[2])
+		} else if err != nil {
+			log.Fatalf("Can't add admin: %v", err)
+		}
+		mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupAdmins, []string{args[2]})
+		fmt.Printf("User %q is now an admin of group %q\n", args[2], g.UID)
+
+	default:
+		requireArgs(nil, 1, usage)
+	}
+}
+
+// runGroupCode implements "wgplaner-api group-code revoke <code>".
+func runGroupCode(args []string) {
+	const usage = "group-code revoke <code>"
+	requireArgs(args, 2, usage)
+	if args[0] != "revoke" {
+		requireArgs(nil, 1, usage)
+	}
+	initAdminContext()
+
+	if err := models.RevokeGroupCode(args[1]); models.IsErrGroupCodeNotExist(err) {
+		log.Fatalf("Group code %q does not exist", args[1])
+	} else if err != nil {
+		log.Fatalf("Can't revoke group code: %v", err)
+	}
+	fmt.Printf("Revoked group code %q\n", args[1])
+}
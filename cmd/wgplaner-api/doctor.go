@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+)
+
+type check struct {
+	name string
+	run  func() error
+}
+
+// runChecks runs all checks and prints their result. It returns false if a check failed.
+func runChecks(checks []check) bool {
+	ok := true
+	for _, c := range checks {
+		if err := c.run(); err != nil {
+			fmt.Printf("[FAIL] %s:\n       %s\n", c.name, strings.Replace(err.Error(), "\n", "\n       ", -1))
+			ok = false
+		} else {
+			fmt.Printf("[ OK ] %s\n", c.name)
+		}
+	}
+	return ok
+}
+
+func checkConfigFile() error {
+	return setting.LoadConfig()
+}
+
+func checkConfigValues() error {
+	if problems := setting.CheckConfiguration(); len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// runConfig implements "wgplaner-api config check".
+func runConfig(args []string) {
+	requireArgs(args, 1, "config check")
+	if args[0] != "check" {
+		requireArgs(nil, 1, "config check")
+	}
+
+	fmt.Printf("Checking %s\n", setting.ConfigPath())
+	if !runChecks([]check{{"config file", checkConfigFile}}) ||
+		!runChecks([]check{{"config values", checkConfigValues}}) {
+		os.Exit(1)
+	}
+}
+
+// checkDataDir makes sure that "dir" is a writable directory.
+func checkDataDir(dir string) error {
+	dir = path.Join(setting.AppWorkPath, dir)
+	if stat, err := os.Stat(dir); err != nil {
+		return err
+	} else if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	f, err := ioutil.TempFile(dir, ".doctor")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %v", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func checkDataDirs() error {
+	var e []string
+	for _, dir := range []string{setting.AppConfig.Data.UserImageDir, setting.AppConfig.Data.GroupImageDir} {
+		if err := checkDataDir(dir); err != nil {
+			e = append(e, err.Error())
+		}
+	}
+	if len(e) > 0 {
+		return errors.New(strings.Join(e, "\n"))
+	}
+	return nil
+}
+
+func checkDefaultImages() error {
+	var e []string
+	for _, file := range []string{setting.AppConfig.Data.UserImageDefault, setting.AppConfig.Data.GroupImageDefault} {
+		if stat, err := os.Stat(path.Join(setting.AppWorkPath, file)); err != nil {
+			e = append(e, err.Error())
+		} else if stat.IsDir() {
+			e = append(e, fmt.Sprintf("%s is a directory", file))
+		}
+	}
+	if len(e) > 0 {
+		return errors.New(strings.Join(e, "\n"))
+	}
+	return nil
+}
+
+func checkDatabase() error {
+	if err := models.InitEngine(); err != nil {
+		return err
+	}
+
+	if pending, err := models.Migrate(true); err != nil {
+		return err
+	} else if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations, run \"%s migrate\"", len(pending), os.Args[0])
+	}
+	return nil
+}
+
+func checkFirebase() error {
+	if !setting.AppConfig.Auth.IgnoreFirebase {
+		if _, err := setting.NewFirebaseApp(); err != nil {
+			return fmt.Errorf("firebase app: %v", err)
+		}
+	}
+	if _, err := setting.NewTokenVerifier(); err != nil {
+		return fmt.Errorf("token verifier: %v", err)
+	}
+	return nil
+}
+
+// runDoctor implements "wgplaner-api doctor".
+func runDoctor(args []string) {
+	fmt.Printf("Checking %s\n", setting.ConfigPath())
+
+	// All other checks need a loaded configuration
+	if !runChecks([]check{{"config file", checkConfigFile}}) {
+		os.Exit(1)
+	}
+
+	ok := runChecks([]check{
+		{"config values", checkConfigValues},
+		{"data directories", checkDataDirs},
+		{"default images", checkDefaultImages},
+		{"database", checkDatabase},
+		{"firebase credentials", checkFirebase},
+	})
+	if !ok {
+		os.Exit(1)
+	}
+}
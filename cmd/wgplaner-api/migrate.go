@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+)
+
+// runMigrate runs the pending database migrations ("wgplaner-api migrate").
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the pending migrations")
+	flags.Parse(args)
+
+	setting.NewConfigContext()
+
+	if err := models.InitEngine(); err != nil {
+		log.Fatalf("Failed to initialize ORM engine: %v", err)
+	}
+
+	migrations, err := models.Migrate(*dryRun)
+	for _, m := range migrations {
+		fmt.Println(m)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	switch {
+	case len(migrations) == 0:
+		fmt.Println("Database is up to date")
+	case *dryRun:
+		fmt.Printf("%d pending migrations\n", len(migrations))
+	default:
+		fmt.Printf("Executed %d migrations\n", len(migrations))
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/wgplaner/wg_planer_server/controllers"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+	"github.com/wgplaner/wg_planer_server/modules/notification"
+	"github.com/wgplaner/wg_planer_server/modules/scheduler"
 	"github.com/wgplaner/wg_planer_server/modules/setting"
 	"github.com/wgplaner/wg_planer_server/restapi"
 	"github.com/wgplaner/wg_planer_server/restapi/operations"
@@ -16,7 +22,58 @@ func init() {
 	setting.AppVersion = Version
 }
 
+type command struct {
+	name  string
+	usage string
+	run   func(args []string)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "serve                           Start the API server (default)", runServe},
+		{"migrate", "migrate [--dry-run]             Run pending database migrations", runMigrate},
+		{"user", "user show|delete <userID>       Show or delete a user", runUser},
+		{"group", "group show|delete <groupUID>    Show or delete a group (delete --purge skips the grace period)\n" +
+			"  group add-admin <groupUID> <userID>\n" +
+			"                                  Make a member admin of the group", runGroup},
+		{"group-code", "group-code revoke <code>        Revoke a group code", runGroupCode},
+		{"config", "config check                    Validate the configuration file", runConfig},
+		{"doctor", "doctor                          Check configuration, data directories, database and Firebase", runDoctor},
+	}
+}
+
 func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	for _, c := range commands {
+		if c.name == name {
+			c.run(args)
+			return
+		}
+	}
+
+	if name != "help" {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	}
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "WGPlaner API server %s\n\nUsage: %s <command> [arguments]\n\nCommands:\n",
+		Version, os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", c.usage)
+	}
+}
+
+// runServe starts the API server ("wgplaner-api serve").
+func runServe(args []string) {
 	var (
 		api    = operations.NewWgplanerAPI(setting.LoadSwaggerSpec(restapi.SwaggerJSON))
 		server = restapi.NewServer(api)
@@ -28,6 +85,23 @@ func main() {
 	controllers.InitializeControllers(api)
 
 	server.Port = setting.AppConfig.Server.Port
+	server.SetHandler(controllers.SetupGlobalMiddleware(api.Serve(nil)))
+
+	// Send email notifications in the background
+	if setting.AppConfig.Mail.Enabled {
+		mailer.StartMailQueue(setting.AppConfig.Mail.QueueSize)
+		defer mailer.StopMailQueue()
+	}
+
+	// Deliver push updates of the outbox
+	notification.Start(notification.NewNotifier(), notification.NewOptions())
+	defer notification.Stop()
+
+	// Add items of recurring templates
+	if setting.AppConfig.Scheduler.Enabled {
+		scheduler.Start(setting.AppConfig.Scheduler.IntervalDuration)
+		defer scheduler.Stop()
+	}
 
 	// serve API
 	if err := server.Serve(); err != nil {
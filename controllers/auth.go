@@ -2,25 +2,115 @@ package controllers
 
 import (
 	"net/http"
+	"sync"
+	"time"
 
-	"github.com/go-openapi/errors"
-	"github.com/op/go-logging"
 	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/auth"
 	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/errors"
+	"github.com/op/go-logging"
 )
 
 var authLog = logging.MustGetLogger("Auth")
 
+// getAuthUserID takes the value of the "Authorization" header and returns the user id.
+// The value has to be a signed Firebase ID token (optionally prefixed with "Bearer ").
+// The user id is taken from the token's "sub" claim.
+// Only if "insecure_raw_uid" is set, the header value is used as the user id directly.
+func getAuthUserID(headerValue string) (string, error) {
+	token := auth.ExtractToken(headerValue)
+
+	if setting.AppConfig.Auth.InsecureRawUID {
+		return token, nil
+	}
+
+	if setting.TokenVerifier == nil {
+		authLog.Critical(`No ID token verifier configured`)
+		return "", errors.New(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if uid, ok := verifiedTokens.get(token); ok {
+		return uid, nil
+	}
+
+	claims, err := setting.TokenVerifier.Verify(token)
+	if err != nil {
+		authLog.Debugf(`Invalid ID token: %s`, err.Error())
+		return "", errors.Unauthenticated("invalid credentials (ID token)")
+	}
+
+	verifiedTokens.put(token, claims.Subject, time.Unix(claims.ExpiresAt, 0))
+	return claims.Subject, nil
+}
+
+// maxVerifiedTokens is the maximum number of cached ID tokens.
+const maxVerifiedTokens = 10000
+
+// verifiedTokens caches the user ids of verified ID tokens until they expire.
+// The rate limiter and the API both authenticate every request, so each
+// token only has to be verified once.
+var verifiedTokens = &tokenCache{tokens: make(map[string]verifiedToken)}
+
+type verifiedToken struct {
+	uid       string
+	expiresAt time.Time
+}
+
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]verifiedToken
+}
+
+// get returns the user id of the token if it was verified and didn't expire.
+func (c *tokenCache) get(token string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.tokens[token]
+	if !ok {
+		return "", false
+	} else if !time.Now().Before(t.expiresAt) {
+		delete(c.tokens, token)
+		return "", false
+	}
+	return t.uid, true
+}
+
+// put adds a verified token. Expired tokens are removed if the cache is full.
+func (c *tokenCache) put(token, uid string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.tokens) >= maxVerifiedTokens {
+		now := time.Now()
+		for k, t := range c.tokens {
+			if !now.Before(t.expiresAt) {
+				delete(c.tokens, k)
+			}
+		}
+		if len(c.tokens) >= maxVerifiedTokens {
+			c.tokens = make(map[string]verifiedToken)
+		}
+	}
+	c.tokens[token] = verifiedToken{uid: uid, expiresAt: expiresAt}
+}
+
 // userIDAuth takes an auth token and validates that token against the database.
 // It returns the user if the auth token is valid and an error otherwise.
 func userIDAuth(token string) (*models.User, error) {
-	authLog.Debugf(`Check userID authorization for user id "%s"`, token)
+	uid, err := getAuthUserID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	authLog.Debugf(`Check userID authorization for user id "%s"`, uid)
 
 	var u *models.User
-	var err error
 
-	if u, err = models.GetUserByUID(token); models.IsErrUserNotExist(err) {
-		authLog.Debugf(`Unauthorized database user "%s"`, token)
+	if u, err = models.GetUserByUID(uid); models.IsErrUserNotExist(err) {
+		authLog.Debugf(`Unauthorized database user "%s"`, uid)
 		return nil, errors.Unauthenticated("invalid credentials (wgplaner account)")
 
 	} else if err != nil {
@@ -34,25 +124,35 @@ func userIDAuth(token string) (*models.User, error) {
 // firebaseIDAuth takes an auth token and validates that token against firebase.
 // It returns a user with only its ID set if the auth token is valid and an error otherwise.
 func firebaseIDAuth(token string) (*models.User, error) {
-	authLog.Debugf(`Check firebaseId authorization for user id "%s"`, token)
+	uid, err := getAuthUserID(token)
+	if err != nil {
+		return nil, err
+	}
 
-	if !models.IsValidUserIDFormat(token) {
+	authLog.Debugf(`Check firebaseId authorization for user id "%s"`, uid)
+
+	if !models.IsValidUserIDFormat(uid) {
 		return nil, errors.Unauthenticated("invalid credentials (format)")
 	}
 
-	u := &models.User{UID: &token}
+	u := &models.User{UID: &uid}
+
+	// A verified ID token was issued by firebase for this account.
+	if !setting.AppConfig.Auth.InsecureRawUID {
+		return u, nil
+	}
 
 	if setting.AppConfig.Auth.IgnoreFirebase {
 		authLog.Debugf(`Ignore firebase auth`)
 		return u, nil
 	}
 
-	if isRegistered, err := models.IsUserOnFirebase(token); err != nil {
+	if isRegistered, err := models.IsUserOnFirebase(uid); err != nil {
 		authLog.Error(`DB error with IsUserOnFirebase`, err.Error())
 		return nil, errors.New(http.StatusInternalServerError, "Internal Server Error")
 
 	} else if !isRegistered {
-		authLog.Debugf(`Unauthorized firebase user "%s"`, token)
+		authLog.Debugf(`Unauthorized firebase user "%s"`, uid)
 		return nil, errors.Unauthenticated("invalid credentials (firebase account)")
 	}
 
@@ -2,14 +2,47 @@ package controllers
 
 import (
 	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
 	"github.com/wgplaner/wg_planer_server/restapi/operations/bill"
 
 	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/op/go-logging"
 )
 
 var billLog = logging.MustGetLogger("Bill")
 
+// getBillOrError returns the bill of the given group or an error response.
+func getBillOrError(groupUID, billUID strfmt.UUID) (*models.Bill, middleware.Responder) {
+	b, err := models.GetBillByUIDs(groupUID, billUID)
+	if models.IsErrBillNotExist(err) {
+		billLog.Debugf(`Can't find bill "%s" of group "%s"`, billUID, groupUID)
+		return nil, newNotFoundResponse("Bill not found on server.")
+
+	} else if err != nil {
+		billLog.Critical(`Database Error!`, err)
+		return nil, newInternalServerError("Internal Database Error")
+	}
+	return b, nil
+}
+
+// getBillErrorResponse converts errors of bill state transitions into responses.
+func getBillErrorResponse(err error) middleware.Responder {
+	if models.IsErrBillInvalidTransition(err) ||
+		models.IsErrBillNotRecipient(err) ||
+		models.IsErrBillAlreadyPaid(err) {
+		billLog.Debugf(err.Error())
+		return NewBadRequest(err.Error())
+	} else if models.IsErrVersionMismatch(err) {
+		billLog.Debugf(err.Error())
+		return newConflictResponse(err.Error())
+	}
+
+	billLog.Critical(`Database Error!`, err)
+	return newInternalServerError("Internal Database Error")
+}
+
 // getBillList returns a list of bills for the requested group.
 func getBillList(params bill.GetBillListParams, principal *models.User) middleware.Responder {
 	groupLog.Debugf(`User %q gets bills for group "%s"`, *principal.UID, principal.GroupUID)
@@ -48,10 +81,118 @@ func createBill(params bill.CreateBillParams, principal *models.User) middleware
 	}
 
 	b, err := models.CreateBillForUser(principal, params.Body)
-	if err != nil {
+	if models.IsErrBillNoItems(err) {
+		billLog.Debugf(err.Error())
+		return NewBadRequest(err.Error())
+
+	} else if err != nil {
 		billLog.Critical("Can't create bill for user", *principal.UID, err)
 		return newInternalServerError("Internal Server Error")
 	}
 
 	return bill.NewCreateBillOK().WithPayload(b)
 }
+
+// getGroupBalances returns the balances of the requested group.
+func getGroupBalances(params bill.GetGroupBalancesParams, principal *models.User) middleware.Responder {
+	billLog.Debugf(`User %q gets balances for group "%s"`, *principal.UID, principal.GroupUID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	balances, err := models.GetGroupBalances(g)
+	if err != nil {
+		billLog.Critical("Can't get balances for group", g.UID, err)
+		return newInternalServerError("Internal Server Error")
+	}
+
+	return bill.NewGetGroupBalancesOK().WithPayload(balances)
+}
+
+// sendBill sends a draft to group members.
+func sendBill(params bill.SendBillParams, principal *models.User) middleware.Responder {
+	billLog.Debugf(`User %q sends bill "%s"`, *principal.UID, params.BillUID)
+
+	var g *models.Group
+	var b *models.Bill
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if b, errResp = getBillOrError(g.UID, params.BillUID); errResp != nil {
+		return errResp
+	}
+	if swag.StringValue(b.CreatedBy) != *principal.UID {
+		return NewUnauthorizedResponse("Only the creator of a bill can send it")
+	}
+
+	if err := b.Send(params.Body); err != nil {
+		return getBillErrorResponse(err)
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushBillSent, []string{string(b.UID)})
+	if err := mailer.SendBillSentMails(b, g); err != nil {
+		billLog.Errorf(`Can't send mails for bill "%s": %s`, b.UID, err)
+	}
+
+	return bill.NewSendBillOK().WithPayload(b)
+}
+
+// payBill marks the share of a bill's recipient as paid.
+func payBill(params bill.PayBillParams, principal *models.User) middleware.Responder {
+	billLog.Debugf(`User %q marks bill "%s" as paid by %q`, *principal.UID, params.BillUID, params.UserID)
+
+	var g *models.Group
+	var b *models.Bill
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if b, errResp = getBillOrError(g.UID, params.BillUID); errResp != nil {
+		return errResp
+	}
+	if swag.StringValue(b.CreatedBy) != *principal.UID && params.UserID != *principal.UID {
+		return NewUnauthorizedResponse("Only the creator of a bill or the recipient can mark a share as paid")
+	}
+
+	if err := b.Pay(params.UserID); err != nil {
+		return getBillErrorResponse(err)
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushBillPayment, []string{string(b.UID), params.UserID})
+
+	return bill.NewPayBillOK().WithPayload(b)
+}
+
+// cancelBill cancels a bill and releases its items.
+func cancelBill(params bill.CancelBillParams, principal *models.User) middleware.Responder {
+	billLog.Debugf(`User %q cancels bill "%s"`, *principal.UID, params.BillUID)
+
+	var g *models.Group
+	var b *models.Bill
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if b, errResp = getBillOrError(g.UID, params.BillUID); errResp != nil {
+		return errResp
+	}
+	if swag.StringValue(b.CreatedBy) != *principal.UID {
+		return NewUnauthorizedResponse("Only the creator of a bill can cancel it")
+	}
+
+	if err := b.Cancel(); err != nil {
+		return getBillErrorResponse(err)
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushBillCancelled, []string{string(b.UID)})
+
+	return bill.NewCancelBillOK().WithPayload(b)
+}
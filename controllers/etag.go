@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// etag returns the entity tag of a resource version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatches returns true if the If-Match header is missing or contains
+// the entity tag of the version (or "*"). Weak tags never match.
+func ifMatches(header *string, version int64) bool {
+	if header == nil || strings.TrimSpace(*header) == "" {
+		return true
+	}
+	for _, tag := range strings.Split(*header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion returns the version that an update has to match: the current
+// version if an If-Match header was sent and 0 (any version) otherwise.
+func ifMatchVersion(header *string, current int64) int64 {
+	if header == nil || strings.TrimSpace(*header) == "" {
+		return 0
+	}
+	return current
+}
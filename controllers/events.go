@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/eventbus"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/events"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/op/go-logging"
+)
+
+var eventsLog = logging.MustGetLogger("Events")
+
+// eventStreamRetry is the reconnection time (ms) suggested to clients.
+const eventStreamRetry = 3000
+
+func getEvents(params events.GetEventsParams, principal *models.User) middleware.Responder {
+	eventsLog.Debugf(`Stream events for user "%s"`, *principal.UID)
+
+	var lastEventID int64
+	if params.LastEventID != nil {
+		lastEventID = *params.LastEventID
+	}
+
+	sub, replay, complete := eventbus.Default.Subscribe(*principal.UID, lastEventID)
+
+	return &eventStream{
+		sub:    sub,
+		replay: replay,
+		resync: !complete,
+		done:   params.HTTPRequest.Context().Done(),
+	}
+}
+
+// eventStream writes the events of a subscription as server-sent events
+// until the client disconnects.
+type eventStream struct {
+	sub    *eventbus.Subscription
+	replay []*eventbus.Event
+	resync bool
+	done   <-chan struct{}
+}
+
+func (s *eventStream) WriteResponse(rw http.ResponseWriter, _ runtime.Producer) {
+	defer s.sub.Close()
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	rw.WriteHeader(http.StatusOK)
+
+	flusher, _ := rw.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if _, err := fmt.Fprintf(rw, "retry: %d\n\n", eventStreamRetry); err != nil {
+		return
+	}
+
+	if s.resync {
+		// The client reloads all data, so the incomplete replay is skipped
+		// and the stream continues after the start of the subscription.
+		s.replay = []*eventbus.Event{{ID: s.sub.StartID, Type: eventbus.TypeResync}}
+	}
+	for _, e := range s.replay {
+		if err := writeEvent(rw, e); err != nil {
+			return
+		}
+	}
+	flush()
+
+	keepAlive := time.NewTicker(setting.AppConfig.Events.KeepAliveDuration)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+
+		case e, ok := <-s.sub.Events():
+			if !ok {
+				// Dropped because the client was too slow. It reconnects
+				// and resumes using the id of the last received event.
+				return
+			}
+			if err := writeEvent(rw, e); err != nil {
+				return
+			}
+
+		case <-keepAlive.C:
+			if _, err := io.WriteString(rw, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flush()
+	}
+}
+
+func writeEvent(w io.Writer, e *eventbus.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
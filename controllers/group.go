@@ -66,7 +66,7 @@ func getGroup(params group.GetGroupParams, principal *models.User) middleware.Re
 	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
 		return errResp
 	}
-	return group.NewGetGroupOK().WithPayload(g)
+	return group.NewGetGroupOK().WithETag(etag(g.Version)).WithPayload(g)
 }
 
 func getGroupImage(params group.GetGroupImageParams, principal *models.User) middleware.Responder {
@@ -99,36 +99,6 @@ func getGroupImage(params group.GetGroupImageParams, principal *models.User) mid
 
 }
 
-func createGroupCode(params group.CreateGroupCodeParams, principal *models.User) middleware.Responder {
-	groupLog.Debugf(`User %q generates code for group %q!`, *principal.UID, principal.GroupUID)
-
-	var (
-		c   *models.GroupCode
-		err error
-
-		groupUID = strfmt.UUID(principal.GroupUID)
-	)
-
-	if principal.GroupUID != groupUID {
-		return NewUnauthorizedResponse("Can't create group code for other groups")
-	}
-
-	// Group MUST exist or we have inconsistencies
-	if _, err = models.GetGroupByUID(groupUID); err != nil {
-		groupLog.Debugf(`Error validating group "%s": "%s"`, principal.GroupUID, err.Error())
-		return newInternalServerError("Internal Server Error")
-	}
-
-	// TODO: Check authorization for user in the group
-
-	if c, err = models.CreateGroupCode(groupUID); err != nil {
-		groupLog.Critical("Database error!", err)
-		return newInternalServerError("Internal Database Error")
-	}
-
-	return group.NewCreateGroupCodeOK().WithPayload(c)
-}
-
 func createGroup(params group.CreateGroupParams, principal *models.User) middleware.Responder {
 	groupLog.Debugf(`User %q starts creating a group`, *principal.UID)
 
@@ -141,15 +111,8 @@ func createGroup(params group.CreateGroupParams, principal *models.User) middlew
 	}
 
 	// Create new group
-	newGroupUID := strfmt.UUID(groupUID.String())
-
-	theUser := &models.User{
-		UID:      principal.UID,
-		GroupUID: newGroupUID,
-	}
-
 	theGroup := &models.Group{
-		UID:         newGroupUID,
+		UID:         strfmt.UUID(groupUID.String()),
 		Admins:      []string{*principal.UID},
 		DisplayName: params.Body.DisplayName,
 		Currency:    params.Body.Currency,
@@ -157,13 +120,8 @@ func createGroup(params group.CreateGroupParams, principal *models.User) middlew
 
 	// TODO: Check if user has already a group
 
-	if err = models.UpdateUserCols(theUser, "group_uid"); err != nil {
-		groupLog.Critical("Database error!", err)
-		return newInternalServerError("Internal Database Error")
-	}
-
-	// Insert new user into database
-	if err = models.CreateGroup(theGroup); err != nil {
+	// Insert new group into database and add the user
+	if err = models.CreateGroupForUser(theGroup, principal); err != nil {
 		groupLog.Critical("Database error!", err)
 		return newInternalServerError("Internal Database Error")
 	}
@@ -191,23 +149,43 @@ func updateGroup(params group.UpdateGroupParams, principal *models.User) middlew
 	if !g.HasAdmin(*principal.UID) {
 		return NewUnauthorizedResponse("Not an admin")
 	}
+	if !ifMatches(params.IfMatch, g.Version) {
+		return group.NewUpdateGroupPreconditionFailed().WithETag(etag(g.Version)).WithPayload(g)
+	}
 
+	version := ifMatchVersion(params.IfMatch, g.Version)
 	g.DisplayName = params.Body.DisplayName
 	g.Currency = params.Body.Currency
 
 	// Update user into database
-	if err := models.UpdateGroupCols(g, `display_name`, `currency`); err != nil {
+	err := models.UpdateGroupColsIfVersion(g, version, `display_name`, `currency`)
+	if models.IsErrVersionMismatch(err) {
+		// Changed since the check above
+		if g, errResp = getGroupOrError(principal.GroupUID); errResp != nil {
+			return errResp
+		}
+		return group.NewUpdateGroupPreconditionFailed().WithETag(etag(g.Version)).WithPayload(g)
+
+	} else if models.IsErrGroupNotExist(err) {
+		return newNotFoundResponse("Group not found on server.")
+
+	} else if err != nil {
 		groupLog.Critical("Database error!", err)
 		return newInternalServerError("Internal Database Error")
 	}
 
+	// Get the updated group
+	if g, errResp = getGroupOrError(principal.GroupUID); errResp != nil {
+		return errResp
+	}
+
 	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupData, []string{
 		string(g.UID),
 	})
 
 	groupLog.Infof(`Updated group "%s"`, g.UID)
 
-	return group.NewCreateGroupOK().WithPayload(g)
+	return group.NewUpdateGroupOK().WithETag(etag(g.Version)).WithPayload(g)
 }
 
 func joinGroup(params group.JoinGroupParams, principal *models.User) middleware.Responder {
@@ -215,7 +193,7 @@ func joinGroup(params group.JoinGroupParams, principal *models.User) middleware.
 
 	g, err := principal.JoinGroupWithCode(params.GroupCode)
 
-	if models.IsErrGroupCodeNotExist(err) {
+	if models.IsErrGroupCodeNotExist(err) || models.IsErrGroupNotExist(err) {
 		return NewBadRequest("Invalid group code")
 
 	} else if err != nil {
@@ -264,33 +242,37 @@ func leaveGroup(params group.LeaveGroupParams, principal *models.User) middlewar
 		return errResp
 	}
 
-	if len(g.Members) <= 1 {
-		// TODO: Delete group
+	// Tasks of the user are assigned to other members
+	tasks, err := models.GetTasksAssignedTo(g.UID, *principal.UID)
+	if err != nil {
+		groupLog.Critical("Database error getting tasks!", err)
+		return newInternalServerError("Internal Database Error")
+	}
 
-	} else if principal.IsAdmin() {
-		// Remove user as admin and add another one
-		// TODO: Put in another function
-		if len(g.Admins) == 1 {
-			g.Admins = []string{}
-		} else {
-			base.RemoveStringFromSlice(g.Admins, *principal.UID)
-		}
+	// The last admin hands the group over to another member
+	if err := g.Leave(principal); err != nil {
+		groupLog.Critical("Database error updating group!", err)
+		return newInternalServerError("Internal Database Error")
+	}
 
-		for _, m := range g.Members {
-			if m != *principal.UID {
-				g.Admins = []string{m}
-				break
-			}
-		}
-		err := models.UpdateGroupCols(g, `admins`)
-		if err != nil {
-			groupLog.Critical("Error updating group")
+	// The last member left
+	if len(g.Members) == 0 {
+		if err := models.DeleteGroup(g); err != nil {
+			groupLog.Critical("Database error deleting group!", err)
+			return newInternalServerError("Internal Database Error")
 		}
+		groupLog.Infof(`Deleted group "%s" after the last member left`, g.UID)
+
+		return group.NewLeaveGroupOK().WithPayload(&models.SuccessResponse{
+			Message: swag.String("Successfully left group"),
+			Status:  swag.Int64(http.StatusOK),
+		})
 	}
 
-	if err := principal.LeaveGroup(); err != nil {
-		groupLog.Critical("Database error updating group!", err)
-		return newInternalServerError("Internal Database Error")
+	for _, t := range tasks {
+		if t, err = models.GetTaskByUIDs(g.UID, t.ID); err == nil {
+			notifyTaskAssignee(t)
+		}
 	}
 
 	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupMemberLeft, []string{
@@ -303,6 +285,227 @@ func leaveGroup(params group.LeaveGroupParams, principal *models.User) middlewar
 	})
 }
 
+func deleteGroup(params group.DeleteGroupParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q deletes his group %q`, *principal.UID, principal.GroupUID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	if err := models.DeleteGroup(g); err != nil {
+		groupLog.Critical("Database error deleting group!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupDeleted, []string{
+		string(g.UID),
+	})
+
+	groupLog.Infof(`Deleted group "%s"`, g.UID)
+
+	return group.NewDeleteGroupOK().WithPayload(&models.SuccessResponse{
+		Message: swag.String("Successfully deleted group"),
+		Status:  swag.Int64(http.StatusOK),
+	})
+}
+
+func restoreGroup(params group.RestoreGroupParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q restores group %q`, *principal.UID, params.GroupUID)
+
+	left := principal.GroupUID
+
+	g, err := models.RestoreGroup(params.GroupUID, principal)
+	if models.IsErrGroupNotExist(err) || models.IsErrGroupInvalidUUID(err) {
+		groupLog.Debugf(err.Error())
+		return newNotFoundResponse("Deleted group not found on server.")
+
+	} else if models.IsErrGroupNotMember(err) {
+		return NewUnauthorizedResponse("Not an admin")
+
+	} else if err != nil {
+		groupLog.Critical("Database error restoring group!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupRestored, []string{
+		string(g.UID),
+	})
+
+	// The admin left the group he joined in the meantime
+	if left != "" && left != g.UID {
+		if members, err := models.GetGroupMemberUIDs(left); err == nil {
+			mailer.SendPushUpdateToUserIDs(members, mailer.PushUpdateGroupMemberLeft, []string{
+				string(*principal.UID),
+			})
+		}
+	}
+
+	groupLog.Infof(`Restored group "%s"`, g.UID)
+
+	return group.NewRestoreGroupOK().WithPayload(g)
+}
+
+// getGroupAdminOrError returns the group of the principal if he is one of its admins.
+func getGroupAdminOrError(principal *models.User) (*models.Group, middleware.Responder) {
+	g, errResp := getGroupAuthorizedOrError(principal.GroupUID, *principal.UID)
+	if errResp != nil {
+		return nil, errResp
+	}
+	if !g.HasAdmin(*principal.UID) {
+		return nil, NewUnauthorizedResponse("Not an admin")
+	}
+	return g, nil
+}
+
+// getGroupMemberErrorResponse converts errors of group member management into responses.
+func getGroupMemberErrorResponse(err error) middleware.Responder {
+	if models.IsErrGroupNotMember(err) {
+		groupLog.Debugf(err.Error())
+		return newNotFoundResponse("User is not a member of the group")
+
+	} else if models.IsErrGroupLastAdmin(err) {
+		groupLog.Debugf(err.Error())
+		return NewBadRequest("The last admin of a group can't be removed")
+
+	} else if models.IsErrGroupNotAdmin(err) {
+		groupLog.Debugf(err.Error())
+		return NewUnauthorizedResponse("Not an admin")
+
+	} else if models.IsErrVersionMismatch(err) {
+		groupLog.Debugf(err.Error())
+		return newConflictResponse(err.Error())
+	}
+
+	groupLog.Critical("Database error updating group!", err)
+	return newInternalServerError("Internal Database Error")
+}
+
+func removeGroupMember(params group.RemoveGroupMemberParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q removes %q from his group`, *principal.UID, params.UserID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	if params.UserID == *principal.UID {
+		return NewBadRequest("Use /group/leave to leave the group")
+	}
+
+	u, err := models.GetUserByUID(params.UserID)
+	if models.IsErrUserNotExist(err) {
+		return newNotFoundResponse("User is not a member of the group")
+	} else if err != nil {
+		groupLog.Critical("Database error getting user!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	// Tasks of the user are assigned to other members
+	tasks, err := models.GetTasksAssignedTo(g.UID, params.UserID)
+	if err != nil {
+		groupLog.Critical("Database error getting tasks!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	if err := g.RemoveMember(u); err != nil {
+		return getGroupMemberErrorResponse(err)
+	}
+
+	for _, t := range tasks {
+		if t, err = models.GetTaskByUIDs(g.UID, t.ID); err == nil {
+			notifyTaskAssignee(t)
+		}
+	}
+
+	mailer.SendPushUpdateToUsers([]*models.User{u}, mailer.PushUpdateGroupMemberRemoved, []string{
+		string(g.UID),
+	})
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupMemberLeft, []string{
+		params.UserID,
+	})
+
+	groupLog.Infof(`Removed user "%s" from group "%s"`, params.UserID, g.UID)
+
+	return group.NewRemoveGroupMemberOK().WithPayload(g)
+}
+
+func addGroupAdmin(params group.AddGroupAdminParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q promotes %q to admin`, *principal.UID, params.UserID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	if err := g.AddAdmin(params.UserID); err != nil {
+		return getGroupMemberErrorResponse(err)
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupAdmins, []string{
+		params.UserID,
+	})
+
+	return group.NewAddGroupAdminOK().WithPayload(g)
+}
+
+func removeGroupAdmin(params group.RemoveGroupAdminParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q demotes %q`, *principal.UID, params.UserID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	if !g.HasMember(params.UserID) {
+		return newNotFoundResponse("User is not a member of the group")
+	}
+
+	if err := g.RemoveAdmin(params.UserID); err != nil {
+		return getGroupMemberErrorResponse(err)
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupAdmins, []string{
+		params.UserID,
+	})
+
+	return group.NewRemoveGroupAdminOK().WithPayload(g)
+}
+
+func transferGroupAdmin(params group.TransferGroupAdminParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q transfers his admin rights to %q`, *principal.UID, params.UserID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	if params.UserID == *principal.UID {
+		return NewBadRequest("Can't transfer admin rights to yourself")
+	}
+
+	if err := g.TransferAdmin(*principal.UID, params.UserID); err != nil {
+		return getGroupMemberErrorResponse(err)
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushUpdateGroupAdmins, []string{
+		*principal.UID,
+		params.UserID,
+	})
+
+	return group.NewTransferGroupAdminOK().WithPayload(g)
+}
+
 func updateGroupImage(params group.UpdateGroupImageParams, principal *models.User) middleware.Responder {
 	groupLog.Debugf(`User %q starts updating image of group %q`, *principal.UID, principal.GroupUID)
 
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/group"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+)
+
+// getGroupCodeOrError returns the code of the given group or an error response.
+func getGroupCodeOrError(g *models.Group, c string) (*models.GroupCode, middleware.Responder) {
+	code, err := models.GetGroupCode(c)
+	if models.IsErrGroupCodeNotExist(err) {
+		groupLog.Debugf(err.Error())
+		return nil, newNotFoundResponse("Group code not found on server.")
+
+	} else if err != nil {
+		groupLog.Critical(`Database Error!`, err)
+		return nil, newInternalServerError("Internal Database Error")
+
+	} else if *code.GroupUID != g.UID {
+		groupLog.Debugf(`Group code %q does not belong to group "%s"`, c, g.UID)
+		return nil, newNotFoundResponse("Group code not found on server.")
+	}
+	return code, nil
+}
+
+func createGroupCode(params group.CreateGroupCodeParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q generates code for group %q!`, *principal.UID, principal.GroupUID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	opts := &models.GroupCode{}
+	if params.Label != nil {
+		opts.Label = *params.Label
+	}
+	if params.MaxUses != nil {
+		opts.MaxUses = *params.MaxUses
+	}
+	if params.ValidUntil != nil {
+		if !time.Time(*params.ValidUntil).After(time.Now()) {
+			return NewBadRequest("validUntil must be in the future")
+		}
+		opts.ValidUntil = *params.ValidUntil
+	}
+
+	c, err := models.CreateGroupCode(g.UID, *principal.UID, opts)
+	if err != nil {
+		groupLog.Critical("Database error!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	return group.NewCreateGroupCodeOK().WithPayload(c)
+}
+
+func getGroupCodes(params group.GetGroupCodesParams, principal *models.User) middleware.Responder {
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	codes, err := models.GetGroupCodesByGroupUID(g.UID)
+	if err != nil {
+		groupLog.Critical("Can't get codes for group", g.UID, err)
+		return newInternalServerError("Internal Server Error")
+	}
+
+	return group.NewGetGroupCodesOK().WithPayload(&models.GroupCodeList{
+		Count: int64(len(codes)),
+		Codes: codes,
+	})
+}
+
+func revokeGroupCode(params group.RevokeGroupCodeParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q revokes group code %q`, *principal.UID, params.Code)
+
+	var g *models.Group
+	var code *models.GroupCode
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+	if code, errResp = getGroupCodeOrError(g, params.Code); errResp != nil {
+		return errResp
+	}
+
+	if err := models.RevokeGroupCode(*code.Code); err != nil {
+		groupLog.Critical("Database error!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+	code.ValidUntil = strfmt.DateTime(time.Now().UTC())
+
+	return group.NewRevokeGroupCodeOK().WithPayload(code)
+}
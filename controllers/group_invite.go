@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/group"
+
+	"github.com/go-openapi/runtime/middleware"
+)
+
+// getGroupInviteOrError returns the invite of the given group or an error response.
+func getGroupInviteOrError(g *models.Group, id int64) (*models.GroupInvite, middleware.Responder) {
+	invite, err := models.GetGroupInviteByIDs(g.UID, id)
+	if models.IsErrGroupInviteNotExist(err) {
+		groupLog.Debugf(err.Error())
+		return nil, newNotFoundResponse("Invite not found on server.")
+
+	} else if err != nil {
+		groupLog.Critical(`Database Error!`, err)
+		return nil, newInternalServerError("Internal Database Error")
+	}
+	return invite, nil
+}
+
+func getGroupInvites(params group.GetGroupInvitesParams, principal *models.User) middleware.Responder {
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	invites, err := models.GetGroupInvitesByGroupUID(g.UID)
+	if err != nil {
+		groupLog.Critical("Can't get invites for group", g.UID, err)
+		return newInternalServerError("Internal Server Error")
+	}
+
+	return group.NewGetGroupInvitesOK().WithPayload(&models.GroupInviteList{
+		Count:   int64(len(invites)),
+		Invites: invites,
+	})
+}
+
+func createGroupInvite(params group.CreateGroupInviteParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q invites someone to group "%s"`, *principal.UID, principal.GroupUID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+
+	// The code is only sent by mail
+	if !setting.AppConfig.Mail.Enabled {
+		return NewBadRequest("Mails are disabled on this server. Use a group code instead.")
+	}
+
+	locale := params.Body.Locale
+	if locale == "" {
+		locale = principal.Locale
+	}
+
+	invite, err := models.CreateGroupInvite(g, *principal.UID, params.Body.Email, locale)
+	if err != nil {
+		groupLog.Critical("Database error!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	if err = mailer.SendGroupInviteMail(invite.Email, invite.Locale, g, principal, invite.Token); err != nil {
+		groupLog.Errorf(`Can't send mail for invite %d: %s`, invite.ID, err)
+		return newInternalServerError("Internal Server Error")
+	}
+
+	groupLog.Infof(`Created invite %d for group "%s"`, invite.ID, g.UID)
+
+	return group.NewCreateGroupInviteOK().WithPayload(invite)
+}
+
+func revokeGroupInvite(params group.RevokeGroupInviteParams, principal *models.User) middleware.Responder {
+	groupLog.Debugf(`User %q revokes invite %d`, *principal.UID, params.InviteID)
+
+	var g *models.Group
+	var invite *models.GroupInvite
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAdminOrError(principal); errResp != nil {
+		return errResp
+	}
+	if invite, errResp = getGroupInviteOrError(g, params.InviteID); errResp != nil {
+		return errResp
+	}
+
+	if err := invite.Revoke(); models.IsErrGroupInviteUsed(err) {
+		groupLog.Debugf(err.Error())
+		return NewBadRequest("The invite was already used")
+
+	} else if err != nil {
+		groupLog.Critical("Database error!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	return group.NewRevokeGroupInviteOK().WithPayload(invite)
+}
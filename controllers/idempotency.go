@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/wgplaner/wg_planer_server/models"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/swag"
+	"github.com/op/go-logging"
+)
+
+var idempotencyLog = logging.MustGetLogger("Idempotency")
+
+// withIdempotencyKey runs handle once per "Idempotency-Key" of the user.
+// Retries with the same key and body get the stored response of the first
+// request. Requests without a key are always handled.
+func withIdempotencyKey(key *string, principal *models.User, operation string, body interface{},
+	handle func() middleware.Responder) middleware.Responder {
+
+	if key == nil || strings.TrimSpace(*key) == "" {
+		return handle()
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return newInternalServerError("Internal Server Error")
+	}
+	sum := sha256.Sum256(append([]byte(operation+"\n"), data...))
+	hash := hex.EncodeToString(sum[:])
+
+	k, isNew, err := models.BeginIdempotentRequest(*principal.UID, strings.TrimSpace(*key), hash)
+	if err != nil {
+		idempotencyLog.Critical("Database error reserving idempotency key!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	if !isNew {
+		if k.RequestHash != hash {
+			return &idempotencyErrorResponse{http.StatusUnprocessableEntity, &models.ErrorResponse{
+				Message: swag.String("The Idempotency-Key was already used for another request"),
+				Status:  swag.Int64(http.StatusUnprocessableEntity),
+			}}
+		} else if k.IsInProgress() {
+			// Same body as a conflict of the request itself
+			return &idempotencyErrorResponse{http.StatusConflict, &models.BuyConflict{
+				Message:   swag.String("A request with this Idempotency-Key is still in progress"),
+				Status:    swag.Int64(http.StatusConflict),
+				ListItems: []*models.ListItem{},
+			}}
+		}
+		idempotencyLog.Debugf(`Replay response for key %q of user %q`, k.RequestKey, k.UserUID)
+		return &replayedResponse{k}
+	}
+
+	return &idempotentResponder{key: k, next: handle()}
+}
+
+// idempotentResponder writes the response of "next" and stores it for
+// retries. Server errors release the key, so that the request can be retried.
+type idempotentResponder struct {
+	key  *models.IdempotencyKey
+	next middleware.Responder
+}
+
+func (r *idempotentResponder) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+	rec := &bodyRecorder{statusRecorder: statusRecorder{ResponseWriter: rw, status: http.StatusOK}}
+	r.next.WriteResponse(rec, producer)
+
+	var err error
+	if rec.status >= http.StatusInternalServerError {
+		err = r.key.Release()
+	} else {
+		err = r.key.Finish(rec.status, rec.body.Bytes())
+	}
+	if err != nil {
+		idempotencyLog.Error("Error storing idempotent response: ", err)
+	}
+}
+
+// bodyRecorder remembers the status code and body of a response.
+type bodyRecorder struct {
+	statusRecorder
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// replayedResponse writes the stored response of an idempotency key.
+type replayedResponse struct {
+	key *models.IdempotencyKey
+}
+
+func (r *replayedResponse) WriteResponse(rw http.ResponseWriter, _ runtime.Producer) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Idempotent-Replayed", "true")
+	rw.WriteHeader(r.key.StatusCode)
+	rw.Write([]byte(r.key.Response))
+}
+
+// idempotencyErrorResponse is returned for keys that can't be used (yet).
+type idempotencyErrorResponse struct {
+	status  int
+	payload interface{}
+}
+
+func (r *idempotencyErrorResponse) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+	rw.WriteHeader(r.status)
+	if err := producer.Produce(rw, r.payload); err != nil {
+		panic(err) // let the recovery middleware deal with this
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"io"
 
 	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/eventbus"
 	"github.com/wgplaner/wg_planer_server/modules/setting"
 	"github.com/wgplaner/wg_planer_server/restapi/operations"
 	"github.com/wgplaner/wg_planer_server/restapi/operations/bill"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/events"
 	"github.com/wgplaner/wg_planer_server/restapi/operations/group"
 	"github.com/wgplaner/wg_planer_server/restapi/operations/info"
 	"github.com/wgplaner/wg_planer_server/restapi/operations/shoppinglist"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/sync"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/task"
 	"github.com/wgplaner/wg_planer_server/restapi/operations/user"
 
 	"github.com/go-openapi/runtime"
@@ -28,6 +32,8 @@ func GlobalInit() {
 	if err := models.NewEngine(); err != nil {
 		initLog.Fatalf("Failed to initialize ORM engine: %v", err)
 	}
+
+	eventbus.Default = eventbus.New(setting.AppConfig.Events.BufferSize)
 }
 
 func InitializeControllers(api *operations.WgplanerAPI) {
@@ -47,11 +53,22 @@ func InitializeControllers(api *operations.WgplanerAPI) {
 	// Create API handlers
 	api.InfoGetVersionHandler = info.GetVersionHandlerFunc(getVersionInfo)
 
+	api.EventsGetEventsHandler = events.GetEventsHandlerFunc(getEvents)
+
 	api.BillCreateBillHandler = bill.CreateBillHandlerFunc(createBill)
 	api.BillGetBillListHandler = bill.GetBillListHandlerFunc(getBillList)
+	api.BillGetGroupBalancesHandler = bill.GetGroupBalancesHandlerFunc(getGroupBalances)
+	api.BillSendBillHandler = bill.SendBillHandlerFunc(sendBill)
+	api.BillPayBillHandler = bill.PayBillHandlerFunc(payBill)
+	api.BillCancelBillHandler = bill.CancelBillHandlerFunc(cancelBill)
 
 	api.GroupCreateGroupHandler = group.CreateGroupHandlerFunc(createGroup)
 	api.GroupCreateGroupCodeHandler = group.CreateGroupCodeHandlerFunc(createGroupCode)
+	api.GroupGetGroupCodesHandler = group.GetGroupCodesHandlerFunc(getGroupCodes)
+	api.GroupRevokeGroupCodeHandler = group.RevokeGroupCodeHandlerFunc(revokeGroupCode)
+	api.GroupGetGroupInvitesHandler = group.GetGroupInvitesHandlerFunc(getGroupInvites)
+	api.GroupCreateGroupInviteHandler = group.CreateGroupInviteHandlerFunc(createGroupInvite)
+	api.GroupRevokeGroupInviteHandler = group.RevokeGroupInviteHandlerFunc(revokeGroupInvite)
 	api.GroupGetGroupHandler = group.GetGroupHandlerFunc(getGroup)
 	api.GroupGetGroupImageHandler = group.GetGroupImageHandlerFunc(getGroupImage)
 	api.GroupUpdateGroupHandler = group.UpdateGroupHandlerFunc(updateGroup)
@@ -59,6 +76,19 @@ func InitializeControllers(api *operations.WgplanerAPI) {
 	api.GroupJoinGroupHandler = group.JoinGroupHandlerFunc(joinGroup)
 	api.GroupJoinGroupHelpHandler = group.JoinGroupHelpHandlerFunc(joinGroupHelp)
 	api.GroupLeaveGroupHandler = group.LeaveGroupHandlerFunc(leaveGroup)
+	api.GroupDeleteGroupHandler = group.DeleteGroupHandlerFunc(deleteGroup)
+	api.GroupRestoreGroupHandler = group.RestoreGroupHandlerFunc(restoreGroup)
+	api.GroupRemoveGroupMemberHandler = group.RemoveGroupMemberHandlerFunc(removeGroupMember)
+	api.GroupAddGroupAdminHandler = group.AddGroupAdminHandlerFunc(addGroupAdmin)
+	api.GroupRemoveGroupAdminHandler = group.RemoveGroupAdminHandlerFunc(removeGroupAdmin)
+	api.GroupTransferGroupAdminHandler = group.TransferGroupAdminHandlerFunc(transferGroupAdmin)
+
+	api.TaskGetTaskListHandler = task.GetTaskListHandlerFunc(getTaskList)
+	api.TaskCreateTaskHandler = task.CreateTaskHandlerFunc(createTask)
+	api.TaskCompleteTaskHandler = task.CompleteTaskHandlerFunc(completeTask)
+	api.TaskSkipTaskHandler = task.SkipTaskHandlerFunc(skipTask)
+	api.TaskSwapTaskHandler = task.SwapTaskHandlerFunc(swapTask)
+	api.TaskGetTaskHistoryHandler = task.GetTaskHistoryHandlerFunc(getTaskHistory)
 
 	api.UserCreateUserHandler = user.CreateUserHandlerFunc(createUser)
 	api.UserGetUserHandler = user.GetUserHandlerFunc(getUser)
@@ -69,7 +99,19 @@ func InitializeControllers(api *operations.WgplanerAPI) {
 
 	api.ShoppinglistCreateListItemHandler = shoppinglist.CreateListItemHandlerFunc(createListItem)
 	api.ShoppinglistGetListItemsHandler = shoppinglist.GetListItemsHandlerFunc(getListItems)
+	api.ShoppinglistGetListItemHandler = shoppinglist.GetListItemHandlerFunc(getListItem)
+	api.ShoppinglistDeleteListItemHandler = shoppinglist.DeleteListItemHandlerFunc(deleteListItem)
 	api.ShoppinglistUpdateListItemHandler = shoppinglist.UpdateListItemHandlerFunc(updateListItem)
 	api.ShoppinglistBuyListItemsHandler = shoppinglist.BuyListItemsHandlerFunc(buyListItems)
 	api.ShoppinglistRevertItemPurchaseHandler = shoppinglist.RevertItemPurchaseHandlerFunc(revertItemPurchase)
+	api.ShoppinglistBuyListItemPartiallyHandler = shoppinglist.BuyListItemPartiallyHandlerFunc(buyListItemPartially)
+	api.ShoppinglistGetListItemPurchasesHandler = shoppinglist.GetListItemPurchasesHandlerFunc(getListItemPurchases)
+	api.ShoppinglistGetListItemTemplatesHandler = shoppinglist.GetListItemTemplatesHandlerFunc(getListItemTemplates)
+	api.ShoppinglistGetListItemTemplateHandler = shoppinglist.GetListItemTemplateHandlerFunc(getListItemTemplate)
+	api.ShoppinglistCreateListItemTemplateHandler = shoppinglist.CreateListItemTemplateHandlerFunc(createListItemTemplate)
+	api.ShoppinglistUpdateListItemTemplateHandler = shoppinglist.UpdateListItemTemplateHandlerFunc(updateListItemTemplate)
+	api.ShoppinglistDeleteListItemTemplateHandler = shoppinglist.DeleteListItemTemplateHandlerFunc(deleteListItemTemplate)
+
+	api.SyncGetSyncChangesHandler = sync.GetSyncChangesHandlerFunc(getSyncChanges)
+	api.SyncApplySyncMutationsHandler = sync.ApplySyncMutationsHandlerFunc(applySyncMutations)
 }
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/ratelimit"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/swag"
+	"github.com/op/go-logging"
+)
+
+var rateLimitLog = logging.MustGetLogger("RateLimit")
+
+// joinPathPrefix is the path of "joinGroup" and "joinGroupHelp".
+const joinPathPrefix = "/group/join/"
+
+// SetupGlobalMiddleware wraps the API handler with the middlewares that
+// apply to all requests.
+func SetupGlobalMiddleware(handler http.Handler) http.Handler {
+	if setting.AppConfig.RateLimit.Enabled {
+		handler = newRateLimitHandler(handler)
+	}
+	return handler
+}
+
+// rateLimitHandler throttles requests per user, or per client IP for requests
+// without a valid ID token. Attempts to join a group with a code have a
+// stricter limit per user and client IP, which are locked out after
+// repeated invalid codes.
+type rateLimitHandler struct {
+	next              http.Handler
+	requests          *ratelimit.Limiter
+	joins             *ratelimit.Limiter
+	joinLockout       *ratelimit.Lockout
+	trustProxyHeaders bool
+}
+
+// newRateLimitHandler creates a rate limiter with the limits of the configuration.
+func newRateLimitHandler(next http.Handler) *rateLimitHandler {
+	cfg := setting.AppConfig.RateLimit
+	return &rateLimitHandler{
+		next:              next,
+		requests:          ratelimit.NewLimiter(cfg.RequestsPerMinute, cfg.Burst),
+		joins:             ratelimit.NewLimiter(cfg.JoinPerMinute, cfg.JoinBurst),
+		joinLockout:       ratelimit.NewLockout(cfg.JoinMaxFailures, cfg.JoinLockoutDuration),
+		trustProxyHeaders: cfg.TrustProxyHeaders,
+	}
+}
+
+func (h *rateLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ipKey := "ip:" + h.clientIP(r)
+	key := ipKey
+
+	// Invalid tokens must not be able to use up the requests of other
+	// users. The API gets the verified token from the cache.
+	if header := r.Header.Get("Authorization"); header != "" {
+		if uid, err := getAuthUserID(header); err == nil && uid != "" {
+			key = "user:" + uid
+		}
+	}
+
+	if ok, retryAfter := h.requests.Allow(key); !ok {
+		rateLimitLog.Debugf(`Throttled request of %q to %s`, key, r.URL.Path)
+		writeTooManyRequests(w, retryAfter, "Too many requests")
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, joinPathPrefix) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	keys := []string{ipKey}
+	if key != ipKey {
+		keys = append(keys, key)
+	}
+
+	// Only joining counts for the lockout, showing the help page doesn't
+	isJoin := r.Method == http.MethodPost
+	for _, k := range keys {
+		if isJoin {
+			if locked, retryAfter := h.joinLockout.Locked(k); locked {
+				rateLimitLog.Debugf(`Join attempt of locked out %q`, k)
+				writeTooManyRequests(w, retryAfter, "Too many invalid group codes")
+				return
+			}
+		}
+		if ok, retryAfter := h.joins.Allow(k); !ok {
+			rateLimitLog.Debugf(`Throttled join attempt of %q`, k)
+			writeTooManyRequests(w, retryAfter, "Too many join attempts")
+			return
+		}
+	}
+
+	if !isJoin {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.next.ServeHTTP(rec, r)
+
+	switch rec.status {
+	case http.StatusBadRequest, http.StatusNotFound:
+		for _, k := range keys {
+			if h.joinLockout.Fail(k) {
+				rateLimitLog.Warningf(`Locked out %q after too many invalid group codes`, k)
+			}
+		}
+	case http.StatusOK:
+		for _, k := range keys {
+			h.joinLockout.Reset(k)
+		}
+	}
+}
+
+// clientIP returns the IP address of the client.
+func (h *rateLimitHandler) clientIP(r *http.Request) string {
+	if h.trustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// writeTooManyRequests writes a "429 Too Many Requests" error response.
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(&models.ErrorResponse{
+		Message: swag.String(msg),
+		Status:  swag.Int64(http.StatusTooManyRequests),
+	})
+}
+
+// statusRecorder remembers the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
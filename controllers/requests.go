@@ -122,6 +122,43 @@ func (o *NotFoundResponse) WriteResponse(rw http.ResponseWriter, producer runtim
 	}
 }
 
+//  _  _      ___     ___
+// | || |    / _ \   / _ \
+// | || |_  | | | | | (_) |
+// |__   _| | | | |  \__, |
+//    | |   | |_| |    / /
+//    |_|    \___/    /_/
+//
+
+type ConflictResponse struct {
+	Payload *models.ErrorResponse `json:"body,omitempty"`
+}
+
+func newConflictResponse(msg string) *ConflictResponse {
+	return &ConflictResponse{
+		Payload: &models.ErrorResponse{
+			Message: swag.String(msg),
+			Status:  swag.Int64(http.StatusConflict),
+		},
+	}
+}
+
+func (o *ConflictResponse) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+	rw.WriteHeader(http.StatusConflict)
+	payload := o.Payload
+
+	if payload == nil {
+		payload = &models.ErrorResponse{
+			Message: swag.String("Conflict"),
+			Status:  swag.Int64(http.StatusConflict),
+		}
+	}
+
+	if err := producer.Produce(rw, payload); err != nil {
+		panic(err) // let the recovery middleware deal with this
+	}
+}
+
 //
 //  _____    ___     ___
 // | ____|  / _ \   / _ \
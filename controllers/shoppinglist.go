@@ -1,6 +1,9 @@
 package controllers
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/satori/go.uuid"
@@ -17,9 +20,9 @@ var shoppingLog = logging.MustGetLogger("Shop")
 
 func getListItems(params shoppinglist.GetListItemsParams, principal *models.User) middleware.Responder {
 	var (
-		err   error
-		g     *models.Group
-		items []*models.ListItem
+		err  error
+		g    *models.Group
+		list *models.ShoppingList
 	)
 
 	if g, err = models.GetGroupByUID(principal.GroupUID); err != nil {
@@ -27,16 +30,114 @@ func getListItems(params shoppinglist.GetListItemsParams, principal *models.User
 		return newInternalServerError("Internal Server Error")
 	}
 
-	if items, err = g.GetActiveShoppingListItems(); err != nil {
-		shoppingLog.Criticalf(`Database error finding list items for group "%s"`, g.UID)
+	query := &models.ListItemQuery{
+		Category:      swag.StringValue(params.Category),
+		RequestedBy:   swag.StringValue(params.RequestedBy),
+		RequestedFor:  swag.StringValue(params.RequestedFor),
+		State:         swag.StringValue(params.State),
+		CreatedAfter:  dateTimeToTime(params.CreatedAfter),
+		CreatedBefore: dateTimeToTime(params.CreatedBefore),
+		BoughtAfter:   dateTimeToTime(params.BoughtAfter),
+		BoughtBefore:  dateTimeToTime(params.BoughtBefore),
+		Sort:          swag.StringValue(params.Sort),
+		Desc:          swag.StringValue(params.Order) == "desc",
+		Limit:         int(swag.Int64Value(params.Limit)),
+		Cursor:        swag.StringValue(params.Cursor),
+	}
+
+	list, err = g.FindListItems(query)
+	if models.IsErrListItemInvalidCursor(err) || models.IsErrListItemInvalidQuery(err) {
+		return NewBadRequest(err.Error())
+
+	} else if err != nil {
+		shoppingLog.Criticalf(`Database error finding list items for group "%s": %s`, g.UID, err)
 		return newInternalServerError("Database Error")
 	}
 
-	// TODO: Add filters (limit), etc.
+	return shoppinglist.NewGetListItemsOK().WithPayload(list)
+}
+
+// dateTimeToTime converts an optional query parameter to a time.
+func dateTimeToTime(dt *strfmt.DateTime) *time.Time {
+	if dt == nil {
+		return nil
+	}
+	t := time.Time(*dt)
+	return &t
+}
+
+// getListItemOrError returns the item of the given group or an error response.
+func getListItemOrError(groupUID, itemUID strfmt.UUID) (*models.ListItem, middleware.Responder) {
+	item, err := models.GetListItemByUIDs(groupUID, itemUID)
+	if models.IsErrListItemNotExist(err) {
+		shoppingLog.Debugf(`Can't find item "%s" of group "%s"`, itemUID, groupUID)
+		return nil, newNotFoundResponse("Item not found on server.")
 
-	return shoppinglist.NewGetListItemsOK().WithPayload(&models.ShoppingList{
-		Count:     int64(len(items)),
-		ListItems: items,
+	} else if err != nil {
+		shoppingLog.Critical(`Database Error!`, err)
+		return nil, newInternalServerError("Internal Database Error")
+	}
+	return item, nil
+}
+
+func getListItem(params shoppinglist.GetListItemParams, principal *models.User) middleware.Responder {
+	var (
+		g       *models.Group
+		item    *models.ListItem
+		errResp middleware.Responder
+	)
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if item, errResp = getListItemOrError(g.UID, params.ItemUID); errResp != nil {
+		return errResp
+	}
+
+	return shoppinglist.NewGetListItemOK().WithETag(etag(item.Version)).WithPayload(item)
+}
+
+func deleteListItem(params shoppinglist.DeleteListItemParams, principal *models.User) middleware.Responder {
+	shoppingLog.Debugf(`Deleting shopping list item "%s". User "%s"`, params.ItemUID, *principal.UID)
+
+	var (
+		g       *models.Group
+		item    *models.ListItem
+		errResp middleware.Responder
+	)
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if item, errResp = getListItemOrError(g.UID, params.ItemUID); errResp != nil {
+		return errResp
+	}
+
+	if item.RequestedBy != *principal.UID && !g.HasAdmin(*principal.UID) {
+		return NewUnauthorizedResponse("Only the requester or an admin can delete an item")
+	}
+
+	err := models.DeleteListItem(item)
+	if models.IsErrListItemIsBought(err) || models.IsErrListItemHasBill(err) {
+		shoppingLog.Debugf(err.Error())
+		return NewBadRequest(err.Error())
+
+	} else if models.IsErrListItemNotExist(err) {
+		shoppingLog.Debugf(err.Error())
+		return newNotFoundResponse("Item not found on server.")
+
+	} else if err != nil {
+		shoppingLog.Critical("Database error deleting list item!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushShoppingListDelete, []string{
+		string(item.ID),
+	})
+
+	return shoppinglist.NewDeleteListItemOK().WithPayload(&models.SuccessResponse{
+		Message: swag.String("deleted item"),
+		Status:  swag.Int64(200),
 	})
 }
 
@@ -44,8 +145,10 @@ func updateListItem(params shoppinglist.UpdateListItemParams, principal *models.
 	shoppingLog.Debugf(`Updating shopping list item. User "%s"`, *principal.UID)
 
 	var (
-		err error
-		g   *models.Group
+		err     error
+		g       *models.Group
+		current *models.ListItem
+		errResp middleware.Responder
 	)
 
 	if !strfmt.IsUUID(string(params.Body.ID)) {
@@ -67,6 +170,14 @@ func updateListItem(params shoppinglist.UpdateListItemParams, principal *models.
 		return NewBadRequest("A requestedFor user does not exist")
 	}
 
+	if current, errResp = getListItemOrError(g.UID, params.Body.ID); errResp != nil {
+		return errResp
+	}
+	if !ifMatches(params.IfMatch, current.Version) {
+		return shoppinglist.NewUpdateListItemPreconditionFailed().
+			WithETag(etag(current.Version)).WithPayload(current)
+	}
+
 	listItem := &models.ListItem{
 		ID:           params.Body.ID,
 		GroupUID:     principal.GroupUID,
@@ -74,13 +185,31 @@ func updateListItem(params shoppinglist.UpdateListItemParams, principal *models.
 		Category:     params.Body.Category,
 		Count:        params.Body.Count,
 		Price:        params.Body.Price,
+		PriceMode:    params.Body.PriceMode,
+		Unit:         params.Body.Unit,
+		Quantity:     params.Body.Quantity,
 		RequestedFor: params.Body.RequestedFor,
 	}
 
-	// TODO: Check that the item exists
-
 	// Insert new code into database
-	if err := models.UpdateListItemCols(listItem, `title`, `category`, `count`, `price`, `requested_for`); err != nil {
+	err = models.UpdateListItemColsIfVersion(listItem, ifMatchVersion(params.IfMatch, current.Version),
+		`title`, `category`, `count`, `price`, `price_mode`, `unit`, `quantity`, `requested_for`)
+	if models.IsErrVersionMismatch(err) {
+		// Changed since the check above
+		if current, errResp = getListItemOrError(g.UID, params.Body.ID); errResp != nil {
+			return errResp
+		}
+		return shoppinglist.NewUpdateListItemPreconditionFailed().
+			WithETag(etag(current.Version)).WithPayload(current)
+
+	} else if models.IsErrListItemNotExist(err) {
+		return newNotFoundResponse("Item not found on server.")
+
+	} else if models.IsErrListItemIsBought(err) || models.IsErrListItemHasBill(err) {
+		shoppingLog.Debugf(err.Error())
+		return NewBadRequest(err.Error())
+
+	} else if err != nil {
 		shoppingLog.Critical("Database error updating list item!", err)
 		return newInternalServerError("Internal Database Error")
 	}
@@ -95,7 +224,7 @@ func updateListItem(params shoppinglist.UpdateListItemParams, principal *models.
 		return newInternalServerError("Internal Database Error")
 	}
 
-	return shoppinglist.NewUpdateListItemOK().WithPayload(listItem)
+	return shoppinglist.NewUpdateListItemOK().WithETag(etag(listItem.Version)).WithPayload(listItem)
 }
 
 func createListItem(params shoppinglist.CreateListItemParams, principal *models.User) middleware.Responder {
@@ -143,11 +272,31 @@ func createListItem(params shoppinglist.CreateListItemParams, principal *models.
 		Category:     params.Body.Category,
 		Count:        params.Body.Count,
 		Price:        params.Body.Price,
+		PriceMode:    params.Body.PriceMode,
+		Unit:         params.Body.Unit,
+		Quantity:     params.Body.Quantity,
 		RequestedFor: params.Body.RequestedFor,
 		RequestedBy:  *principal.UID,
 		GroupUID:     g.UID,
 	}
 
+	if swag.BoolValue(params.Merge) {
+		// Add to an existing item with the same title
+		item, merged, err := models.MergeOrCreateListItem(&listItem)
+		if err != nil {
+			shoppingLog.Critical("Database error merging list item!", err)
+			return newInternalServerError("Internal Database Error")
+		}
+
+		pushType := mailer.PushShoppingListAdd
+		if merged {
+			pushType = mailer.PushShoppingListUpdate
+		}
+		mailer.SendPushUpdateToUserIDs(g.Members, pushType, []string{string(item.ID)})
+
+		return shoppinglist.NewCreateListItemOK().WithPayload(item)
+	}
+
 	// Insert new code into database
 	if err := models.CreateListItem(&listItem); err != nil {
 		shoppingLog.Critical("Database error inserting list item!", err)
@@ -162,6 +311,13 @@ func createListItem(params shoppinglist.CreateListItemParams, principal *models.
 }
 
 func buyListItems(params shoppinglist.BuyListItemsParams, principal *models.User) middleware.Responder {
+	return withIdempotencyKey(params.IdempotencyKey, principal, "buyListItems", params.Body,
+		func() middleware.Responder {
+			return doBuyListItems(params, principal)
+		})
+}
+
+func doBuyListItems(params shoppinglist.BuyListItemsParams, principal *models.User) middleware.Responder {
 	var err error
 	var g *models.Group
 
@@ -173,22 +329,26 @@ func buyListItems(params shoppinglist.BuyListItemsParams, principal *models.User
 		return NewBadRequest(err.Error())
 	}
 
-	if principal.GroupUID != principal.GroupUID {
-		return NewUnauthorizedResponse("Can't buy items for another group")
-	}
-
-	// TODO: Sanity checks, etc.
 	err = principal.BuyListItemsByUIDs(params.Body)
 	if models.IsErrListItemNotExist(err) {
 		return NewBadRequest(err.Error())
 
+	} else if errBought, ok := err.(models.ErrListItemsAlreadyBought); ok {
+		shoppingLog.Debugf(`User %q tried to buy %d items that were already bought`,
+			*principal.UID, len(errBought.Items))
+		return shoppinglist.NewBuyListItemsConflict().WithPayload(&models.BuyConflict{
+			Message:   swag.String("Some items were already bought. No items were bought."),
+			Status:    swag.Int64(http.StatusConflict),
+			ListItems: errBought.Items,
+		})
+
 	} else if err != nil {
 		shoppingLog.Criticalf("Database error: %s", err)
 		return newInternalServerError("Error buying items")
 	}
 
 	// Send push notification
-	list := make([]string, len(params.Body))
+	list := make([]string, 0, len(params.Body))
 	for _, item := range params.Body {
 		list = append(list, string(item))
 	}
@@ -236,3 +396,238 @@ func revertItemPurchase(params shoppinglist.RevertItemPurchaseParams, principal
 		Status:  swag.Int64(200),
 	})
 }
+
+func buyListItemPartially(params shoppinglist.BuyListItemPartiallyParams, principal *models.User) middleware.Responder {
+	shoppingLog.Debugf(`User %q buys %d of item "%s"`, *principal.UID, *params.Body.Count, params.ItemUID)
+
+	var (
+		g       *models.Group
+		errResp middleware.Responder
+	)
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	result, err := principal.BuyListItemPartially(params.ItemUID, *params.Body.Count, params.Body.Price)
+	if models.IsErrListItemNotExist(err) {
+		return newNotFoundResponse("Item not found on server.")
+
+	} else if models.IsErrListItemInvalidPurchaseCount(err) {
+		return NewBadRequest(err.Error())
+
+	} else if errBought, ok := err.(models.ErrListItemsAlreadyBought); ok {
+		return shoppinglist.NewBuyListItemPartiallyConflict().WithPayload(&models.BuyConflict{
+			Message:   swag.String("The item was already bought."),
+			Status:    swag.Int64(http.StatusConflict),
+			ListItems: errBought.Items,
+		})
+
+	} else if err != nil {
+		shoppingLog.Criticalf("Database error: %s", err)
+		return newInternalServerError("Error buying item")
+	}
+
+	// Send push notification
+	mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushShoppingListBuy,
+		[]string{string(result.ListItem.ID)})
+	if result.Remainder != nil {
+		mailer.SendPushUpdateToUserIDs(g.Members, mailer.PushShoppingListAdd,
+			[]string{string(result.Remainder.ID)})
+	}
+
+	return shoppinglist.NewBuyListItemPartiallyOK().WithPayload(result)
+}
+
+func getListItemPurchases(params shoppinglist.GetListItemPurchasesParams, principal *models.User) middleware.Responder {
+	var (
+		g       *models.Group
+		item    *models.ListItem
+		errResp middleware.Responder
+	)
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if item, errResp = getListItemOrError(g.UID, params.ItemUID); errResp != nil {
+		return errResp
+	}
+
+	purchases, err := models.GetListItemPurchases(item)
+	if err != nil {
+		shoppingLog.Critical(`Database Error!`, err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	return shoppinglist.NewGetListItemPurchasesOK().WithPayload(purchases)
+}
+
+// getListItemTemplateOrError returns the template of the given group or an error response.
+func getListItemTemplateOrError(groupUID, templateUID strfmt.UUID) (*models.ListItemTemplate, middleware.Responder) {
+	t, err := models.GetListItemTemplateByUIDs(groupUID, templateUID)
+	if models.IsErrListItemTemplateNotExist(err) {
+		shoppingLog.Debugf(`Can't find template "%s" of group "%s"`, templateUID, groupUID)
+		return nil, newNotFoundResponse("Template not found on server.")
+
+	} else if err != nil {
+		shoppingLog.Critical(`Database Error!`, err)
+		return nil, newInternalServerError("Internal Database Error")
+	}
+	return t, nil
+}
+
+// validateTemplateRequestedFor checks that all users of "requestedFor" exist.
+func validateTemplateRequestedFor(requestedFor []string) middleware.Responder {
+	if len(requestedFor) == 0 {
+		return NewBadRequest("RequestedFor must contain at least one user")
+	}
+
+	if exists, err := models.AreUsersExist(base.Unique(requestedFor)); err != nil {
+		return newInternalServerError(err.Error())
+
+	} else if !exists {
+		return NewBadRequest("A requestedFor user does not exist")
+	}
+	return nil
+}
+
+func getListItemTemplates(params shoppinglist.GetListItemTemplatesParams, principal *models.User) middleware.Responder {
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	templates, err := models.GetListItemTemplatesByGroupUID(g.UID)
+	if err != nil {
+		shoppingLog.Criticalf(`Database error finding templates for group "%s"`, g.UID)
+		return newInternalServerError("Database Error")
+	}
+
+	return shoppinglist.NewGetListItemTemplatesOK().WithPayload(&models.ListItemTemplateList{
+		Count:     int64(len(templates)),
+		Templates: templates,
+	})
+}
+
+func getListItemTemplate(params shoppinglist.GetListItemTemplateParams, principal *models.User) middleware.Responder {
+	var g *models.Group
+	var t *models.ListItemTemplate
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if t, errResp = getListItemTemplateOrError(g.UID, params.TemplateUID); errResp != nil {
+		return errResp
+	}
+
+	return shoppinglist.NewGetListItemTemplateOK().WithPayload(t)
+}
+
+func createListItemTemplate(params shoppinglist.CreateListItemTemplateParams, principal *models.User) middleware.Responder {
+	shoppingLog.Debugf(`Creating list item template. User "%s" for group "%s"`,
+		*principal.UID, principal.GroupUID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if errResp = validateTemplateRequestedFor(params.Body.RequestedFor); errResp != nil {
+		return errResp
+	}
+
+	templateUID, err := uuid.NewV4()
+	if err != nil {
+		shoppingLog.Critical("Error generating NewV4 UID!", err)
+		return newInternalServerError("Internal Error")
+	}
+
+	t := &models.ListItemTemplate{
+		ID:           strfmt.UUID(templateUID.String()),
+		GroupUID:     g.UID,
+		Title:        params.Body.Title,
+		Category:     params.Body.Category,
+		Count:        params.Body.Count,
+		Price:        params.Body.Price,
+		RequestedBy:  *principal.UID,
+		RequestedFor: params.Body.RequestedFor,
+		Recurrence:   params.Body.Recurrence,
+		Interval:     params.Body.Interval,
+		NextDueAt:    params.Body.NextDueAt,
+	}
+
+	if err := models.CreateListItemTemplate(t); err != nil {
+		shoppingLog.Critical("Database error inserting list item template!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	return shoppinglist.NewCreateListItemTemplateOK().WithPayload(t)
+}
+
+func updateListItemTemplate(params shoppinglist.UpdateListItemTemplateParams, principal *models.User) middleware.Responder {
+	shoppingLog.Debugf(`Updating list item template "%s". User "%s"`, params.TemplateUID, *principal.UID)
+
+	var g *models.Group
+	var t *models.ListItemTemplate
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if t, errResp = getListItemTemplateOrError(g.UID, params.TemplateUID); errResp != nil {
+		return errResp
+	}
+	if errResp = validateTemplateRequestedFor(params.Body.RequestedFor); errResp != nil {
+		return errResp
+	}
+
+	t.Title = params.Body.Title
+	t.Category = params.Body.Category
+	t.Count = params.Body.Count
+	t.Price = params.Body.Price
+	t.RequestedFor = params.Body.RequestedFor
+	t.Recurrence = params.Body.Recurrence
+	t.Interval = params.Body.Interval
+	cols := []string{`title`, `category`, `count`, `price`, `requested_for`, `recurrence`, `interval`}
+
+	if params.Body.NextDueAt != nil {
+		t.NextDueAt = params.Body.NextDueAt
+		cols = append(cols, `next_due_at`)
+	}
+
+	if err := models.UpdateListItemTemplateCols(t, cols...); err != nil {
+		shoppingLog.Critical("Database error updating list item template!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	return shoppinglist.NewUpdateListItemTemplateOK().WithPayload(t)
+}
+
+func deleteListItemTemplate(params shoppinglist.DeleteListItemTemplateParams, principal *models.User) middleware.Responder {
+	shoppingLog.Debugf(`Deleting list item template "%s". User "%s"`, params.TemplateUID, *principal.UID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	err := models.DeleteListItemTemplateByUIDs(g.UID, params.TemplateUID)
+	if models.IsErrListItemTemplateNotExist(err) {
+		return newNotFoundResponse("Template not found on server.")
+
+	} else if err != nil {
+		shoppingLog.Critical("Database error deleting list item template!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	return shoppinglist.NewDeleteListItemTemplateOK().WithPayload(&models.SuccessResponse{
+		Message: swag.String("deleted template"),
+		Status:  swag.Int64(200),
+	})
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/sync"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/op/go-logging"
+)
+
+var syncLog = logging.MustGetLogger("Sync")
+
+// syncPushTypes are the push updates that are sent for applied offline mutations.
+var syncPushTypes = map[string]mailer.PushUpdateType{
+	models.SyncOpCreate: mailer.PushShoppingListAdd,
+	models.SyncOpUpdate: mailer.PushShoppingListUpdate,
+	models.SyncOpDelete: mailer.PushShoppingListDelete,
+	models.SyncOpBuy:    mailer.PushShoppingListBuy,
+	models.SyncOpRevert: mailer.PushShoppingListRevertPurchase,
+}
+
+func getSyncChanges(params sync.GetSyncChangesParams, principal *models.User) middleware.Responder {
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	since := ""
+	if params.Since != nil {
+		since = *params.Since
+	}
+
+	changes, err := models.GetSyncChanges(g, *principal.UID, since)
+	if models.IsErrSyncInvalidCursor(err) {
+		syncLog.Debugf(err.Error())
+		return NewBadRequest(err.Error())
+
+	} else if err != nil {
+		syncLog.Critical("Database error getting changes!", err)
+		return newInternalServerError("Internal Database Error")
+	}
+
+	return sync.NewGetSyncChangesOK().WithPayload(changes)
+}
+
+func applySyncMutations(params sync.ApplySyncMutationsParams, principal *models.User) middleware.Responder {
+	syncLog.Debugf(`User "%s" applies %d offline mutations`, *principal.UID, len(params.Body.Mutations))
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	results, err := models.ApplySyncMutations(principal, g, params.Body)
+	if models.IsErrSyncInvalidCursor(err) {
+		syncLog.Debugf(err.Error())
+		return NewBadRequest(err.Error())
+
+	} else if err != nil {
+		syncLog.Critical("Database error applying mutations!", err)
+		if len(results) == 0 {
+			return newInternalServerError("Internal Database Error")
+		}
+		// Tell the client which mutations were applied before the error
+	}
+
+	// Send push notifications
+	for i, r := range results {
+		if r.Status != models.SyncStatusApplied {
+			continue
+		}
+		m := params.Body.Mutations[i]
+		mailer.SendPushUpdateToUserIDs(g.Members, syncPushTypes[m.Op], []string{
+			string(m.EntityID),
+		})
+	}
+
+	return sync.NewApplySyncMutationsOK().WithPayload(&models.SyncMutationResults{
+		Results: results,
+	})
+}
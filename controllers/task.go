@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+	"github.com/wgplaner/wg_planer_server/restapi/operations/task"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/strfmt"
+	"github.com/op/go-logging"
+	"github.com/satori/go.uuid"
+)
+
+var taskLog = logging.MustGetLogger("Task")
+
+// defaultUpcomingAssignments is the number of upcoming assignments returned per task.
+const defaultUpcomingAssignments = 3
+
+// getTaskOrError returns the task of the given group or an error response.
+func getTaskOrError(groupUID, taskUID strfmt.UUID) (*models.Task, middleware.Responder) {
+	t, err := models.GetTaskByUIDs(groupUID, taskUID)
+	if models.IsErrTaskNotExist(err) {
+		taskLog.Debugf(`Can't find task "%s" of group "%s"`, taskUID, groupUID)
+		return nil, newNotFoundResponse("Task not found on server.")
+
+	} else if err != nil {
+		taskLog.Critical(`Database Error!`, err)
+		return nil, newInternalServerError("Internal Database Error")
+	}
+	return t, nil
+}
+
+// getTaskErrorResponse converts errors of task turns into responses.
+func getTaskErrorResponse(err error) middleware.Responder {
+	if models.IsErrTaskNotInRotation(err) || models.IsErrTaskNoAssignee(err) {
+		taskLog.Debugf(err.Error())
+		return NewBadRequest(err.Error())
+	} else if models.IsErrVersionMismatch(err) {
+		taskLog.Debugf(err.Error())
+		return newConflictResponse(err.Error())
+	}
+
+	taskLog.Critical(`Database Error!`, err)
+	return newInternalServerError("Internal Database Error")
+}
+
+// notifyTaskAssignee sends a push notification to the assignee of the task.
+func notifyTaskAssignee(t *models.Task) {
+	if t.Assignee == "" {
+		return
+	}
+	mailer.SendPushUpdateToUserIDs([]string{t.Assignee}, mailer.PushTaskAssigned, []string{
+		string(t.ID),
+	})
+}
+
+func getTaskList(params task.GetTaskListParams, principal *models.User) middleware.Responder {
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	tasks, err := models.GetTasksByGroupUID(g.UID)
+	if err != nil {
+		taskLog.Critical("Can't get tasks for group", g.UID, err)
+		return newInternalServerError("Internal Server Error")
+	}
+
+	upcoming := defaultUpcomingAssignments
+	if params.Upcoming != nil {
+		upcoming = int(*params.Upcoming)
+	}
+	for _, t := range tasks {
+		t.LoadUpcoming(upcoming)
+	}
+
+	return task.NewGetTaskListOK().WithPayload(&models.TaskList{
+		Count: int64(len(tasks)),
+		Tasks: tasks,
+	})
+}
+
+func createTask(params task.CreateTaskParams, principal *models.User) middleware.Responder {
+	taskLog.Debugf(`User %q creates a task for group "%s"`, *principal.UID, principal.GroupUID)
+
+	var g *models.Group
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+
+	taskUID, err := uuid.NewV4()
+	if err != nil {
+		taskLog.Critical("Error generating NewV4 UID!", err)
+		return newInternalServerError("Internal Error")
+	}
+
+	t := &models.Task{
+		ID:          strfmt.UUID(taskUID.String()),
+		GroupUID:    g.UID,
+		Title:       params.Body.Title,
+		Description: params.Body.Description,
+		Recurrence:  params.Body.Recurrence,
+		Interval:    params.Body.Interval,
+		Rotation:    params.Body.Rotation,
+		DueAt:       params.Body.DueAt,
+		CreatedBy:   *principal.UID,
+	}
+
+	if err := models.CreateTask(t); err != nil {
+		return getTaskErrorResponse(err)
+	}
+
+	t.LoadUpcoming(defaultUpcomingAssignments)
+	notifyTaskAssignee(t)
+
+	return task.NewCreateTaskOK().WithPayload(t)
+}
+
+func completeTask(params task.CompleteTaskParams, principal *models.User) middleware.Responder {
+	taskLog.Debugf(`User %q completes task "%s"`, *principal.UID, params.TaskUID)
+
+	var g *models.Group
+	var t *models.Task
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if t, errResp = getTaskOrError(g.UID, params.TaskUID); errResp != nil {
+		return errResp
+	}
+
+	if err := t.Done(*principal.UID); err != nil {
+		return getTaskErrorResponse(err)
+	}
+
+	t.LoadUpcoming(defaultUpcomingAssignments)
+	notifyTaskAssignee(t)
+
+	return task.NewCompleteTaskOK().WithPayload(t)
+}
+
+func skipTask(params task.SkipTaskParams, principal *models.User) middleware.Responder {
+	taskLog.Debugf(`User %q skips turn of task "%s"`, *principal.UID, params.TaskUID)
+
+	var g *models.Group
+	var t *models.Task
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if t, errResp = getTaskOrError(g.UID, params.TaskUID); errResp != nil {
+		return errResp
+	}
+
+	if err := t.Skip(*principal.UID); err != nil {
+		return getTaskErrorResponse(err)
+	}
+
+	t.LoadUpcoming(defaultUpcomingAssignments)
+	notifyTaskAssignee(t)
+
+	return task.NewSkipTaskOK().WithPayload(t)
+}
+
+func swapTask(params task.SwapTaskParams, principal *models.User) middleware.Responder {
+	taskLog.Debugf(`User %q swaps turn of task "%s" with %q`, *principal.UID, params.TaskUID, params.Body)
+
+	var g *models.Group
+	var t *models.Task
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if t, errResp = getTaskOrError(g.UID, params.TaskUID); errResp != nil {
+		return errResp
+	}
+
+	if err := t.Swap(params.Body, *principal.UID); err != nil {
+		return getTaskErrorResponse(err)
+	}
+
+	t.LoadUpcoming(defaultUpcomingAssignments)
+	notifyTaskAssignee(t)
+
+	return task.NewSwapTaskOK().WithPayload(t)
+}
+
+func getTaskHistory(params task.GetTaskHistoryParams, principal *models.User) middleware.Responder {
+	var g *models.Group
+	var t *models.Task
+	var errResp middleware.Responder
+
+	if g, errResp = getGroupAuthorizedOrError(principal.GroupUID, *principal.UID); errResp != nil {
+		return errResp
+	}
+	if t, errResp = getTaskOrError(g.UID, params.TaskUID); errResp != nil {
+		return errResp
+	}
+
+	completions, err := t.GetHistory()
+	if err != nil {
+		taskLog.Critical("Can't get history of task", t.ID, err)
+		return newInternalServerError("Internal Server Error")
+	}
+
+	return task.NewGetTaskHistoryOK().WithPayload(&models.TaskHistory{
+		Count:       int64(len(completions)),
+		Completions: completions,
+	})
+}
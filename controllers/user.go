@@ -45,6 +45,8 @@ func createUser(params user.CreateUserParams, principal *models.User) middleware
 	userBuilder.SetUID(params.Body.UID)
 	userBuilder.SetEmail(params.Body.Email)
 	userBuilder.SetFirebaseInstanceID(params.Body.FirebaseInstanceID)
+	userBuilder.SetLocale(params.Body.Locale)
+	userBuilder.SetMailOptOut(params.Body.MailOptOut)
 	u, err := userBuilder.Construct()
 
 	if err != nil {
@@ -78,24 +80,50 @@ func updateUser(params user.UpdateUserParams, principal *models.User) middleware
 	// Check if the user is already registered
 	if theUser, err = models.GetUserByUID(*params.Body.UID); models.IsErrUserNotExist(err) {
 		userLog.Infof(`User "%s" does not exist!`, *params.Body.UID)
-		return NewBadRequest("User does not exist!")
+		return newNotFoundResponse("User does not exist!")
 
 	} else if err != nil {
 		userLog.Critical("Database Error!", err)
 		return newInternalServerError("Internal Database Error")
 	}
 
+	if !ifMatches(params.IfMatch, theUser.Version) {
+		return user.NewUpdateUserPreconditionFailed().WithETag(etag(theUser.Version)).WithPayload(theUser)
+	}
+	version := ifMatchVersion(params.IfMatch, theUser.Version)
+
 	// Create new user
 	theUser = &models.User{
 		UID:                params.Body.UID,
 		DisplayName:        params.Body.DisplayName,
 		Email:              params.Body.Email,
 		FirebaseInstanceID: params.Body.FirebaseInstanceID,
+		Locale:             params.Body.Locale,
+		MailOptOut:         params.Body.MailOptOut,
+	}
+
+	// Locale and mail opt-outs are only changed if they are sent
+	cols := []string{"display_name", "email", "firebase_instance_id"}
+	if theUser.Locale != "" {
+		cols = append(cols, "locale")
+	}
+	if theUser.MailOptOut != nil {
+		cols = append(cols, "mail_opt_out")
 	}
 
 	// Insert new user into database
-	err = models.UpdateUserCols(theUser, "display_name", "email", "firebase_instance_id")
-	if err != nil {
+	err = models.UpdateUserColsIfVersion(theUser, version, cols...)
+	if models.IsErrVersionMismatch(err) {
+		// Changed since the check above
+		if theUser, err = models.GetUserByUID(*params.Body.UID); err != nil {
+			return newInternalServerError("Internal Database Error")
+		}
+		return user.NewUpdateUserPreconditionFailed().WithETag(etag(theUser.Version)).WithPayload(theUser)
+
+	} else if models.IsErrUserNotExist(err) {
+		return newNotFoundResponse("User does not exist!")
+
+	} else if err != nil {
 		userLog.Critical("Database error!", err)
 		return newInternalServerError("Internal Database Error")
 	}
@@ -120,7 +148,7 @@ func updateUser(params user.UpdateUserParams, principal *models.User) middleware
 		})
 	}
 
-	return user.NewUpdateUserOK().WithPayload(theUser)
+	return user.NewUpdateUserOK().WithETag(etag(theUser.Version)).WithPayload(theUser)
 }
 
 func getUser(params user.GetUserParams, principal *models.User) middleware.Responder {
@@ -160,7 +188,7 @@ func getUser(params user.GetUserParams, principal *models.User) middleware.Respo
 		return newInternalServerError("Internal Database Error")
 	}
 
-	return user.NewGetUserOK().WithPayload(u)
+	return user.NewGetUserOK().WithETag(etag(u.Version)).WithPayload(u)
 }
 
 func getUserBoughtItems(params user.GetUserBoughtItemsParams, principal *models.User) middleware.Responder {
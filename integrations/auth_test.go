@@ -3,6 +3,12 @@ package integrations
 import (
 	"net/http"
 	"testing"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/auth"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestUserIDAuth(t *testing.T) {
@@ -16,3 +22,36 @@ func TestUserIDAuth(t *testing.T) {
 		"/users/1234567890fakefirebaseid0001/image")
 	MakeRequest(t, req2, http.StatusUnauthorized)
 }
+
+func TestRawUserIDAuthRejected(t *testing.T) {
+	prepareTestEnv(t)
+	req := NewRequestWithRawAuth(t, "GET", "1234567890fakefirebaseid0001",
+		"/users/1234567890fakefirebaseid0001/image", nil)
+	MakeRequest(t, req, http.StatusUnauthorized)
+}
+
+func TestExpiredIDTokenRejected(t *testing.T) {
+	prepareTestEnv(t)
+	token, err := auth.Sign(testTokenKey, testTokenKeyID, &auth.Claims{
+		Issuer:    auth.FirebaseIssuerPrefix + testTokenProjectID,
+		Audience:  testTokenProjectID,
+		Subject:   "1234567890fakefirebaseid0001",
+		IssuedAt:  time.Now().Add(-3 * time.Hour).Unix(),
+		ExpiresAt: time.Now().Add(-2 * time.Hour).Unix(),
+	})
+	assert.NoError(t, err)
+
+	req := NewRequestWithRawAuth(t, "GET", "Bearer "+token,
+		"/users/1234567890fakefirebaseid0001/image", nil)
+	MakeRequest(t, req, http.StatusUnauthorized)
+}
+
+func TestInsecureRawUserIDAuth(t *testing.T) {
+	prepareTestEnv(t)
+	setting.AppConfig.Auth.InsecureRawUID = true
+	defer func() { setting.AppConfig.Auth.InsecureRawUID = false }()
+
+	req := NewRequestWithRawAuth(t, "GET", "1234567890fakefirebaseid0001",
+		"/users/1234567890fakefirebaseid0001/image", nil)
+	MakeRequest(t, req, http.StatusOK)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/stretchr/testify/assert"
 	"github.com/wgplaner/wg_planer_server/models"
 )
@@ -27,6 +28,12 @@ func TestCreateBill(t *testing.T) {
 	item := models.AssertExistsAndLoadBean(t, &models.ListItem{ID: "00112233-4455-6677-8899-000000000004"}).(*models.ListItem)
 	assert.Equal(t, bill.UID, item.BillUID)
 	models.AssertCount(t, &models.Bill{}, 2)
+
+	// Assert that balances were updated (Eggs: 129, bought by 0002 for 0001)
+	b1 := models.AssertExistsAndLoadBean(t, &models.MemberBalance{UserUID: swag.String("1234567890fakefirebaseid0001")}).(*models.MemberBalance)
+	b2 := models.AssertExistsAndLoadBean(t, &models.MemberBalance{UserUID: swag.String(authValid)}).(*models.MemberBalance)
+	assert.Equal(t, int64(-79), *b1.Balance)
+	assert.Equal(t, int64(79), *b2.Balance)
 }
 
 func TestGetBills(t *testing.T) {
@@ -43,9 +50,105 @@ func TestGetBills(t *testing.T) {
 	assert.Equal(t, int64(1), billList.Count)
 	assert.Len(t, billList.Bills[0].BoughtItems, 2)
 	assert.Len(t, billList.Bills[0].SentTo, 2)
-	assert.Equal(t, "todo", *billList.Bills[0].State)
+	assert.Equal(t, models.BillStatePartial, *billList.Bills[0].State)
 	assert.Equal(t, int64(270), billList.Bills[0].Sum)
 	assert.Equal(t, strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff"), billList.Bills[0].GroupUID)
 	assert.Equal(t, strfmt.UUID("00112233-4455-6677-8899-000000000001"), billList.Bills[0].BoughtListItems[0].ID)
 	assert.Equal(t, strfmt.UUID("00112233-4455-6677-8899-123000000001"), billList.Bills[0].BoughtListItems[0].BillUID)
 }
+
+func TestGetGroupBalances(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		balances  = models.GroupBalances{}
+		authValid = "1234567890fakefirebaseid0001"
+		req       = NewRequest(t, "GET", authValid, "/group/balances")
+		resp      = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &balances)
+
+	assert.Equal(t, strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff"), balances.GroupUID)
+	assert.Len(t, balances.Balances, 2)
+	assert.Len(t, balances.Settlements, 1)
+	assert.Equal(t, "1234567890fakefirebaseid0002", *balances.Settlements[0].From)
+	assert.Equal(t, authValid, *balances.Settlements[0].To)
+	assert.Equal(t, int64(50), *balances.Settlements[0].Amount)
+
+	// User without a group
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0003", "/group/balances")
+	MakeRequest(t, req, http.StatusNotFound)
+}
+
+func TestPayBill(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		bill      = models.Bill{}
+		authValid = "1234567890fakefirebaseid0002"
+		url       = "/group/bills/00112233-4455-6677-8899-123000000001/pay/" + authValid
+		req       = NewRequest(t, "POST", authValid, url)
+		resp      = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &bill)
+	assert.Equal(t, models.BillStatePaid, *bill.State)
+	assert.Len(t, bill.PayedBy, 2)
+
+	// Already paid
+	req = NewRequest(t, "POST", authValid, url)
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	// Unknown bill
+	req = NewRequest(t, "POST", authValid, "/group/bills/00112233-4455-6677-8899-123000000002/pay/"+authValid)
+	MakeRequest(t, req, http.StatusNotFound)
+}
+
+func TestCreateBillWithoutItems(t *testing.T) {
+	prepareTestEnv(t)
+	authValid := "1234567890fakefirebaseid0002"
+
+	req := NewRequestWithJSON(t, "POST", authValid, "/group/bills/create", models.Bill{BoughtItems: []string{}})
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	// "Apples" were not bought yet
+	req = NewRequestWithJSON(t, "POST", authValid, "/group/bills/create",
+		models.Bill{BoughtItems: []string{"00112233-4455-6677-8899-000000000002"}})
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	models.AssertCount(t, &models.Bill{}, 1)
+}
+
+func TestSendAndCancelBill(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		bill      = models.Bill{}
+		authValid = "1234567890fakefirebaseid0002"
+		items     = []string{"00112233-4455-6677-8899-000000000004"}
+		req       = NewRequestWithJSON(t, "POST", authValid, "/group/bills/create", models.Bill{BoughtItems: items})
+		resp      = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &bill)
+	assert.Equal(t, models.BillStateDraft, *bill.State)
+
+	// Only the creator can send the bill
+	req = NewRequestWithJSON(t, "POST", "1234567890fakefirebaseid0001",
+		"/group/bills/"+string(bill.UID)+"/send", []string{})
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	req = NewRequestWithJSON(t, "POST", authValid, "/group/bills/"+string(bill.UID)+"/send",
+		[]string{"1234567890fakefirebaseid0001"})
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &bill)
+	assert.Equal(t, models.BillStateSent, *bill.State)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0001"}, bill.SentTo)
+
+	req = NewRequest(t, "POST", authValid, "/group/bills/"+string(bill.UID)+"/cancel")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &bill)
+	assert.Equal(t, models.BillStateCancelled, *bill.State)
+
+	item := models.AssertExistsAndLoadBean(t, &models.ListItem{ID: "00112233-4455-6677-8899-000000000004"}).(*models.ListItem)
+	assert.Empty(t, item.BillUID)
+
+	// Cancelled bills can't be cancelled again
+	req = NewRequest(t, "POST", authValid, "/group/bills/"+string(bill.UID)+"/cancel")
+	MakeRequest(t, req, http.StatusBadRequest)
+}
@@ -0,0 +1,68 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/eventbus"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+// getEventStream reads the event stream of the user for a short time.
+func getEventStream(t *testing.T, uid string, lastEventID int64) string {
+	req := NewRequest(t, "GET", uid, "/events")
+	if lastEventID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(lastEventID, 10))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	resp := MakeRequest(t, req.WithContext(ctx), http.StatusOK)
+	assert.Equal(t, "text/event-stream", resp.Headers.Get("Content-Type"))
+	return string(resp.Body)
+}
+
+func TestGetEvents(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		authInGroup = "1234567890fakefirebaseid0001"
+		authNoGroup = "1234567890fakefirebaseid0003"
+		lastEventID = eventbus.Default.LastID()
+		item        = models.ListItem{
+			Title:        swag.String("Eggs"),
+			Category:     swag.String("Groceries"),
+			Count:        swag.Int64(1),
+			RequestedFor: []string{authInGroup},
+		}
+	)
+
+	req := NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist", item)
+	MakeRequest(t, req, http.StatusOK)
+
+	// Members of the group receive the event
+	body := getEventStream(t, authInGroup, lastEventID)
+	assert.Contains(t, body, "id: "+strconv.FormatInt(lastEventID+1, 10)+"\n")
+	assert.Contains(t, body, "event: ShoppingList-Add\n")
+
+	// ... others don't
+	body = getEventStream(t, authNoGroup, lastEventID)
+	assert.NotContains(t, body, "ShoppingList-Add")
+
+	// Unknown event ids require a resync
+	body = getEventStream(t, authInGroup, lastEventID+1000)
+	assert.Contains(t, body, "event: Resync\n")
+	assert.NotContains(t, body, "ShoppingList-Add")
+}
+
+func TestGetEventsUnauthorized(t *testing.T) {
+	prepareTestEnv(t)
+	req := NewRequest(t, "GET", AuthEmpty, "/events")
+	MakeRequest(t, req, http.StatusUnauthorized)
+}
@@ -0,0 +1,94 @@
+package integrations
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/wgplaner/wg_planer_server/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateGroupInvite(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		admin   = "1234567890fakefirebaseid0001"
+		member  = "1234567890fakefirebaseid0002"
+		invitee = "1234567890fakefirebaseid0003"
+		invite  = models.GroupInvite{}
+		body    = models.GroupInvite{Email: "arne@example.com"}
+	)
+
+	// Invites are sent by mail
+	req := NewRequestWithJSON(t, "POST", admin, "/group/invites", body)
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	smtpServer, stop := useFakeSMTPServer(t)
+	defer stop()
+
+	// Only admins can invite
+	req = NewRequestWithJSON(t, "POST", member, "/group/invites", body)
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	req = NewRequestWithJSON(t, "POST", admin, "/group/invites", body)
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &invite)
+	assert.Equal(t, models.GroupInviteStatePending, invite.State)
+	assert.Equal(t, "DE", invite.Locale)
+
+	list := models.GroupInviteList{}
+	req = NewRequest(t, "GET", admin, "/group/invites")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &list)
+	assert.EqualValues(t, 1, list.Count)
+
+	// The token is only sent by mail
+	token := models.AssertExistsAndLoadBean(t, &models.GroupInvite{ID: invite.ID}).(*models.GroupInvite).Token
+	assert.NotContains(t, string(resp.Body), token)
+
+	stop()
+	mails := smtpServer.Mails()
+	if assert.Len(t, mails, 1) {
+		assert.Equal(t, []string{"arne@example.com"}, mails[0].To)
+		assert.Equal(t, "John Doe lädt dich in Group 2 ein", mails[0].Subject)
+		assert.Contains(t, mails[0].Text, "/group/join/"+token)
+	}
+
+	// The token can be used once like a group code
+	req = NewRequest(t, "POST", invitee, "/group/join/"+token)
+	MakeRequest(t, req, http.StatusOK)
+	req = NewRequest(t, "POST", "1234567890fakefirebaseid0004", "/group/join/"+token)
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	// Used invites can't be revoked
+	req = NewRequest(t, "DELETE", admin, "/group/invites/"+strconv.FormatInt(invite.ID, 10))
+	MakeRequest(t, req, http.StatusBadRequest)
+}
+
+func TestRevokeGroupInvite(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		admin = "1234567890fakefirebaseid0001"
+		g     = models.AssertExistsAndLoadBean(t, &models.Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*models.Group)
+	)
+
+	invite, err := models.CreateGroupInvite(g, admin, "arne@example.com", "")
+	assert.NoError(t, err)
+	path := "/group/invites/" + strconv.FormatInt(invite.ID, 10)
+
+	// Only admins can revoke invites
+	req := NewRequest(t, "DELETE", "1234567890fakefirebaseid0002", path)
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	req = NewRequest(t, "DELETE", admin, path)
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, invite)
+	assert.Equal(t, models.GroupInviteStateRevoked, invite.State)
+
+	req = NewRequest(t, "POST", "1234567890fakefirebaseid0003", "/group/join/"+invite.Token)
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	req = NewRequest(t, "DELETE", admin, "/group/invites/999")
+	MakeRequest(t, req, http.StatusNotFound)
+}
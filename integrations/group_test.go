@@ -114,6 +114,56 @@ func TestCreateGroupCode(t *testing.T) {
 	DecodeJSON(t, resp, &code)
 	assert.Equal(t, *code.GroupUID, uid)
 	assert.Len(t, *code.Code, 12)
+
+	// Only admins can create codes
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0002", url)
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0001", url+"?label=Flyer&maxUses=5&validUntil=2050-01-01T00:00:00Z")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &code)
+	assert.Equal(t, "Flyer", code.Label)
+	assert.EqualValues(t, 5, code.MaxUses)
+	assert.Equal(t, "1234567890fakefirebaseid0001", code.CreatedBy)
+
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0001", url+"?validUntil=2017-01-01T00:00:00Z")
+	MakeRequest(t, req, http.StatusBadRequest)
+}
+
+func TestGetGroupCodes(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		list = models.GroupCodeList{}
+		req  = NewRequest(t, "GET", "1234567890fakefirebaseid0002", "/group/codes")
+		resp = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &list)
+	if assert.EqualValues(t, 1, list.Count) {
+		assert.Equal(t, "ABCDEFGHI123", *list.Codes[0].Code)
+	}
+
+	// Users without a group
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0003", "/group/codes")
+	MakeRequest(t, req, http.StatusNotFound)
+}
+
+func TestRevokeGroupCode(t *testing.T) {
+	prepareTestEnv(t)
+	path := "/group/codes/ABCDEFGHI123"
+
+	// Only admins can revoke codes
+	req := NewRequest(t, "DELETE", "1234567890fakefirebaseid0002", path)
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	// Codes of other groups
+	req = NewRequest(t, "DELETE", "1234567890fakefirebaseid0004", path)
+	MakeRequest(t, req, http.StatusNotFound)
+
+	req = NewRequest(t, "DELETE", "1234567890fakefirebaseid0001", path)
+	MakeRequest(t, req, http.StatusOK)
+
+	req = NewRequest(t, "POST", "1234567890fakefirebaseid0003", "/group/join/ABCDEFGHI123")
+	MakeRequest(t, req, http.StatusBadRequest)
 }
 
 func TestGetJoinGroupHelp(t *testing.T) {
@@ -160,4 +210,152 @@ func TestLeaveGroup(t *testing.T) {
 	resp := MakeRequest(t, req, http.StatusOK)
 	DecodeJSON(t, resp, u)
 	assert.Empty(t, u.GroupUID)
+
+	// User was removed from the task rotations
+	task := models.AssertExistsAndLoadBean(t, &models.Task{ID: "00112233-4455-6677-8899-789000000001"}).(*models.Task)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0002"}, task.Rotation)
+	assert.Equal(t, "1234567890fakefirebaseid0002", task.Assignee)
+}
+
+func TestGroupAdmins(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		g      models.Group
+		admin  = "1234567890fakefirebaseid0001"
+		member = "1234567890fakefirebaseid0002"
+	)
+
+	// Only admins can promote members
+	req := NewRequest(t, "POST", member, "/group/members/"+member+"/admin")
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	// The last admin can't be demoted
+	req = NewRequest(t, "DELETE", admin, "/group/members/"+admin+"/admin")
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	// Users outside of the group can't be promoted
+	req = NewRequest(t, "POST", admin, "/group/members/1234567890fakefirebaseid0003/admin")
+	MakeRequest(t, req, http.StatusNotFound)
+
+	req = NewRequest(t, "POST", admin, "/group/members/"+member+"/admin")
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &g)
+	assert.Equal(t, []string{admin, member}, g.Admins)
+
+	req = NewRequest(t, "DELETE", member, "/group/members/"+admin+"/admin")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &g)
+	assert.Equal(t, []string{member}, g.Admins)
+}
+
+func TestTransferGroupAdmin(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		g      models.Group
+		admin  = "1234567890fakefirebaseid0001"
+		member = "1234567890fakefirebaseid0002"
+		req    = NewRequest(t, "POST", admin, "/group/members/"+member+"/transfer")
+		resp   = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &g)
+	assert.Equal(t, []string{member}, g.Admins)
+}
+
+func TestRemoveGroupMember(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		g      models.Group
+		admin  = "1234567890fakefirebaseid0001"
+		member = "1234567890fakefirebaseid0002"
+	)
+
+	// Only admins can remove members
+	req := NewRequest(t, "DELETE", member, "/group/members/"+admin)
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	// Admins have to use /group/leave
+	req = NewRequest(t, "DELETE", admin, "/group/members/"+admin)
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	req = NewRequest(t, "DELETE", admin, "/group/members/"+member)
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &g)
+	assert.Equal(t, []string{admin}, g.Members)
+
+	u := models.AssertExistsAndLoadBean(t, &models.User{UID: swag.String(member)}).(*models.User)
+	assert.Empty(t, u.GroupUID)
+
+	// The user's tasks were handed over
+	task := models.AssertExistsAndLoadBean(t, &models.Task{ID: "00112233-4455-6677-8899-789000000001"}).(*models.Task)
+	assert.Equal(t, []string{admin}, task.Rotation)
+}
+
+func TestLeaveGroupHandsOverAdmin(t *testing.T) {
+	prepareTestEnv(t)
+	admin := "1234567890fakefirebaseid0001"
+	req := NewRequest(t, "POST", admin, "/group/leave")
+	MakeRequest(t, req, http.StatusOK)
+
+	g := models.AssertExistsAndLoadBean(t,
+		&models.Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*models.Group)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0002"}, g.Admins)
+}
+
+func TestDeleteGroup(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		admin    = "1234567890fakefirebaseid0001"
+		member   = "1234567890fakefirebaseid0002"
+		groupUID = strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+	)
+
+	// Only admins can delete the group
+	req := NewRequest(t, "DELETE", member, "/group")
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	req = NewRequest(t, "DELETE", admin, "/group")
+	MakeRequest(t, req, http.StatusOK)
+
+	models.AssertNotExistsBean(t, &models.Group{UID: groupUID})
+	models.AssertNotExistsBean(t, &models.ListItem{GroupUID: groupUID})
+	models.AssertNotExistsBean(t, &models.Bill{GroupUID: groupUID})
+	u := models.AssertExistsAndLoadBean(t, &models.User{UID: swag.String(member)}).(*models.User)
+	assert.Empty(t, u.GroupUID)
+}
+
+func TestLeaveGroupAsLastMember(t *testing.T) {
+	prepareTestEnv(t)
+	req := NewRequest(t, "POST", "1234567890fakefirebaseid0004", "/group/leave")
+	MakeRequest(t, req, http.StatusOK)
+
+	models.AssertNotExistsBean(t, &models.Group{UID: "00112233-4455-6677-8899-aabbccddeef0"})
+}
+
+func TestUpdateGroupIfMatch(t *testing.T) {
+	prepareTestEnv(t)
+	g := models.Group{
+		UID:         "00112233-4455-6677-8899-aabbccddeeff",
+		DisplayName: swag.String("Updated Group"),
+	}
+
+	req := NewRequest(t, "GET", "1234567890fakefirebaseid0001", "/group")
+	resp := MakeRequest(t, req, http.StatusOK)
+	tag := resp.Headers.Get("ETag")
+	assert.NotEmpty(t, tag)
+
+	req = NewRequestWithJSON(t, "PUT", "1234567890fakefirebaseid0001", "/group", g)
+	req.Header.Set("If-Match", `W/`+tag)
+	MakeRequest(t, req, http.StatusPreconditionFailed)
+
+	req = NewRequestWithJSON(t, "PUT", "1234567890fakefirebaseid0001", "/group", g)
+	req.Header.Set("If-Match", tag)
+	resp = MakeRequest(t, req, http.StatusOK)
+	assert.NotEqual(t, tag, resp.Headers.Get("ETag"))
+
+	var current models.Group
+	req = NewRequestWithJSON(t, "PUT", "1234567890fakefirebaseid0001", "/group", g)
+	req.Header.Set("If-Match", tag)
+	resp = MakeRequest(t, req, http.StatusPreconditionFailed)
+	DecodeJSON(t, resp, &current)
+	assert.Equal(t, "Updated Group", *current.DisplayName)
 }
@@ -2,6 +2,8 @@ package integrations
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,19 +14,28 @@ import (
 	"path"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/wgplaner/wg_planer_server/controllers"
 	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/auth"
 	"github.com/wgplaner/wg_planer_server/modules/setting"
 	"github.com/wgplaner/wg_planer_server/restapi"
 	"github.com/wgplaner/wg_planer_server/restapi/operations"
 
 	"github.com/stretchr/testify/assert"
-	"gopkg.in/testfixtures.v2"
 )
 
 var server *restapi.Server
 
+// testTokenKey is used to sign the ID tokens of all test requests.
+var testTokenKey *rsa.PrivateKey
+
+const (
+	testTokenKeyID     = "integration-test-key"
+	testTokenProjectID = "wgplaner-integration-test"
+)
+
 var (
 	AuthValid   = "1234567890fakefirebaseid0001"
 	AuthInvalid = "invalid"
@@ -37,10 +48,8 @@ func TestMain(m *testing.M) {
 	initIntegrationTest()
 	defer server.Shutdown()
 
-	var sqlHelper = &testfixtures.SQLite{}
-
 	err := models.InitFixtures(
-		sqlHelper,
+		models.FixturesHelper(),
 		path.Join(setting.AppWorkPath, "models/fixtures/"),
 	)
 
@@ -69,6 +78,8 @@ func initIntegrationTest() {
 
 	setting.NewConfigContext()
 	setting.AppConfig.Auth.IgnoreFirebase = true
+	// Tests send many requests in a short time. See ratelimit_test.go.
+	setting.AppConfig.RateLimit.Enabled = false
 
 	api = operations.NewWgplanerAPI(setting.LoadSwaggerSpec(restapi.SwaggerJSON))
 	server = restapi.NewServer(api)
@@ -77,8 +88,40 @@ func initIntegrationTest() {
 	controllers.GlobalInit()
 	controllers.InitializeControllers(api)
 
+	initTestTokenVerifier()
+
 	// Set handler
-	server.SetHandler(api.Serve(nil))
+	server.SetHandler(controllers.SetupGlobalMiddleware(api.Serve(nil)))
+}
+
+// initTestTokenVerifier creates a key pair that is used to sign ID tokens,
+// so that integration tests don't need access to firebase.
+func initTestTokenVerifier() {
+	var err error
+	if testTokenKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		log.Fatalln("Can't create key for ID tokens: ", err)
+	}
+
+	setting.AppConfig.Auth.InsecureRawUID = false
+	setting.AppConfig.Auth.FirebaseProjectID = testTokenProjectID
+	setting.TokenVerifier = auth.NewVerifier(
+		auth.NewKeySet(map[string]*rsa.PublicKey{testTokenKeyID: &testTokenKey.PublicKey}),
+		testTokenProjectID,
+	)
+}
+
+// NewIDToken returns a signed ID token for the given user id.
+func NewIDToken(t testing.TB, uid string) string {
+	now := time.Now()
+	token, err := auth.Sign(testTokenKey, testTokenKeyID, &auth.Claims{
+		Issuer:    auth.FirebaseIssuerPrefix + testTokenProjectID,
+		Audience:  testTokenProjectID,
+		Subject:   uid,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	})
+	assert.NoError(t, err)
+	return token
 }
 
 func prepareTestEnv(t testing.TB) {
@@ -121,10 +164,21 @@ func NewRequestWithJSON(t testing.TB, method, auth string, urlStr string, v inte
 	return req
 }
 
-func NewRequestWithBody(t testing.TB, method, auth string, urlStr string, body io.Reader) *http.Request {
+// NewRequestWithBody creates a request. If "uid" is not empty, a signed
+// ID token for that user is set as "Authorization" header.
+func NewRequestWithBody(t testing.TB, method, uid string, urlStr string, body io.Reader) *http.Request {
+	var authHeader string
+	if uid != "" {
+		authHeader = "Bearer " + NewIDToken(t, uid)
+	}
+	return NewRequestWithRawAuth(t, method, authHeader, urlStr, body)
+}
+
+// NewRequestWithRawAuth creates a request with "authHeader" as is.
+func NewRequestWithRawAuth(t testing.TB, method, authHeader string, urlStr string, body io.Reader) *http.Request {
 	request, err := http.NewRequest(method, urlStr, body)
 	assert.NoError(t, err)
-	request.Header.Add("Authorization", auth)
+	request.Header.Add("Authorization", authHeader)
 	request.RequestURI = urlStr
 	return request
 }
@@ -152,14 +206,19 @@ func NewRequestWithImage(t testing.TB, method, auth string, urlStr string, imgPa
 const NoExpectedStatus = -1
 
 func MakeRequest(t testing.TB, req *http.Request, expectedStatus int) *TestResponse {
+	fmt.Println(server)
+	return MakeRequestWithHandler(t, server.GetHandler(), req, expectedStatus)
+}
+
+// MakeRequestWithHandler serves the request with the given handler instead of the server's.
+func MakeRequestWithHandler(t testing.TB, handler http.Handler, req *http.Request, expectedStatus int) *TestResponse {
 	buffer := bytes.NewBuffer(nil)
 	respWriter := &TestResponseWriter{
 		Writer:  buffer,
 		Headers: make(map[string][]string),
 	}
 
-	fmt.Println(server)
-	server.GetHandler().ServeHTTP(respWriter, req)
+	handler.ServeHTTP(respWriter, req)
 
 	if expectedStatus != NoExpectedStatus {
 		assert.EqualValues(t, expectedStatus, respWriter.HeaderCode,
@@ -0,0 +1,126 @@
+package integrations
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/scheduler"
+)
+
+func TestGetListItemTemplates(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		list        models.ListItemTemplateList
+		authInGroup = "1234567890fakefirebaseid0001"
+		req         = NewRequest(t, "GET", authInGroup, "/shoppinglist/templates")
+		resp        = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &list)
+	assert.Equal(t, int64(3), list.Count)
+	assert.Len(t, list.Templates, 3)
+
+	// Other group
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0004", "/shoppinglist/templates")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &list)
+	assert.Equal(t, int64(0), list.Count)
+}
+
+func TestListItemTemplateCRUD(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		tpl         models.ListItemTemplate
+		authInGroup = "1234567890fakefirebaseid0001"
+		newTpl      = models.ListItemTemplate{
+			Title:        swag.String("Bread"),
+			Category:     swag.String("Groceries"),
+			Count:        swag.Int64(1),
+			RequestedFor: []string{authInGroup},
+			Recurrence:   swag.String(models.RecurrenceDaily),
+			Interval:     2,
+		}
+		req  = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/templates", newTpl)
+		resp = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &tpl)
+	assert.Equal(t, "Bread", *tpl.Title)
+	assert.Equal(t, authInGroup, tpl.RequestedBy)
+	assert.NotNil(t, tpl.NextDueAt)
+	models.AssertExistsAndLoadBean(t, &models.ListItemTemplate{ID: tpl.ID})
+
+	url := "/shoppinglist/templates/" + string(tpl.ID)
+
+	newTpl.Title = swag.String("Whole grain bread")
+	req = NewRequestWithJSON(t, "PUT", authInGroup, url, newTpl)
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &tpl)
+	assert.Equal(t, "Whole grain bread", *tpl.Title)
+
+	req = NewRequest(t, "GET", authInGroup, url)
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &tpl)
+	assert.Equal(t, "Whole grain bread", *tpl.Title)
+
+	// Other group
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0004", url)
+	MakeRequest(t, req, http.StatusNotFound)
+
+	req = NewRequest(t, "DELETE", authInGroup, url)
+	MakeRequest(t, req, http.StatusOK)
+	models.AssertNotExistsBean(t, &models.ListItemTemplate{ID: tpl.ID})
+
+	req = NewRequest(t, "DELETE", authInGroup, url)
+	MakeRequest(t, req, http.StatusNotFound)
+}
+
+func TestCreateListItemTemplateInvalid(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		authInGroup = "1234567890fakefirebaseid0001"
+		tpl         = models.ListItemTemplate{
+			Title:      swag.String("Bread"),
+			Category:   swag.String("Groceries"),
+			Count:      swag.Int64(1),
+			Recurrence: swag.String("yearly"),
+		}
+		req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/templates", tpl)
+	)
+	MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+	// No requestedFor
+	tpl.Recurrence = swag.String(models.RecurrenceWeekly)
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/templates", tpl)
+	MakeRequest(t, req, http.StatusBadRequest)
+}
+
+func TestSchedulerAddDueListItems(t *testing.T) {
+	prepareTestEnv(t)
+
+	items, err := scheduler.AddDueListItems(time.Now().UTC())
+	assert.NoError(t, err)
+
+	// "Apples" are skipped because they have not been bought yet
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "Milk", *items[0].Title)
+	}
+
+	var (
+		shopList    models.ShoppingList
+		authInGroup = "1234567890fakefirebaseid0001"
+		req         = NewRequest(t, "GET", authInGroup, "/shoppinglist")
+		resp        = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &shopList)
+
+	found := false
+	for _, item := range shopList.ListItems {
+		if item.ID == items[0].ID {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,256 @@
+package integrations
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMail is a mail received by the fake SMTP server.
+type fakeMail struct {
+	To      []string
+	Subject string
+	Data    string
+	// Text is the decoded plain text part
+	Text string
+}
+
+// fakeSMTPServer accepts all mails without authentication and keeps them in memory.
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	mails    []*fakeMail
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	s := &fakeSMTPServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	c := textproto.NewConn(conn)
+	m := &fakeMail{}
+
+	c.PrintfLine("220 localhost ESMTP fake")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "MAIL", "NOOP", "RSET":
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			addr := strings.TrimSpace(line[strings.Index(line, ":")+1:])
+			m.To = append(m.To, strings.Trim(addr, "<>"))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 Go ahead")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.Data = string(data)
+			if msg, err := mail.ReadMessage(strings.NewReader(m.Data)); err == nil {
+				m.Subject, _ = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+				m.Text = readTextPart(msg)
+			}
+
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			m = &fakeMail{}
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return
+		default:
+			c.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+// readTextPart returns the decoded text/plain part of a multipart message.
+func readTextPart(msg *mail.Message) string {
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			return ""
+		}
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain") {
+			text, _ := ioutil.ReadAll(part)
+			return string(text)
+		}
+	}
+}
+
+// Mails returns all received mails.
+func (s *fakeSMTPServer) Mails() []*fakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*fakeMail{}, s.mails...)
+}
+
+// useFakeSMTPServer enables mails and sends them to a fake SMTP server.
+// The returned function sends all queued mails and restores the configuration.
+func useFakeSMTPServer(t *testing.T) (*fakeSMTPServer, func()) {
+	s := startFakeSMTPServer(t)
+	oldConfig := setting.AppConfig.Mail
+
+	addr := s.listener.Addr().(*net.TCPAddr)
+	setting.AppConfig.Mail.Enabled = true
+	setting.AppConfig.Mail.SMTPHost = addr.IP.String()
+	setting.AppConfig.Mail.SMTPPort = addr.Port
+	setting.AppConfig.Mail.SMTPIdentity = "info@example.com"
+	setting.AppConfig.Mail.SMTPUser = ""
+	mailer.StartMailQueue(10)
+
+	return s, func() {
+		mailer.StopMailQueue()
+		setting.AppConfig.Mail = oldConfig
+		s.listener.Close()
+	}
+}
+
+// createAndSendBill creates a bill of the "Eggs" item as user 0002 and sends it to user 0001.
+func createAndSendBill(t *testing.T, dueDate string) *models.Bill {
+	var (
+		bill      = models.Bill{}
+		authValid = "1234567890fakefirebaseid0002"
+		items     = []string{"00112233-4455-6677-8899-000000000004"}
+		req       = NewRequestWithJSON(t, "POST", authValid, "/group/bills/create", models.Bill{BoughtItems: items, DueDate: dueDate})
+		resp      = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &bill)
+
+	req = NewRequestWithJSON(t, "POST", authValid, "/group/bills/"+string(bill.UID)+"/send",
+		[]string{"1234567890fakefirebaseid0001"})
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &bill)
+	return &bill
+}
+
+func TestBillSentMail(t *testing.T) {
+	prepareTestEnv(t)
+	smtpServer, stop := useFakeSMTPServer(t)
+
+	createAndSendBill(t, "2019-06-07")
+	stop()
+
+	mails := smtpServer.Mails()
+	if assert.Len(t, mails, 1) {
+		// The locale of the user is "DE"
+		assert.Equal(t, []string{"john@example.com"}, mails[0].To)
+		assert.Equal(t, "Neue Rechnung von Max Meier in Group 2", mails[0].Subject)
+		assert.Contains(t, mails[0].Data, "multipart/alternative")
+		assert.Contains(t, mails[0].Text, "1.29 €")
+	}
+}
+
+func TestBillSentMailOptOut(t *testing.T) {
+	prepareTestEnv(t)
+	smtpServer, stop := useFakeSMTPServer(t)
+
+	u := &models.User{
+		UID:        swag.String("1234567890fakefirebaseid0001"),
+		MailOptOut: []string{models.MailTypeBillSent},
+	}
+	assert.NoError(t, models.UpdateUserCols(u, "mail_opt_out"))
+
+	createAndSendBill(t, "2019-06-07")
+	stop()
+
+	assert.Empty(t, smtpServer.Mails())
+}
+
+func TestPaymentReminderMail(t *testing.T) {
+	prepareTestEnv(t)
+	smtpServer, stop := useFakeSMTPServer(t)
+	now := time.Now().UTC()
+
+	bill := createAndSendBill(t, now.Add(12*time.Hour).Format(time.RFC3339))
+
+	n, err := mailer.SendPaymentReminders(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Recipients are only reminded once
+	n, err = mailer.SendPaymentReminders(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	stop()
+
+	mails := smtpServer.Mails()
+	if assert.Len(t, mails, 2) {
+		assert.Equal(t, []string{"john@example.com"}, mails[1].To)
+		assert.True(t, strings.HasPrefix(mails[1].Subject, "Erinnerung: Rechnung von Max Meier"), mails[1].Subject)
+	}
+
+	b := models.AssertExistsAndLoadBean(t, &models.Bill{UID: bill.UID}).(*models.Bill)
+	assert.NotNil(t, b.ReminderSentAt)
+}
+
+func TestGroupDigestMail(t *testing.T) {
+	prepareTestEnv(t)
+	smtpServer, stop := useFakeSMTPServer(t)
+	now := time.Now().UTC()
+
+	// The first digest is sent one interval later
+	n, err := mailer.SendGroupDigests(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = mailer.SendGroupDigests(now.Add(8 * 24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	stop()
+
+	// Only the group with items has news
+	mails := smtpServer.Mails()
+	if assert.Len(t, mails, 2) {
+		for _, m := range mails {
+			assert.Equal(t, "Neuigkeiten aus Group 2", m.Subject)
+			assert.Contains(t, m.Text, "Apples")
+		}
+	}
+}
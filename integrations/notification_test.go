@@ -0,0 +1,100 @@
+package integrations
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+	"github.com/wgplaner/wg_planer_server/modules/notification"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationOutbox(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		authInGroup = "1234567890fakefirebaseid0001"
+		item        = models.ListItem{
+			Title:        swag.String("Eggs"),
+			Category:     swag.String("Groceries"),
+			Count:        swag.Int64(1),
+			RequestedFor: []string{authInGroup},
+		}
+		notifier = &notification.MemoryNotifier{}
+	)
+
+	req := NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist", item)
+	MakeRequest(t, req, http.StatusOK)
+
+	n := models.AssertExistsAndLoadBean(t, &models.Notification{Type: "ShoppingList-Add"}).(*models.Notification)
+	assert.Equal(t, models.NotificationStatePending, n.State)
+	assert.Len(t, n.Receivers, 2)
+
+	processed, err := notification.ProcessDue(notifier, notification.Options{}, time.Now().UTC().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	// Both members use the same device in the fixtures
+	messages := notifier.Messages()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "ShoppingList-Add", messages[0].Type)
+		assert.Equal(t, []string{"1"}, messages[0].Tokens)
+	}
+
+	n = models.AssertExistsAndLoadBean(t, &models.Notification{ID: n.ID}).(*models.Notification)
+	assert.Equal(t, models.NotificationStateSent, n.State)
+}
+
+func TestNotificationUnregisteredDevice(t *testing.T) {
+	prepareTestEnv(t)
+	notifier := &notification.MemoryNotifier{Unregistered: map[string]bool{"1": true}}
+
+	userID := "1234567890fakefirebaseid0001"
+	mailer.SendPushUpdateToUserIDs([]string{userID}, mailer.PushUserUpdate, []string{userID})
+
+	_, err := notification.ProcessDue(notifier, notification.Options{}, time.Now().UTC().Add(time.Second))
+	assert.NoError(t, err)
+
+	u := models.AssertExistsAndLoadBean(t, &models.User{UID: swag.String(userID)}).(*models.User)
+	assert.Empty(t, u.FirebaseInstanceID)
+	models.AssertExistsAndLoadBean(t, &models.Notification{State: models.NotificationStateSent})
+}
+
+func TestNotificationRetry(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		notifier = &notification.MemoryNotifier{Err: errors.New("unavailable")}
+		opts     = notification.Options{MaxAttempts: 2, Backoff: time.Minute}
+		now      = time.Now().UTC().Add(time.Second)
+		userID   = "1234567890fakefirebaseid0001"
+	)
+
+	mailer.SendPushUpdateToUserIDs([]string{userID}, mailer.PushUserUpdate, []string{userID})
+
+	processed, err := notification.ProcessDue(notifier, opts, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	n := models.AssertExistsAndLoadBean(t, &models.Notification{Type: string(mailer.PushUserUpdate)}).(*models.Notification)
+	assert.Equal(t, models.NotificationStatePending, n.State)
+	assert.Equal(t, 1, n.Attempts)
+	assert.Equal(t, "unavailable", n.LastError)
+
+	// Not due before the backoff
+	processed, err = notification.ProcessDue(notifier, opts, now.Add(30*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, processed)
+
+	// The second attempt is the last one
+	processed, err = notification.ProcessDue(notifier, opts, now.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	n = models.AssertExistsAndLoadBean(t, &models.Notification{ID: n.ID}).(*models.Notification)
+	assert.Equal(t, models.NotificationStateDead, n.State)
+	assert.Equal(t, []string{userID}, n.Receivers)
+}
@@ -0,0 +1,117 @@
+package integrations
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/controllers"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useRateLimit returns the server's handler with a rate limiter that
+// allows "burst" requests and "joinBurst" join attempts. The returned
+// function restores the configuration.
+func useRateLimit(burst, joinBurst, joinMaxFailures int) (http.Handler, func()) {
+	oldConfig := setting.AppConfig.RateLimit
+
+	cfg := &setting.AppConfig.RateLimit
+	cfg.Enabled = true
+	cfg.RequestsPerMinute = 1
+	cfg.Burst = burst
+	cfg.JoinPerMinute = 1
+	cfg.JoinBurst = joinBurst
+	cfg.JoinMaxFailures = joinMaxFailures
+	cfg.JoinLockoutDuration = 15 * time.Minute
+
+	return controllers.SetupGlobalMiddleware(server.GetHandler()), func() {
+		setting.AppConfig.RateLimit = oldConfig
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	prepareTestEnv(t)
+	handler, restore := useRateLimit(2, 5, 5)
+	defer restore()
+
+	for i := 0; i < 2; i++ {
+		req := NewRequest(t, "GET", "1234567890fakefirebaseid0001", "/group")
+		MakeRequestWithHandler(t, handler, req, http.StatusOK)
+	}
+
+	req := NewRequest(t, "GET", "1234567890fakefirebaseid0001", "/group")
+	resp := MakeRequestWithHandler(t, handler, req, http.StatusTooManyRequests)
+	assert.Equal(t, "60", resp.Headers.Get("Retry-After"))
+
+	// Limits are per user
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0002", "/group")
+	MakeRequestWithHandler(t, handler, req, http.StatusOK)
+}
+
+func TestRateLimitJoinGroup(t *testing.T) {
+	prepareTestEnv(t)
+	handler, restore := useRateLimit(100, 2, 5)
+	defer restore()
+
+	for i := 0; i < 2; i++ {
+		req := NewRequest(t, "GET", "", "/group/join/ABCDEFGHI123")
+		req.RemoteAddr = "192.0.2.1:1234"
+		MakeRequestWithHandler(t, handler, req, http.StatusOK)
+	}
+
+	req := NewRequest(t, "GET", "", "/group/join/ABCDEFGHI123")
+	req.RemoteAddr = "192.0.2.1:1234"
+	resp := MakeRequestWithHandler(t, handler, req, http.StatusTooManyRequests)
+	assert.NotEmpty(t, resp.Headers.Get("Retry-After"))
+
+	// Other clients
+	req = NewRequest(t, "GET", "", "/group/join/ABCDEFGHI123")
+	req.RemoteAddr = "192.0.2.2:1234"
+	MakeRequestWithHandler(t, handler, req, http.StatusOK)
+}
+
+func TestRateLimitJoinGroupLockout(t *testing.T) {
+	prepareTestEnv(t)
+	handler, restore := useRateLimit(100, 100, 3)
+	defer restore()
+
+	for i := 0; i < 3; i++ {
+		req := NewRequest(t, "POST", "1234567890fakefirebaseid0003", "/group/join/AAAAAAAAAAAA")
+		req.RemoteAddr = "192.0.2.1:1234"
+		MakeRequestWithHandler(t, handler, req, http.StatusBadRequest)
+	}
+
+	// Valid codes are locked out as well
+	req := NewRequest(t, "POST", "1234567890fakefirebaseid0003", "/group/join/ABCDEFGHI123")
+	req.RemoteAddr = "192.0.2.2:1234"
+	resp := MakeRequestWithHandler(t, handler, req, http.StatusTooManyRequests)
+	assert.Equal(t, "900", resp.Headers.Get("Retry-After"))
+
+	req = NewRequest(t, "POST", "1234567890fakefirebaseid0004", "/group/join/ABCDEFGHI123")
+	req.RemoteAddr = "192.0.2.1:1234"
+	MakeRequestWithHandler(t, handler, req, http.StatusTooManyRequests)
+
+	// Other users and clients aren't affected
+	req = NewRequest(t, "POST", "1234567890fakefirebaseid0004", "/group/join/ABCDEFGHI123")
+	req.RemoteAddr = "192.0.2.2:1234"
+	MakeRequestWithHandler(t, handler, req, http.StatusOK)
+}
+
+func TestRateLimitJoinGroupHelpNoLockout(t *testing.T) {
+	prepareTestEnv(t)
+	handler, restore := useRateLimit(100, 100, 1)
+	defer restore()
+
+	// Showing the help page of an invalid code isn't a join attempt
+	for i := 0; i < 2; i++ {
+		req := NewRequest(t, "GET", "", "/group/join/invalid")
+		req.RemoteAddr = "192.0.2.1:1234"
+		MakeRequestWithHandler(t, handler, req, http.StatusBadRequest)
+	}
+
+	req := NewRequest(t, "POST", "1234567890fakefirebaseid0003", "/group/join/ABCDEFGHI123")
+	req.RemoteAddr = "192.0.2.1:1234"
+	MakeRequestWithHandler(t, handler, req, http.StatusOK)
+}
@@ -1,6 +1,9 @@
 package integrations
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
 	"testing"
 
@@ -24,6 +27,45 @@ func TestGetShoppinglist(t *testing.T) {
 	assert.Equal(t, shopList.Count, int64(2))
 }
 
+func TestGetShoppinglistQuery(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		page1       models.ShoppingList
+		page2       models.ShoppingList
+		authInGroup = "1234567890fakefirebaseid0001"
+		url         = "/shoppinglist?state=all&sort=price&order=desc&limit=3"
+		req         = NewRequest(t, "GET", authInGroup, url)
+		resp        = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &page1)
+	assert.Len(t, page1.ListItems, 3)
+	assert.Equal(t, int64(5), page1.Count)
+	assert.Equal(t, int64(170), page1.ListItems[0].Price)
+	assert.NotEmpty(t, page1.NextCursor)
+
+	req = NewRequest(t, "GET", authInGroup, url+"&cursor="+page1.NextCursor)
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &page2)
+	assert.Len(t, page2.ListItems, 2)
+	assert.Equal(t, int64(5), page2.Count)
+	assert.Empty(t, page2.NextCursor)
+
+	var filtered models.ShoppingList
+	req = NewRequest(t, "GET", authInGroup, "/shoppinglist?state=bought&requestedFor="+authInGroup)
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &filtered)
+	assert.Len(t, filtered.ListItems, 1)
+	assert.Equal(t, strfmt.UUID("00112233-4455-6677-8899-000000000004"), filtered.ListItems[0].ID)
+
+	// Invalid cursor
+	req = NewRequest(t, "GET", authInGroup, "/shoppinglist?cursor=invalid")
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	// Invalid state
+	req = NewRequest(t, "GET", authInGroup, "/shoppinglist?state=unknown")
+	MakeRequest(t, req, http.StatusUnprocessableEntity)
+}
+
 func TestCreateListItemInvalid(t *testing.T) {
 	prepareTestEnv(t)
 	var (
@@ -57,6 +99,39 @@ func TestCreateListItem(t *testing.T) {
 	assert.Equal(t, int64(3), shopList.Count)
 }
 
+func TestCreateListItemMerge(t *testing.T) {
+	prepareTestEnv(t)
+	const authInGroup = "1234567890fakefirebaseid0001"
+
+	flour := models.ListItem{
+		Title:        swag.String("Flour"),
+		Category:     swag.String("Groceries"),
+		Count:        swag.Int64(1),
+		Unit:         models.UnitGram,
+		Quantity:     swag.Float64(500),
+		RequestedFor: []string{authInGroup},
+	}
+	var created models.ListItem
+	req := NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist?merge=true", flour)
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &created)
+
+	var merged models.ListItem
+	flour.Unit = models.UnitKilogram
+	flour.Quantity = swag.Float64(1)
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist?merge=true", flour)
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &merged)
+	assert.Equal(t, created.ID, merged.ID)
+	assert.Equal(t, models.UnitGram, merged.Unit)
+	assert.Equal(t, 1500.0, *merged.Quantity)
+
+	// Invalid units
+	flour.Unit = "oz"
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist", flour)
+	MakeRequest(t, req, http.StatusUnprocessableEntity)
+}
+
 func TestUpdateListItem(t *testing.T) {
 	prepareTestEnv(t)
 	var (
@@ -64,7 +139,7 @@ func TestUpdateListItem(t *testing.T) {
 		groupUID    = "00112233-4455-6677-8899-aabbccddeeff"
 		uItem       = models.ListItem{}
 		item        = models.ListItem{
-			ID:           "00112233-4455-6677-8899-000000000001",
+			ID:           "00112233-4455-6677-8899-000000000002",
 			GroupUID:     strfmt.UUID(groupUID),
 			Title:        swag.String("New Milk"),
 			Category:     swag.String("New Groceries"),
@@ -82,6 +157,11 @@ func TestUpdateListItem(t *testing.T) {
 	assert.Equal(t, int64(0), uItem.Price)
 	assert.Equal(t, int64(2), *uItem.Count)
 	assert.NotEqual(t, uItem.CreatedAt, uItem.UpdatedAt)
+
+	// Billed items can't be changed
+	item.ID = "00112233-4455-6677-8899-000000000001"
+	req = NewRequestWithJSON(t, "PUT", authInGroup, "/shoppinglist", item)
+	MakeRequest(t, req, http.StatusBadRequest)
 }
 
 func TestUpdateListItemInvalid(t *testing.T) {
@@ -98,7 +178,7 @@ func TestBuyListItems(t *testing.T) {
 	prepareTestEnv(t)
 	var (
 		boughByID = "1234567890fakefirebaseid0002"
-		items     = []string{"00112233-4455-6677-8899-000000000002", "00112233-4455-6677-8899-000000000003"}
+		items     = []string{"00112233-4455-6677-8899-000000000002", "00112233-4455-6677-8899-000000000005"}
 		req       = NewRequestWithJSON(t, "POST", boughByID,
 			"/shoppinglist/buy-items", items)
 	)
@@ -109,6 +189,69 @@ func TestBuyListItems(t *testing.T) {
 	assert.Equal(t, boughByID, listItem.BoughtBy)
 }
 
+func TestBuyListItemsAlreadyBought(t *testing.T) {
+	prepareTestEnv(t)
+	const authInGroup = "1234567890fakefirebaseid0001"
+
+	// Eggs were bought by the other flatmate
+	var conflict models.BuyConflict
+	items := []string{"00112233-4455-6677-8899-000000000002", "00112233-4455-6677-8899-000000000004"}
+	req := NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/buy-items", items)
+	resp := MakeRequest(t, req, http.StatusConflict)
+	DecodeJSON(t, resp, &conflict)
+	if assert.Len(t, conflict.ListItems, 1) {
+		assert.EqualValues(t, items[1], conflict.ListItems[0].ID)
+		assert.Equal(t, "1234567890fakefirebaseid0002", conflict.ListItems[0].BoughtBy)
+	}
+
+	// Nothing was bought
+	listItem := models.AssertExistsAndLoadBean(t,
+		&models.ListItem{ID: strfmt.UUID(items[0])}).(*models.ListItem)
+	assert.Nil(t, listItem.BoughtAt)
+}
+
+func TestBuyListItemsIdempotencyKey(t *testing.T) {
+	prepareTestEnv(t)
+	const authInGroup = "1234567890fakefirebaseid0001"
+	items := []string{"00112233-4455-6677-8899-000000000002"}
+
+	req := NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/buy-items", items)
+	req.Header.Set("Idempotency-Key", "buy-1")
+	first := MakeRequest(t, req, http.StatusOK)
+
+	// The retry isn't applied again
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/buy-items", items)
+	req.Header.Set("Idempotency-Key", "buy-1")
+	retry := MakeRequest(t, req, http.StatusOK)
+	assert.Equal(t, "true", retry.Headers.Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body, retry.Body)
+
+	// The same key with another body
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/buy-items",
+		[]string{"00112233-4455-6677-8899-000000000005"})
+	req.Header.Set("Idempotency-Key", "buy-1")
+	MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+	// Without a key the item is bought already
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/buy-items", items)
+	MakeRequest(t, req, http.StatusConflict)
+
+	// Another request with the key is still in progress
+	items = []string{"00112233-4455-6677-8899-000000000005"}
+	data, _ := json.Marshal(items)
+	sum := sha256.Sum256(append([]byte("buyListItems\n"), data...))
+	_, _, err := models.BeginIdempotentRequest(authInGroup, "buy-2", hex.EncodeToString(sum[:]))
+	assert.NoError(t, err)
+
+	var conflict models.BuyConflict
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/shoppinglist/buy-items", items)
+	req.Header.Set("Idempotency-Key", "buy-2")
+	resp := MakeRequest(t, req, http.StatusConflict)
+	DecodeJSON(t, resp, &conflict)
+	assert.EqualValues(t, http.StatusConflict, *conflict.Status)
+	assert.Empty(t, conflict.ListItems)
+}
+
 func TestBuyListItemsThatDoNotExist(t *testing.T) {
 	prepareTestEnv(t)
 	var (
@@ -119,6 +262,45 @@ func TestBuyListItemsThatDoNotExist(t *testing.T) {
 	MakeRequest(t, req, http.StatusBadRequest)
 }
 
+func TestBuyListItemPartially(t *testing.T) {
+	prepareTestEnv(t)
+	const authInGroup = "1234567890fakefirebaseid0001"
+
+	var result models.PartialPurchaseResult
+	req := NewRequestWithJSON(t, "POST", authInGroup,
+		"/shoppinglist/item/00112233-4455-6677-8899-000000000005/purchases",
+		models.PartialPurchase{Count: swag.Int64(2), Price: swag.Int64(20)})
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &result)
+	assert.EqualValues(t, 2, *result.ListItem.Count)
+	assert.Equal(t, authInGroup, result.ListItem.BoughtBy)
+	if assert.NotNil(t, result.Remainder) {
+		assert.EqualValues(t, 18, *result.Remainder.Count)
+		assert.EqualValues(t, "00112233-4455-6677-8899-000000000005", result.Remainder.RemainderOf)
+	}
+
+	var purchases models.PurchaseList
+	req = NewRequest(t, "GET", authInGroup,
+		"/shoppinglist/item/"+string(result.Remainder.ID)+"/purchases")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &purchases)
+	if assert.Len(t, purchases.Purchases, 1) {
+		assert.True(t, purchases.Purchases[0].Partial)
+	}
+
+	// More than requested
+	req = NewRequestWithJSON(t, "POST", authInGroup,
+		"/shoppinglist/item/"+string(result.Remainder.ID)+"/purchases",
+		models.PartialPurchase{Count: swag.Int64(19)})
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	// Already bought
+	req = NewRequestWithJSON(t, "POST", authInGroup,
+		"/shoppinglist/item/00112233-4455-6677-8899-000000000005/purchases",
+		models.PartialPurchase{Count: swag.Int64(1)})
+	MakeRequest(t, req, http.StatusConflict)
+}
+
 func TestUnBuyListItems(t *testing.T) {
 	prepareTestEnv(t)
 	var (
@@ -148,3 +330,89 @@ func TestUnBuyListItemsWithBill(t *testing.T) {
 		&models.ListItem{ID: strfmt.UUID(item)}).(*models.ListItem)
 	assert.Equal(t, boughtByID, listItem.BoughtBy)
 }
+
+func TestGetListItem(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		item        models.ListItem
+		authInGroup = "1234567890fakefirebaseid0001"
+		req         = NewRequest(t, "GET", authInGroup,
+			"/shoppinglist/item/00112233-4455-6677-8899-000000000002")
+		resp = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &item)
+	assert.Equal(t, "Apples", *item.Title)
+
+	// Unknown item
+	req = NewRequest(t, "GET", authInGroup, "/shoppinglist/item/00112233-4455-6677-8899-ccbbaa000000")
+	MakeRequest(t, req, http.StatusNotFound)
+
+	// User without a group
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0003",
+		"/shoppinglist/item/00112233-4455-6677-8899-000000000002")
+	MakeRequest(t, req, http.StatusNotFound)
+}
+
+func TestDeleteListItem(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		requester = "1234567890fakefirebaseid0001"
+		member    = "1234567890fakefirebaseid0002"
+		itemUID   = "00112233-4455-6677-8899-000000000002"
+	)
+
+	// Neither requester nor admin
+	req := NewRequest(t, "DELETE", member, "/shoppinglist/item/"+itemUID)
+	MakeRequest(t, req, http.StatusUnauthorized)
+
+	// Bought and billed items can't be deleted
+	req = NewRequest(t, "DELETE", requester, "/shoppinglist/item/00112233-4455-6677-8899-000000000004")
+	MakeRequest(t, req, http.StatusBadRequest)
+	req = NewRequest(t, "DELETE", requester, "/shoppinglist/item/00112233-4455-6677-8899-000000000001")
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	req = NewRequest(t, "DELETE", requester, "/shoppinglist/item/"+itemUID)
+	MakeRequest(t, req, http.StatusOK)
+	models.AssertNotExistsBean(t, &models.ListItem{ID: strfmt.UUID(itemUID)})
+}
+
+func TestUpdateListItemIfMatch(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		authInGroup = "1234567890fakefirebaseid0001"
+		itemURL     = "/shoppinglist/item/00112233-4455-6677-8899-000000000002"
+		item        = models.ListItem{
+			ID:           "00112233-4455-6677-8899-000000000002",
+			Title:        swag.String("Pears"),
+			Category:     swag.String("Groceries"),
+			Count:        swag.Int64(3),
+			RequestedFor: []string{authInGroup},
+		}
+	)
+
+	req := NewRequest(t, "GET", authInGroup, itemURL)
+	resp := MakeRequest(t, req, http.StatusOK)
+	tag := resp.Headers.Get("ETag")
+	assert.Equal(t, `"1"`, tag)
+
+	req = NewRequestWithJSON(t, "PUT", authInGroup, "/shoppinglist", item)
+	req.Header.Set("If-Match", tag)
+	resp = MakeRequest(t, req, http.StatusOK)
+	assert.Equal(t, `"2"`, resp.Headers.Get("ETag"))
+
+	// The other flatmate still has the old version
+	var current models.ListItem
+	item.Title = swag.String("Plums")
+	req = NewRequestWithJSON(t, "PUT", "1234567890fakefirebaseid0002", "/shoppinglist", item)
+	req.Header.Set("If-Match", tag)
+	resp = MakeRequest(t, req, http.StatusPreconditionFailed)
+	assert.Equal(t, `"2"`, resp.Headers.Get("ETag"))
+	DecodeJSON(t, resp, &current)
+	assert.Equal(t, "Pears", *current.Title)
+	assert.EqualValues(t, 2, current.Version)
+
+	// Items that don't exist
+	item.ID = "00112233-4455-6677-8899-000000000099"
+	req = NewRequestWithJSON(t, "PUT", authInGroup, "/shoppinglist", item)
+	MakeRequest(t, req, http.StatusNotFound)
+}
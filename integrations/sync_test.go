@@ -0,0 +1,97 @@
+package integrations
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/wgplaner/wg_planer_server/models"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSyncChanges(t *testing.T) {
+	prepareTestEnv(t)
+	const authInGroup = "1234567890fakefirebaseid0001"
+
+	var snapshot models.SyncChanges
+	req := NewRequest(t, "GET", authInGroup, "/sync")
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &snapshot)
+	assert.True(t, snapshot.Full)
+	assert.Len(t, snapshot.ListItems, 5)
+	assert.Len(t, snapshot.Users, 2)
+	assert.NotEmpty(t, snapshot.Cursor)
+
+	req = NewRequest(t, "DELETE", authInGroup, "/shoppinglist/item/00112233-4455-6677-8899-000000000005")
+	MakeRequest(t, req, http.StatusOK)
+
+	var changes models.SyncChanges
+	req = NewRequest(t, "GET", authInGroup, "/sync?since="+url.QueryEscape(snapshot.Cursor))
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &changes)
+	assert.False(t, changes.Full)
+	assert.Empty(t, changes.ListItems)
+	assert.Equal(t, []*models.SyncTombstone{
+		{Type: models.SyncTypeListItem, ID: "00112233-4455-6677-8899-000000000005"},
+	}, changes.Tombstones)
+
+	// Invalid cursor
+	req = NewRequest(t, "GET", authInGroup, "/sync?since=invalid")
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	// Users without a group
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0003", "/sync")
+	MakeRequest(t, req, http.StatusNotFound)
+}
+
+func TestApplySyncMutations(t *testing.T) {
+	prepareTestEnv(t)
+	const authInGroup = "1234567890fakefirebaseid0001"
+
+	var snapshot models.SyncChanges
+	req := NewRequest(t, "GET", authInGroup, "/sync")
+	resp := MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &snapshot)
+
+	// Changed by another member after the snapshot
+	req = NewRequestWithJSON(t, "PUT", "1234567890fakefirebaseid0002",
+		"/shoppinglist", models.ListItem{
+			ID:           "00112233-4455-6677-8899-000000000005",
+			Title:        swag.String("Green apples"),
+			Category:     swag.String("Groceries"),
+			Count:        swag.Int64(20),
+			RequestedFor: []string{"1234567890fakefirebaseid0002"},
+		})
+	MakeRequest(t, req, http.StatusOK)
+
+	var results models.SyncMutationResults
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/sync", models.SyncMutationBatch{
+		Cursor: snapshot.Cursor,
+		Mutations: []*models.SyncMutation{
+			{ID: "a", Type: models.SyncTypeListItem, Op: models.SyncOpBuy,
+				EntityID: "00112233-4455-6677-8899-000000000002"},
+			{ID: "b", Type: models.SyncTypeListItem, Op: models.SyncOpBuy,
+				EntityID: "00112233-4455-6677-8899-000000000005"},
+		},
+	})
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &results)
+	if assert.Len(t, results.Results, 2) {
+		assert.Equal(t, "a", results.Results[0].ID)
+		assert.Equal(t, models.SyncStatusApplied, results.Results[0].Status)
+		assert.Equal(t, "b", results.Results[1].ID)
+		assert.Equal(t, models.SyncStatusConflict, results.Results[1].Status)
+		if assert.NotNil(t, results.Results[1].ListItem) {
+			assert.Equal(t, "Green apples", *results.Results[1].ListItem.Title)
+		}
+	}
+
+	// Missing cursor
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/sync", models.SyncMutationBatch{
+		Cursor:    "invalid",
+		Mutations: []*models.SyncMutation{},
+	})
+	MakeRequest(t, req, http.StatusBadRequest)
+}
@@ -0,0 +1,88 @@
+package integrations
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+	"github.com/wgplaner/wg_planer_server/models"
+)
+
+func TestGetTaskList(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		list        models.TaskList
+		authInGroup = "1234567890fakefirebaseid0001"
+		req         = NewRequest(t, "GET", authInGroup, "/group/tasks?upcoming=4")
+		resp        = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &list)
+	assert.Equal(t, int64(2), list.Count)
+	assert.Len(t, list.Tasks, 2)
+	assert.Len(t, list.Tasks[0].Upcoming, 4)
+
+	// User without group
+	req = NewRequest(t, "GET", "1234567890fakefirebaseid0003", "/group/tasks")
+	MakeRequest(t, req, http.StatusNotFound)
+}
+
+func TestCreateTask(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		task        models.Task
+		authInGroup = "1234567890fakefirebaseid0001"
+		newTask     = models.Task{
+			Title:      swag.String("Vacuum"),
+			Recurrence: swag.String(models.RecurrenceWeekly),
+			Rotation:   []string{"1234567890fakefirebaseid0002", authInGroup},
+		}
+		req  = NewRequestWithJSON(t, "POST", authInGroup, "/group/tasks", newTask)
+		resp = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &task)
+	assert.Equal(t, "1234567890fakefirebaseid0002", task.Assignee)
+	assert.Equal(t, authInGroup, task.CreatedBy)
+	models.AssertExistsAndLoadBean(t, &models.Task{ID: task.ID})
+
+	// User is not a member of the group
+	newTask.Rotation = []string{"1234567890fakefirebaseid0003"}
+	req = NewRequestWithJSON(t, "POST", authInGroup, "/group/tasks", newTask)
+	MakeRequest(t, req, http.StatusBadRequest)
+}
+
+func TestTaskTurns(t *testing.T) {
+	prepareTestEnv(t)
+	var (
+		task        models.Task
+		authInGroup = "1234567890fakefirebaseid0001"
+		url         = "/group/tasks/00112233-4455-6677-8899-789000000001"
+		req         = NewRequest(t, "POST", authInGroup, url+"/done")
+		resp        = MakeRequest(t, req, http.StatusOK)
+	)
+	DecodeJSON(t, resp, &task)
+	assert.Equal(t, "1234567890fakefirebaseid0002", task.Assignee)
+
+	req = NewRequest(t, "POST", authInGroup, url+"/skip")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &task)
+	assert.Equal(t, authInGroup, task.Assignee)
+
+	req = NewRequestWithJSON(t, "POST", authInGroup, url+"/swap", "1234567890fakefirebaseid0002")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &task)
+	assert.Equal(t, "1234567890fakefirebaseid0002", task.Assignee)
+
+	req = NewRequestWithJSON(t, "POST", authInGroup, url+"/swap", "1234567890fakefirebaseid0003")
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	var history models.TaskHistory
+	req = NewRequest(t, "GET", authInGroup, url+"/history")
+	resp = MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &history)
+	assert.Equal(t, int64(4), history.Count)
+
+	// Task of another group
+	req = NewRequest(t, "POST", "1234567890fakefirebaseid0004", url+"/done")
+	MakeRequest(t, req, http.StatusNotFound)
+}
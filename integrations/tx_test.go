@@ -0,0 +1,84 @@
+package integrations
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/wgplaner/wg_planer_server/models"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestFailpoint = errors.New("failpoint")
+
+func TestCreateGroupRollback(t *testing.T) {
+	prepareTestEnv(t)
+	models.EnableFailpoint("CreateGroupForUser", errTestFailpoint)
+	defer models.DisableFailpoints()
+
+	var (
+		userID   = "1234567890fakefirebaseid0003"
+		newGroup = models.Group{DisplayName: swag.String("New Group")}
+		req      = NewRequestWithJSON(t, "POST", userID, "/group", newGroup)
+	)
+	MakeRequest(t, req, http.StatusInternalServerError)
+
+	u := models.AssertExistsAndLoadBean(t, &models.User{UID: swag.String(userID)}).(*models.User)
+	assert.Empty(t, u.GroupUID)
+	models.AssertCount(t, &models.Group{}, 2)
+}
+
+func TestCreateBillRollback(t *testing.T) {
+	prepareTestEnv(t)
+	models.EnableFailpoint("CreateBillForUser", errTestFailpoint)
+	defer models.DisableFailpoints()
+
+	var (
+		itemUID = "00112233-4455-6677-8899-000000000004"
+		newBill = models.Bill{BoughtItems: []string{itemUID}}
+		req     = NewRequestWithJSON(t, "POST", "1234567890fakefirebaseid0002", "/group/bills/create", newBill)
+	)
+	MakeRequest(t, req, http.StatusInternalServerError)
+
+	models.AssertCount(t, &models.Bill{}, 1)
+	item := models.AssertExistsAndLoadBean(t, &models.ListItem{ID: itemUID}).(*models.ListItem)
+	assert.Empty(t, item.BillUID)
+}
+
+func TestLeaveGroupRollback(t *testing.T) {
+	prepareTestEnv(t)
+	models.EnableFailpoint("User.LeaveGroup", errTestFailpoint)
+	defer models.DisableFailpoints()
+
+	var (
+		admin    = "1234567890fakefirebaseid0001"
+		groupUID = "00112233-4455-6677-8899-aabbccddeeff"
+		req      = NewRequest(t, "POST", admin, "/group/leave")
+	)
+	MakeRequest(t, req, http.StatusInternalServerError)
+
+	// The admin is still a member and admin of the group
+	u := models.AssertExistsAndLoadBean(t, &models.User{UID: swag.String(admin)}).(*models.User)
+	assert.Equal(t, groupUID, string(u.GroupUID))
+	g := models.AssertExistsAndLoadBean(t, &models.Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*models.Group)
+	assert.Equal(t, []string{admin}, g.Admins)
+	task := models.AssertExistsAndLoadBean(t, &models.Task{ID: "00112233-4455-6677-8899-789000000001"}).(*models.Task)
+	assert.Contains(t, task.Rotation, admin)
+}
+
+func TestJoinGroupRollback(t *testing.T) {
+	prepareTestEnv(t)
+	models.EnableFailpoint("User.JoinGroupWithCode", errTestFailpoint)
+	defer models.DisableFailpoints()
+
+	var (
+		userID = "1234567890fakefirebaseid0003"
+		req    = NewRequest(t, "POST", userID, "/group/join/ABCDEFGHI123")
+	)
+	MakeRequest(t, req, http.StatusInternalServerError)
+
+	u := models.AssertExistsAndLoadBean(t, &models.User{UID: swag.String(userID)}).(*models.User)
+	assert.Empty(t, u.GroupUID)
+}
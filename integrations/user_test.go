@@ -223,3 +223,25 @@ func TestUpdateUser(t *testing.T) {
 		)
 	}
 }
+
+func TestUpdateUserKeepsMailSettings(t *testing.T) {
+	prepareTestEnv(t)
+	uid := "1234567890fakefirebaseid0002"
+
+	u, err := models.GetUserByUID(uid)
+	assert.NoError(t, err)
+	u.Locale = "de"
+	u.MailOptOut = []string{"group_digest"}
+	assert.NoError(t, models.UpdateUserCols(u, "locale", "mail_opt_out"))
+
+	// Neither locale nor mailOptOut are sent
+	req := NewRequestWithJSON(t, "PUT", uid, "/users", models.User{
+		UID:         &uid,
+		DisplayName: swag.String("Maxi Meier"),
+	})
+	MakeRequest(t, req, http.StatusOK)
+
+	u = models.AssertExistsAndLoadBean(t, &models.User{UID: &uid}).(*models.User)
+	assert.Equal(t, "de", u.Locale)
+	assert.Equal(t, []string{"group_digest"}, u.MailOptOut)
+}
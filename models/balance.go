@@ -0,0 +1,209 @@
+package models
+
+import (
+	"strconv"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// MemberBalance member balance
+// swagger:model MemberBalance
+type MemberBalance struct {
+	// id
+	ID int64 `xorm:"pk autoincr" json:"-"`
+
+	// group UID
+	// Read Only: true
+	GroupUID strfmt.UUID `xorm:"VARCHAR(36) INDEX unique(member)" json:"groupUID,omitempty"`
+
+	// user UID
+	// Required: true
+	UserUID *string `xorm:"VARCHAR(28) unique(member)" json:"userUID"`
+
+	// net balance in cents. Positive if the group owes the user money.
+	// Required: true
+	Balance *int64 `xorm:"NOT NULL DEFAULT 0" json:"balance"`
+
+	// updated at
+	// Read Only: true
+	UpdatedAt strfmt.DateTime `xorm:"updated" json:"updatedAt,omitempty"`
+}
+
+// Validate validates this member balance
+func (m *MemberBalance) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := validate.Required("userUID", "body", m.UserUID); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.Required("balance", "body", m.Balance); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *MemberBalance) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *MemberBalance) UnmarshalBinary(b []byte) error {
+	var res MemberBalance
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// Settlement settlement
+// swagger:model Settlement
+type Settlement struct {
+	// user that pays
+	// Required: true
+	From *string `json:"from"`
+
+	// user that receives the money
+	// Required: true
+	To *string `json:"to"`
+
+	// amount in cents
+	// Required: true
+	Amount *int64 `json:"amount"`
+}
+
+// Validate validates this settlement
+func (m *Settlement) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := validate.Required("from", "body", m.From); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.Required("to", "body", m.To); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.Required("amount", "body", m.Amount); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *Settlement) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *Settlement) UnmarshalBinary(b []byte) error {
+	var res Settlement
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// GroupBalances group balances
+// swagger:model GroupBalances
+type GroupBalances struct {
+	// group UID
+	// Read Only: true
+	GroupUID strfmt.UUID `json:"groupUID,omitempty"`
+
+	// currency
+	// Read Only: true
+	Currency string `json:"currency,omitempty"`
+
+	// balances
+	// Required: true
+	// Read Only: true
+	Balances []*MemberBalance `json:"balances"`
+
+	// settlements
+	// Required: true
+	// Read Only: true
+	Settlements []*Settlement `json:"settlements"`
+}
+
+// Validate validates this group balances
+func (m *GroupBalances) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := m.validateBalances(formats); err != nil {
+		res = append(res, err)
+	}
+	if err := m.validateSettlements(formats); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *GroupBalances) validateBalances(formats strfmt.Registry) error {
+	if err := validate.Required("balances", "body", m.Balances); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Balances); i++ {
+		if m.Balances[i] == nil {
+			continue
+		}
+		if err := m.Balances[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("balances" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *GroupBalances) validateSettlements(formats strfmt.Registry) error {
+	if err := validate.Required("settlements", "body", m.Settlements); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Settlements); i++ {
+		if m.Settlements[i] == nil {
+			continue
+		}
+		if err := m.Settlements[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("settlements" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *GroupBalances) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *GroupBalances) UnmarshalBinary(b []byte) error {
+	var res GroupBalances
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
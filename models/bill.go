@@ -1,13 +1,30 @@
 package models
 
 import (
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+
 	"github.com/go-openapi/errors"
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
 	"github.com/satori/go.uuid"
 )
 
+// Bill states. A bill is created as a draft. Sending it to the group members
+// makes it "sent". It is "partial" as long as only some of the recipients
+// have paid their share and "paid" if all of them did.
+// Only drafts and sent bills can be cancelled.
+const (
+	BillStateDraft     = "draft"
+	BillStateSent      = "sent"
+	BillStatePartial   = "partial"
+	BillStatePaid      = "paid"
+	BillStateCancelled = "cancelled"
+)
+
 // Bill bill
 // swagger:model Bill
 
@@ -41,9 +58,9 @@ type Bill struct {
 	// Read Only: true
 	GroupUID strfmt.UUID `json:"groupUID,omitempty"`
 
-	// state (can be e.g. paid, todo)
+	// state (one of draft, sent, partial, paid, cancelled)
 	// Required: true
-	State *string `xorm:"VARCHAR(5)" json:"state"`
+	State *string `xorm:"VARCHAR(16)" json:"state"`
 
 	// sum
 	Sum int64 `xorm:"-" json:"sum,omitempty"`
@@ -55,6 +72,13 @@ type Bill struct {
 	// updated at
 	// Read Only: true
 	UpdatedAt strfmt.DateTime `xorm:"updated" json:"updatedAt,omitempty"`
+
+	// version (incremented on every change, used as ETag)
+	// Read Only: true
+	Version int64 `xorm:"NOT NULL DEFAULT 1" json:"version,omitempty"`
+
+	// reminder sent at (set once the recipients were reminded of the due date)
+	ReminderSentAt *time.Time `xorm:"NULL" json:"-"`
 }
 
 // Validate validates this bill
@@ -102,6 +126,21 @@ func (m *Bill) GetListItems() error {
 	return err
 }
 
+// GetBillByUIDs returns the bill with the given uid that belongs to the group.
+func GetBillByUIDs(guid, buid strfmt.UUID) (*Bill, error) {
+	b := new(Bill)
+
+	if has, err := x.Where(`group_uid=?`, guid).And(`uid=?`, buid).Get(b); err != nil {
+		return nil, err
+
+	} else if !has {
+		return nil, ErrBillNotExist{UID: buid, GroupUID: guid}
+	}
+
+	return b, nil
+}
+
+// GetBillsByGroupUID returns all bills of the group (without items).
 func GetBillsByGroupUID(guid strfmt.UUID) ([]*Bill, error) {
 	bills := make([]*Bill, 0, 5)
 
@@ -122,23 +161,21 @@ func GetBillsByGroupUIDWithBoughtItems(guid strfmt.UUID) ([]*Bill, error) {
 
 	// Get items for each bill
 	for _, b := range bills {
-		err := b.GetListItems()
-		if err != nil {
+		if err := b.loadItemsAndSum(); err != nil {
 			return nil, err
 		}
-		b.Sum = 0
-		b.BoughtItems = []string{}
-		for _, item := range b.BoughtListItems {
-			b.BoughtItems = append(b.BoughtItems, string(item.ID))
-			b.Sum += item.Price
-		}
 	}
 
 	return bills, nil
 }
 
-// CreateBillForUser create a bill for a user
+// CreateBillForUser create a bill for a user. ErrBillNoItems is returned if
+// none of the items were bought by the user and are not billed yet.
 func CreateBillForUser(u *User, billWithItems *Bill) (*Bill, error) {
+	if len(billWithItems.BoughtItems) == 0 {
+		return nil, ErrBillNoItems{UserUID: *u.UID}
+	}
+
 	billUID, err := uuid.NewV4()
 	if err != nil {
 		return nil, err
@@ -151,22 +188,40 @@ func CreateBillForUser(u *User, billWithItems *Bill) (*Bill, error) {
 		SentTo:    []string{},
 		PayedBy:   []string{},
 		DueDate:   billWithItems.DueDate,
-		State:     swag.String("todo"),
+		State:     swag.String(BillStateDraft),
 		Sum:       0,
 		// TODO: Other fields
 	}
 
-	if _, err := x.InsertOne(b); err != nil {
-		return nil, err
-	}
+	err = withTx(func(sess *xorm.Session) error {
+		if _, err := sess.InsertOne(b); err != nil {
+			return err
+		}
 
-	_, err = x.
-		Cols(`bill_uid`).
-		Where(`(bill_uid IS NULL OR bill_uid="")`).
-		And(`bought_by = ?`, *u.UID).
-		In(`id`, billWithItems.BoughtItems).
-		Update(ListItem{BillUID: b.UID})
+		if err := failpoint("CreateBillForUser"); err != nil {
+			return err
+		}
 
+		if n, err := sess.
+			Cols(`bill_uid`).
+			Where(`(bill_uid IS NULL OR bill_uid = ?)`, "").
+			And(`bought_by = ?`, *u.UID).
+			In(`id`, billWithItems.BoughtItems).
+			Incr(`version`).
+			Update(ListItem{BillUID: b.UID}); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrBillNoItems{UserUID: *u.UID}
+		}
+
+		if err := recordChange(sess, b.GroupUID, SyncTypeBill, string(b.UID), false); err != nil {
+			return err
+		}
+		if err := recordBillItemChanges(sess, b.GroupUID, b.UID); err != nil {
+			return err
+		}
+		return recomputeGroupBalances(sess, b.GroupUID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -179,12 +234,214 @@ func CreateBillForUser(u *User, billWithItems *Bill) (*Bill, error) {
 
 	for _, item := range b.BoughtListItems {
 		b.BoughtItems = append(b.BoughtItems, string(item.ID))
-		b.Sum += item.Price
+		b.Sum += item.TotalPrice()
+	}
+
+	return b, err
+}
+
+// recordBillItemChanges records a change of all items of the bill.
+func recordBillItemChanges(sess *xorm.Session, guid, buid strfmt.UUID) error {
+	var ids []string
+	if err := sess.Table(new(ListItem)).
+		Where(`bill_uid=?`, buid).
+		Cols(`id`).
+		Find(&ids); err != nil {
+		return err
+	}
+	return recordChanges(sess, guid, SyncTypeListItem, ids, false)
+}
+
+// loadItemsAndSum loads the bill's items and calculates its sum.
+func (m *Bill) loadItemsAndSum() error {
+	if err := m.GetListItems(); err != nil {
+		return err
+	}
+	m.Sum = 0
+	m.BoughtItems = []string{}
+	for _, item := range m.BoughtListItems {
+		m.BoughtItems = append(m.BoughtItems, string(item.ID))
+		m.Sum += item.TotalPrice()
 	}
+	return nil
+}
 
+// checkTransition returns an error if the bill can't change from its current state to "to".
+func (m *Bill) checkTransition(to string, allowedFrom ...string) error {
+	state := swag.StringValue(m.State)
+	if !base.StringInSlice(state, allowedFrom) {
+		return ErrBillInvalidTransition{UID: m.UID, From: state, To: to}
+	}
+	return nil
+}
+
+// updateIfUnchanged updates the columns of the bill to the ones of "changed"
+// if it still has the state and version it was read with. Otherwise someone
+// else changed the bill in the meantime and ErrVersionMismatch is returned.
+func (m *Bill) updateIfUnchanged(sess *xorm.Session, changed *Bill, cols ...string) error {
+	affected, err := sess.
+		ID(m.UID).
+		And(`state=?`, swag.StringValue(m.State)).
+		And(`version=?`, m.Version).
+		Cols(cols...).
+		Incr(`version`).
+		Update(changed)
 	if err != nil {
-		return nil, err
+		return err
+	} else if affected == 0 {
+		return ErrVersionMismatch{Type: "bill", ID: string(m.UID), Version: m.Version}
 	}
+	return nil
+}
 
-	return b, err
+// IsPaidBy returns true if the user has paid his share of the bill.
+// The creator of a bill has always paid his share.
+func (m *Bill) IsPaidBy(uid string) bool {
+	return uid == swag.StringValue(m.CreatedBy) || base.StringInSlice(uid, m.PayedBy)
+}
+
+// isPaidByAllRecipients returns true if every recipient has paid his share.
+func (m *Bill) isPaidByAllRecipients() bool {
+	for _, uid := range m.SentTo {
+		if !m.IsPaidBy(uid) {
+			return false
+		}
+	}
+	return true
+}
+
+// Send sends a draft to the given recipients. All recipients have to be
+// members of the bill's group. If no recipients are given, the bill is sent to
+// all group members.
+func (m *Bill) Send(recipients []string) error {
+	if err := m.checkTransition(BillStateSent, BillStateDraft); err != nil {
+		return err
+	}
+
+	members, err := GetGroupMemberUIDs(m.GroupUID)
+	if err != nil {
+		return err
+	}
+
+	if len(recipients) == 0 {
+		recipients = members
+	}
+
+	recipients = base.Unique(recipients)
+	for _, uid := range recipients {
+		if !base.StringInSlice(uid, members) {
+			return ErrBillNotRecipient{UID: m.UID, UserUID: uid}
+		}
+	}
+
+	sent := *m
+	sent.SentTo = recipients
+	sent.PayedBy = []string{}
+	if base.StringInSlice(swag.StringValue(m.CreatedBy), recipients) {
+		sent.PayedBy = append(sent.PayedBy, *m.CreatedBy)
+	}
+	sent.State = swag.String(BillStateSent)
+	if sent.isPaidByAllRecipients() {
+		sent.State = swag.String(BillStatePaid)
+	}
+
+	if err = withTx(func(sess *xorm.Session) error {
+		if err := m.updateIfUnchanged(sess, &sent, `sent_to`, `payed_by`, `state`); err != nil {
+			return err
+		}
+		return recordChange(sess, m.GroupUID, SyncTypeBill, string(m.UID), false)
+	}); err != nil {
+		return err
+	}
+	*m = sent
+	m.Version++
+
+	return m.loadItemsAndSum()
+}
+
+// Pay marks the share of the given user as paid. The bill is "paid"
+// as soon as all recipients have paid their share.
+func (m *Bill) Pay(uid string) error {
+	if err := m.checkTransition(BillStatePaid, BillStateSent, BillStatePartial); err != nil {
+		return err
+	}
+
+	if !base.StringInSlice(uid, m.SentTo) {
+		return ErrBillNotRecipient{UID: m.UID, UserUID: uid}
+	}
+
+	if base.StringInSlice(uid, m.PayedBy) {
+		return ErrBillAlreadyPaid{UID: m.UID, UserUID: uid}
+	}
+
+	paid := *m
+	paid.PayedBy = append(append([]string{}, m.PayedBy...), uid)
+	paid.State = swag.String(BillStatePartial)
+	if paid.isPaidByAllRecipients() {
+		paid.State = swag.String(BillStatePaid)
+	}
+
+	if err := withTx(func(sess *xorm.Session) error {
+		if err := m.updateIfUnchanged(sess, &paid, `payed_by`, `state`); err != nil {
+			return err
+		}
+		if err := recordChange(sess, m.GroupUID, SyncTypeBill, string(m.UID), false); err != nil {
+			return err
+		}
+		return recomputeGroupBalances(sess, m.GroupUID)
+	}); err != nil {
+		return err
+	}
+	*m = paid
+	m.Version++
+
+	return m.loadItemsAndSum()
+}
+
+// Cancel cancels the bill. Its items are released so that
+// they can be added to another bill.
+func (m *Bill) Cancel() error {
+	if err := m.checkTransition(BillStateCancelled, BillStateDraft, BillStateSent); err != nil {
+		return err
+	}
+
+	err := withTx(func(sess *xorm.Session) error {
+		if err := m.updateIfUnchanged(sess, &Bill{
+			State: swag.String(BillStateCancelled),
+		}, `state`); err != nil {
+			return err
+		}
+
+		if err := failpoint("Bill.Cancel"); err != nil {
+			return err
+		}
+
+		// Record the items before they are released
+		if err := recordBillItemChanges(sess, m.GroupUID, m.UID); err != nil {
+			return err
+		}
+		if _, err := sess.
+			Cols(`bill_uid`).
+			Where(`bill_uid=?`, m.UID).
+			Incr(`version`).
+			Update(&ListItem{BillUID: ""}); err != nil {
+			return err
+		}
+		if err := recordChange(sess, m.GroupUID, SyncTypeBill, string(m.UID), false); err != nil {
+			return err
+		}
+		return recomputeGroupBalances(sess, m.GroupUID)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.State = swag.String(BillStateCancelled)
+	m.Version++
+
+	m.BoughtItems = []string{}
+	m.BoughtListItems = []ListItem{}
+	m.Sum = 0
+
+	return nil
 }
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testGroupUID = strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+	testBillUID  = strfmt.UUID("00112233-4455-6677-8899-123000000001")
+)
+
+func TestGetBillByUIDs(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	b1, err1 := GetBillByUIDs(testGroupUID, testBillUID)
+	assert.NoError(t, err1)
+	assert.Equal(t, BillStatePartial, *b1.State)
+
+	_, err2 := GetBillByUIDs("00112233-4455-6677-8899-aabbccddeef0", testBillUID)
+	assert.True(t, IsErrBillNotExist(err2))
+}
+
+func TestBill_Pay(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	b, err := GetBillByUIDs(testGroupUID, testBillUID)
+	assert.NoError(t, err)
+
+	assert.True(t, IsErrBillNotRecipient(b.Pay("1234567890fakefirebaseid0003")))
+	assert.True(t, IsErrBillAlreadyPaid(b.Pay("1234567890fakefirebaseid0001")))
+
+	assert.NoError(t, b.Pay("1234567890fakefirebaseid0002"))
+	assert.Equal(t, BillStatePaid, *b.State)
+	assert.Len(t, b.PayedBy, 2)
+
+	// All shares are paid
+	b1 := AssertExistsAndLoadBean(t, &MemberBalance{UserUID: swag.String("1234567890fakefirebaseid0001")}).(*MemberBalance)
+	b2 := AssertExistsAndLoadBean(t, &MemberBalance{UserUID: swag.String("1234567890fakefirebaseid0002")}).(*MemberBalance)
+	assert.Equal(t, int64(0), *b1.Balance)
+	assert.Equal(t, int64(0), *b2.Balance)
+
+	assert.True(t, IsErrBillInvalidTransition(b.Pay("1234567890fakefirebaseid0002")))
+	assert.True(t, IsErrBillInvalidTransition(b.Cancel()))
+}
+
+func TestBill_PayConflict(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	b, err := GetBillByUIDs(testGroupUID, testBillUID)
+	assert.NoError(t, err)
+	stale, err := GetBillByUIDs(testGroupUID, testBillUID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, b.Pay("1234567890fakefirebaseid0002"))
+
+	// The stale copy is not applied on top of the payment
+	assert.True(t, IsErrVersionMismatch(stale.Pay("1234567890fakefirebaseid0002")))
+	assert.Equal(t, BillStatePartial, *stale.State)
+	assert.Len(t, stale.PayedBy, 1)
+
+	b, err = GetBillByUIDs(testGroupUID, testBillUID)
+	assert.NoError(t, err)
+	assert.Equal(t, BillStatePaid, *b.State)
+	assert.Len(t, b.PayedBy, 2)
+}
+
+func TestBill_SendAndCancel(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	u, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+
+	b, err := CreateBillForUser(u, &Bill{BoughtItems: []string{"00112233-4455-6677-8899-000000000004"}})
+	assert.NoError(t, err)
+	assert.Equal(t, BillStateDraft, *b.State)
+
+	assert.True(t, IsErrBillNotRecipient(b.Send([]string{"1234567890fakefirebaseid0003"})))
+
+	assert.NoError(t, b.Send(nil))
+	assert.Equal(t, BillStateSent, *b.State)
+	assert.Len(t, b.SentTo, 2)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0002"}, b.PayedBy)
+	assert.Equal(t, int64(129), b.Sum)
+
+	assert.True(t, IsErrBillInvalidTransition(b.Send(nil)))
+
+	assert.NoError(t, b.Cancel())
+	assert.Equal(t, BillStateCancelled, *b.State)
+
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000004"}).(*ListItem)
+	assert.Empty(t, item.BillUID)
+
+	// Eggs are no longer part of the ledger
+	b1 := AssertExistsAndLoadBean(t, &MemberBalance{UserUID: swag.String("1234567890fakefirebaseid0001")}).(*MemberBalance)
+	assert.Equal(t, int64(50), *b1.Balance)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/go-openapi/strfmt"
 )
@@ -163,6 +164,54 @@ func (err ErrGroupInvalidUUID) Error() string {
 	return fmt.Sprintf("invalid group UUID [%s]", err.UID)
 }
 
+// ErrGroupNotMember represents a "user is not a member of the group" kind of error.
+type ErrGroupNotMember struct {
+	UID     strfmt.UUID
+	UserUID string
+}
+
+// IsErrGroupNotMember checks if an error is a ErrGroupNotMember.
+func IsErrGroupNotMember(err error) bool {
+	_, ok := err.(ErrGroupNotMember)
+	return ok
+}
+
+func (err ErrGroupNotMember) Error() string {
+	return fmt.Sprintf("user is not a member of the group [uid: %s, user: %s]", err.UID, err.UserUID)
+}
+
+// ErrGroupLastAdmin represents a "can't remove the last admin" kind of error.
+type ErrGroupLastAdmin struct {
+	UID     strfmt.UUID
+	UserUID string
+}
+
+// IsErrGroupLastAdmin checks if an error is a ErrGroupLastAdmin.
+func IsErrGroupLastAdmin(err error) bool {
+	_, ok := err.(ErrGroupLastAdmin)
+	return ok
+}
+
+func (err ErrGroupLastAdmin) Error() string {
+	return fmt.Sprintf("user is the last admin of the group [uid: %s, user: %s]", err.UID, err.UserUID)
+}
+
+// ErrGroupNotAdmin represents a "user is not an admin of the group" kind of error.
+type ErrGroupNotAdmin struct {
+	UID     strfmt.UUID
+	UserUID string
+}
+
+// IsErrGroupNotAdmin checks if an error is a ErrGroupNotAdmin.
+func IsErrGroupNotAdmin(err error) bool {
+	_, ok := err.(ErrGroupNotAdmin)
+	return ok
+}
+
+func (err ErrGroupNotAdmin) Error() string {
+	return fmt.Sprintf("user is not an admin of the group [uid: %s, user: %s]", err.UID, err.UserUID)
+}
+
 //  ____  _                       _               _     _     _
 // / ___|| |__   ___  _ __  _ __ (_)_ __   __ _  | |   (_)___| |_
 // \___ \| '_ \ / _ \| '_ \| '_ \| | '_ \ / _` | | |   | / __| __|
@@ -204,8 +253,331 @@ func (err ErrListItemHasBill) Error() string {
 		err.GroupUID, err.ID)
 }
 
+// ErrListItemIsBought represents a "list item has already been bought" kind of error.
+type ErrListItemIsBought struct {
+	ID       strfmt.UUID
+	GroupUID strfmt.UUID
+}
+
+// IsErrListItemIsBought checks if an error is a ErrListItemIsBought.
+func IsErrListItemIsBought(err error) bool {
+	_, ok := err.(ErrListItemIsBought)
+	return ok
+}
+
+func (err ErrListItemIsBought) Error() string {
+	return fmt.Sprintf("list item has already been bought [groupUID: %s, uid: %s]",
+		err.GroupUID, err.ID)
+}
+
+// ErrListItemsAlreadyBought represents a "some list items were bought in the meantime" kind of error.
+type ErrListItemsAlreadyBought struct {
+	GroupUID strfmt.UUID
+	// Items is the current state of the items that were already bought.
+	Items []*ListItem
+}
+
+// IsErrListItemsAlreadyBought checks if an error is a ErrListItemsAlreadyBought.
+func IsErrListItemsAlreadyBought(err error) bool {
+	_, ok := err.(ErrListItemsAlreadyBought)
+	return ok
+}
+
+func (err ErrListItemsAlreadyBought) Error() string {
+	ids := make([]string, 0, len(err.Items))
+	for _, item := range err.Items {
+		ids = append(ids, string(item.ID))
+	}
+	return fmt.Sprintf("list items have already been bought [groupUID: %s, uids: %s]",
+		err.GroupUID, strings.Join(ids, ", "))
+}
+
+// ErrListItemInvalidPurchaseCount represents a "count of a purchase is out of range" kind of error.
+type ErrListItemInvalidPurchaseCount struct {
+	ID    strfmt.UUID
+	Count int64
+	Max   int64
+}
+
+// IsErrListItemInvalidPurchaseCount checks if an error is a ErrListItemInvalidPurchaseCount.
+func IsErrListItemInvalidPurchaseCount(err error) bool {
+	_, ok := err.(ErrListItemInvalidPurchaseCount)
+	return ok
+}
+
+func (err ErrListItemInvalidPurchaseCount) Error() string {
+	return fmt.Sprintf("purchase count has to be between 1 and %d [uid: %s, count: %d]",
+		err.Max, err.ID, err.Count)
+}
+
+// ErrListItemInvalidCursor represents an "invalid pagination cursor" kind of error.
+type ErrListItemInvalidCursor struct {
+	Cursor string
+}
+
+// IsErrListItemInvalidCursor checks if an error is a ErrListItemInvalidCursor.
+func IsErrListItemInvalidCursor(err error) bool {
+	_, ok := err.(ErrListItemInvalidCursor)
+	return ok
+}
+
+func (err ErrListItemInvalidCursor) Error() string {
+	return fmt.Sprintf("invalid cursor for this query [cursor: %s]", err.Cursor)
+}
+
+// ErrListItemInvalidQuery represents an "invalid query parameter" kind of error.
+type ErrListItemInvalidQuery struct {
+	Field string
+}
+
+// IsErrListItemInvalidQuery checks if an error is a ErrListItemInvalidQuery.
+func IsErrListItemInvalidQuery(err error) bool {
+	_, ok := err.(ErrListItemInvalidQuery)
+	return ok
+}
+
+func (err ErrListItemInvalidQuery) Error() string {
+	return fmt.Sprintf("invalid list item query [field: %s]", err.Field)
+}
+
+// ErrListItemTemplateNotExist represents a "ListItemTemplateNotExist" kind of error.
+type ErrListItemTemplateNotExist struct {
+	ID       strfmt.UUID
+	GroupUID strfmt.UUID
+}
+
+// IsErrListItemTemplateNotExist checks if an error is a ErrListItemTemplateNotExist.
+func IsErrListItemTemplateNotExist(err error) bool {
+	_, ok := err.(ErrListItemTemplateNotExist)
+	return ok
+}
+
+func (err ErrListItemTemplateNotExist) Error() string {
+	return fmt.Sprintf("list item template does not exist [groupUID: %s, uid: %s]",
+		err.GroupUID, err.ID)
+}
+
 //  ____  _ _ _
 // | __ )(_) | |
 // |  _ \| | | |
 // | |_) | | | |
 // |____/|_|_|_|
+
+// ErrBillNotExist represents a "BillNotExist" kind of error.
+type ErrBillNotExist struct {
+	UID      strfmt.UUID
+	GroupUID strfmt.UUID
+}
+
+// IsErrBillNotExist checks if an error is a ErrBillNotExist.
+func IsErrBillNotExist(err error) bool {
+	_, ok := err.(ErrBillNotExist)
+	return ok
+}
+
+func (err ErrBillNotExist) Error() string {
+	return fmt.Sprintf("bill does not exist [groupUID: %s, uid: %s]",
+		err.GroupUID, err.UID)
+}
+
+// ErrBillInvalidTransition represents an "invalid bill state transition" kind of error.
+type ErrBillInvalidTransition struct {
+	UID  strfmt.UUID
+	From string
+	To   string
+}
+
+// IsErrBillInvalidTransition checks if an error is a ErrBillInvalidTransition.
+func IsErrBillInvalidTransition(err error) bool {
+	_, ok := err.(ErrBillInvalidTransition)
+	return ok
+}
+
+func (err ErrBillInvalidTransition) Error() string {
+	return fmt.Sprintf("bill cannot change its state from '%s' to '%s' [uid: %s]",
+		err.From, err.To, err.UID)
+}
+
+// ErrBillNotRecipient represents a "user is not a recipient of the bill" kind of error.
+type ErrBillNotRecipient struct {
+	UID     strfmt.UUID
+	UserUID string
+}
+
+// IsErrBillNotRecipient checks if an error is a ErrBillNotRecipient.
+func IsErrBillNotRecipient(err error) bool {
+	_, ok := err.(ErrBillNotRecipient)
+	return ok
+}
+
+func (err ErrBillNotRecipient) Error() string {
+	return fmt.Sprintf("user is not a (possible) recipient of the bill [uid: %s, user: %s]",
+		err.UID, err.UserUID)
+}
+
+// ErrBillAlreadyPaid represents a "user has already paid his share" kind of error.
+type ErrBillAlreadyPaid struct {
+	UID     strfmt.UUID
+	UserUID string
+}
+
+// IsErrBillAlreadyPaid checks if an error is a ErrBillAlreadyPaid.
+func IsErrBillAlreadyPaid(err error) bool {
+	_, ok := err.(ErrBillAlreadyPaid)
+	return ok
+}
+
+func (err ErrBillAlreadyPaid) Error() string {
+	return fmt.Sprintf("user has already paid his share of the bill [uid: %s, user: %s]",
+		err.UID, err.UserUID)
+}
+
+// ErrBillNoItems represents a "bill without items" kind of error.
+type ErrBillNoItems struct {
+	UserUID string
+}
+
+// IsErrBillNoItems checks if an error is a ErrBillNoItems.
+func IsErrBillNoItems(err error) bool {
+	_, ok := err.(ErrBillNoItems)
+	return ok
+}
+
+func (err ErrBillNoItems) Error() string {
+	return fmt.Sprintf("bill contains no unbilled items bought by the user [user: %s]", err.UserUID)
+}
+
+//  _____         _
+// |_   _|_ _ ___| | __
+//   | |/ _` / __| |/ /
+//   | | (_| \__ \   <
+//   |_|\__,_|___/_|\_\
+//
+
+// ErrTaskNotExist represents a "TaskNotExist" kind of error.
+type ErrTaskNotExist struct {
+	ID       strfmt.UUID
+	GroupUID strfmt.UUID
+}
+
+// IsErrTaskNotExist checks if an error is a ErrTaskNotExist.
+func IsErrTaskNotExist(err error) bool {
+	_, ok := err.(ErrTaskNotExist)
+	return ok
+}
+
+func (err ErrTaskNotExist) Error() string {
+	return fmt.Sprintf("task does not exist [groupUID: %s, uid: %s]",
+		err.GroupUID, err.ID)
+}
+
+// ErrTaskNotInRotation represents a "user is not part of the task's rotation" kind of error.
+type ErrTaskNotInRotation struct {
+	ID      strfmt.UUID
+	UserUID string
+}
+
+// IsErrTaskNotInRotation checks if an error is a ErrTaskNotInRotation.
+func IsErrTaskNotInRotation(err error) bool {
+	_, ok := err.(ErrTaskNotInRotation)
+	return ok
+}
+
+func (err ErrTaskNotInRotation) Error() string {
+	return fmt.Sprintf("user is not (or can't be) part of the task's rotation [uid: %s, user: %s]",
+		err.ID, err.UserUID)
+}
+
+// ErrTaskNoAssignee represents a "task has no assignee" kind of error.
+type ErrTaskNoAssignee struct {
+	ID strfmt.UUID
+}
+
+// IsErrTaskNoAssignee checks if an error is a ErrTaskNoAssignee.
+func IsErrTaskNoAssignee(err error) bool {
+	_, ok := err.(ErrTaskNoAssignee)
+	return ok
+}
+
+func (err ErrTaskNoAssignee) Error() string {
+	return fmt.Sprintf("task has no assignee. Its rotation is empty [uid: %s]", err.ID)
+}
+
+// ErrGroupInviteNotExist represents a "GroupInviteNotExist" kind of error.
+type ErrGroupInviteNotExist struct {
+	GroupUID strfmt.UUID
+	ID       int64
+}
+
+// IsErrGroupInviteNotExist checks if an error is a ErrGroupInviteNotExist.
+func IsErrGroupInviteNotExist(err error) bool {
+	_, ok := err.(ErrGroupInviteNotExist)
+	return ok
+}
+
+func (err ErrGroupInviteNotExist) Error() string {
+	return fmt.Sprintf("group invite does not exist [groupUID: %s, id: %d]",
+		err.GroupUID, err.ID)
+}
+
+// ErrGroupInviteUsed represents a "group invite was already used" kind of error.
+type ErrGroupInviteUsed struct {
+	ID int64
+}
+
+// IsErrGroupInviteUsed checks if an error is a ErrGroupInviteUsed.
+func IsErrGroupInviteUsed(err error) bool {
+	_, ok := err.(ErrGroupInviteUsed)
+	return ok
+}
+
+func (err ErrGroupInviteUsed) Error() string {
+	return fmt.Sprintf("group invite was already used [id: %d]", err.ID)
+}
+
+//  ____
+// / ___| _   _ _ __   ___
+// \___ \| | | | '_ \ / __|
+//  ___) | |_| | | | | (__
+// |____/ \__, |_| |_|\___|
+//        |___/
+//
+
+// ErrSyncInvalidCursor represents an "invalid sync cursor" kind of error.
+type ErrSyncInvalidCursor struct {
+	Cursor string
+}
+
+// IsErrSyncInvalidCursor checks if an error is a ErrSyncInvalidCursor.
+func IsErrSyncInvalidCursor(err error) bool {
+	_, ok := err.(ErrSyncInvalidCursor)
+	return ok
+}
+
+func (err ErrSyncInvalidCursor) Error() string {
+	return fmt.Sprintf("invalid sync cursor [cursor: %s]", err.Cursor)
+}
+
+// __     __            _
+// \ \   / /__ _ __ ___(_) ___  _ __
+//  \ \ / / _ \ '__/ __| |/ _ \| '_ \
+//   \ V /  __/ |  \__ \ | (_) | | | |
+//    \_/ \___|_|  |___/_|\___/|_| |_|
+//
+
+// ErrVersionMismatch represents a "resource was changed in the meantime" kind of error.
+type ErrVersionMismatch struct {
+	Type    string
+	ID      string
+	Version int64
+}
+
+// IsErrVersionMismatch checks if an error is a ErrVersionMismatch.
+func IsErrVersionMismatch(err error) bool {
+	_, ok := err.(ErrVersionMismatch)
+	return ok
+}
+
+func (err ErrVersionMismatch) Error() string {
+	return fmt.Sprintf("%s was changed in the meantime [id: %s, version: %d]", err.Type, err.ID, err.Version)
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/wgplaner/wg_planer_server/modules/avatar"
 	"github.com/wgplaner/wg_planer_server/modules/base"
@@ -17,6 +18,7 @@ import (
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
 	"github.com/nfnt/resize"
 	"github.com/op/go-logging"
 )
@@ -60,6 +62,19 @@ type Group struct {
 	// updated at
 	// Read Only: true
 	UpdatedAt strfmt.DateTime `xorm:"updated" json:"updatedAt,omitempty"`
+
+	// version (incremented on every change, used as ETag)
+	// Read Only: true
+	Version int64 `xorm:"NOT NULL DEFAULT 1" json:"version,omitempty"`
+
+	// deleted at (set if the group is deleted but can still be restored)
+	DeletedAt time.Time `xorm:"deleted" json:"-"`
+
+	// digest sent at (time of the last digest mail)
+	DigestSentAt *time.Time `xorm:"NULL" json:"-"`
+
+	// former members (members of a deleted group, who get it back if it's restored)
+	FormerMembers []string `xorm:"TEXT" json:"-"`
 }
 
 // AfterLoad is invoked from XORM after setting the values of all fields of this object.
@@ -165,6 +180,130 @@ func (g *Group) HasAdmin(uid string) bool {
 	return base.StringInSlice(uid, g.Admins)
 }
 
+// AddAdmin makes the member an admin of the group.
+func (g *Group) AddAdmin(uid string) error {
+	if !g.HasMember(uid) {
+		return ErrGroupNotMember{UID: g.UID, UserUID: uid}
+	}
+	if g.HasAdmin(uid) {
+		return nil
+	}
+
+	return g.updateAdmins(append(append([]string{}, g.Admins...), uid))
+}
+
+// RemoveAdmin revokes the admin rights of the user.
+// The last admin of a group can't be removed.
+func (g *Group) RemoveAdmin(uid string) error {
+	if !g.HasAdmin(uid) {
+		return nil
+	}
+	if len(g.Admins) <= 1 {
+		return ErrGroupLastAdmin{UID: g.UID, UserUID: uid}
+	}
+
+	return g.updateAdmins(base.RemoveStringFromSlice(append([]string{}, g.Admins...), uid))
+}
+
+// TransferAdmin makes "to" an admin and revokes the admin rights of "from".
+func (g *Group) TransferAdmin(from, to string) error {
+	if !g.HasAdmin(from) {
+		return ErrGroupNotAdmin{UID: g.UID, UserUID: from}
+	}
+	if !g.HasMember(to) {
+		return ErrGroupNotMember{UID: g.UID, UserUID: to}
+	}
+
+	admins := append([]string{}, g.Admins...)
+	if !g.HasAdmin(to) {
+		admins = append(admins, to)
+	}
+	return g.updateAdmins(base.RemoveStringFromSlice(admins, from))
+}
+
+// updateAdmins stores the admins if the group wasn't changed since it was
+// read. Otherwise ErrVersionMismatch is returned, so that concurrent changes
+// don't overwrite each other or remove the last admin.
+func (g *Group) updateAdmins(admins []string) error {
+	changed := *g
+	changed.Admins = admins
+	if err := UpdateGroupColsIfVersion(&changed, g.Version, `admins`); err != nil {
+		return err
+	}
+	g.Admins = admins
+	g.Version++
+	return nil
+}
+
+// RemoveMember removes the user from the group. The last
+// admin of a group can't be removed.
+func (g *Group) RemoveMember(u *User) error {
+	if u.GroupUID != g.UID {
+		return ErrGroupNotMember{UID: g.UID, UserUID: *u.UID}
+	}
+	if g.HasAdmin(*u.UID) && len(g.Admins) <= 1 {
+		return ErrGroupLastAdmin{UID: g.UID, UserUID: *u.UID}
+	}
+
+	admins := append([]string{}, g.Admins...)
+	return g.removeMember(u, base.RemoveStringFromSlice(admins, *u.UID))
+}
+
+// Leave removes the user from the group. If he is the last
+// admin, another member becomes admin of the group.
+func (g *Group) Leave(u *User) error {
+	if u.GroupUID != g.UID {
+		return ErrGroupNotMember{UID: g.UID, UserUID: *u.UID}
+	}
+
+	return g.removeMember(u, g.adminsAfterLeaving(*u.UID))
+}
+
+// adminsAfterLeaving returns the admins of the group after the user left. If
+// he is the last admin, another member becomes admin of the group. The last
+// member stays admin, so that he can restore the group after it was deleted.
+func (g *Group) adminsAfterLeaving(uid string) []string {
+	var other string
+	for _, m := range g.Members {
+		if m != uid {
+			other = m
+			break
+		}
+	}
+	if other == "" {
+		return []string{uid}
+	}
+
+	admins := append([]string{}, g.Admins...)
+	if g.HasAdmin(uid) && len(admins) == 1 {
+		admins = append(admins, other)
+	}
+	return base.RemoveStringFromSlice(admins, uid)
+}
+
+// removeMember stores the new admins and removes the user in one transaction.
+func (g *Group) removeMember(u *User, admins []string) error {
+	err := withTx(func(sess *xorm.Session) error {
+		return g.removeMemberInTx(sess, u, admins)
+	})
+	if err != nil {
+		return err
+	}
+
+	u.GroupUID = ""
+	g.Admins = admins
+	g.Members = base.RemoveStringFromSlice(g.Members, *u.UID)
+	return nil
+}
+
+// removeMemberInTx stores the new admins and removes the user within "sess".
+func (g *Group) removeMemberInTx(sess *xorm.Session, u *User, admins []string) error {
+	if _, err := sess.ID(g.UID).Cols(`admins`).Incr(`version`).Update(&Group{Admins: admins}); err != nil {
+		return err
+	}
+	return u.leaveGroup(sess)
+}
+
 func (g *Group) GetActiveShoppingListItems() ([]*ListItem, error) {
 	items := make([]*ListItem, 0, 10)
 	err := x.
@@ -193,24 +332,74 @@ func CreateGroup(g *Group) error {
 	g.DisplayName = swag.String(strings.TrimSpace(swag.StringValue(g.DisplayName)))
 	g.Currency = strings.TrimSpace(g.Currency)
 
-	_, err := x.InsertOne(g)
-	return err
+	return withTx(func(sess *xorm.Session) error {
+		if _, err := sess.InsertOne(g); err != nil {
+			return err
+		}
+		return recordChange(sess, g.UID, SyncTypeGroup, string(g.UID), false)
+	})
 }
 
-func UpdateGroup(g *Group) error {
+// CreateGroupForUser creates the group and makes the user its first member.
+func CreateGroupForUser(g *Group, u *User) error {
 	g.DisplayName = swag.String(strings.TrimSpace(swag.StringValue(g.DisplayName)))
 	g.Currency = strings.TrimSpace(g.Currency)
 
-	_, err := x.ID(g.UID).AllCols().Update(g)
-	return err
+	err := withTx(func(sess *xorm.Session) error {
+		if _, err := sess.InsertOne(g); err != nil {
+			return err
+		}
+
+		if err := failpoint("CreateGroupForUser"); err != nil {
+			return err
+		}
+
+		if _, err := sess.ID(*u.UID).Cols(`group_uid`).Incr(`version`).Update(&User{GroupUID: g.UID}); err != nil {
+			return err
+		}
+		if u.GroupUID != "" && u.GroupUID != g.UID {
+			if err := recordMemberChange(sess, u.GroupUID, *u.UID, true); err != nil {
+				return err
+			}
+		}
+		return recordMemberChange(sess, g.UID, *u.UID, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	u.GroupUID = g.UID
+	return nil
+}
+
+func UpdateGroup(g *Group) error {
+	return UpdateGroupColsIfVersion(g, 0)
 }
 
 func UpdateGroupCols(g *Group, cols ...string) error {
+	return UpdateGroupColsIfVersion(g, 0, cols...)
+}
+
+// UpdateGroupColsIfVersion updates the columns of the group if it still has
+// the given version. A version of 0 matches every version.
+func UpdateGroupColsIfVersion(g *Group, version int64, cols ...string) error {
 	g.DisplayName = swag.String(strings.TrimSpace(swag.StringValue(g.DisplayName)))
 	g.Currency = strings.TrimSpace(g.Currency)
 
-	_, err := x.ID(g.UID).Cols(cols...).Update(g)
-	return err
+	return withTx(func(sess *xorm.Session) error {
+		n, err := updateVersioned(sess.ID(g.UID), g, version, cols...)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			if has, err := sess.ID(g.UID).Exist(new(Group)); err != nil {
+				return err
+			} else if !has {
+				return ErrGroupNotExist{UID: g.UID}
+			}
+			return ErrVersionMismatch{Type: "group", ID: string(g.UID), Version: version}
+		}
+		return recordChange(sess, g.UID, SyncTypeGroup, string(g.UID), false)
+	})
 }
 
 func GetGroupByUID(uid strfmt.UUID) (*Group, error) {
@@ -1,12 +1,14 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	apiErrors "github.com/go-openapi/errors"
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
 	"github.com/wgplaner/wg_planer_server/modules/base"
 )
 
@@ -31,6 +33,26 @@ type GroupCode struct {
 	// Required: true
 	// Read Only: true
 	ValidUntil strfmt.DateTime `json:"validUntil"`
+
+	// label
+	// Max Length: 50
+	Label string `xorm:"VARCHAR(50)" json:"label,omitempty"`
+
+	// max uses (0 is unlimited)
+	// Minimum: 0
+	MaxUses int64 `xorm:"DEFAULT 0" json:"maxUses,omitempty"`
+
+	// uses
+	// Read Only: true
+	Uses int64 `xorm:"DEFAULT 0" json:"uses"`
+
+	// created by
+	// Read Only: true
+	CreatedBy string `xorm:"VARCHAR(28)" json:"createdBy,omitempty"`
+
+	// created at
+	// Read Only: true
+	CreatedAt strfmt.DateTime `xorm:"created" json:"createdAt,omitempty"`
 }
 
 // Validate validates this group code
@@ -48,6 +70,14 @@ func (m *GroupCode) Validate(formats strfmt.Registry) error {
 		// prop
 		res = append(res, err)
 	}
+	if err := m.validateLabel(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+	if err := m.validateMaxUses(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
 	if len(res) > 0 {
 		return apiErrors.CompositeValidationError(res...)
 	}
@@ -84,6 +114,23 @@ func (m *GroupCode) validateValidUntil(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *GroupCode) validateLabel(formats strfmt.Registry) error {
+	if swag.IsZero(m.Label) { // not required
+		return nil
+	}
+	if err := validate.MaxLength("label", "body", string(m.Label), 50); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *GroupCode) validateMaxUses(formats strfmt.Registry) error {
+	if err := validate.MinimumInt("maxUses", "body", int64(m.MaxUses), 0, false); err != nil {
+		return err
+	}
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *GroupCode) MarshalBinary() ([]byte, error) {
 	if m == nil {
@@ -102,38 +149,123 @@ func (m *GroupCode) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
-func CreateGroupCode(uid strfmt.UUID) (*GroupCode, error) {
-	g, err := GetGroupByUID(uid)
-	if err != nil {
-		return nil, err
+// GroupCodeList group code list
+// swagger:model GroupCodeList
+type GroupCodeList struct {
+	// count
+	// Required: true
+	// Read Only: true
+	Count int64 `json:"count"`
+
+	// codes
+	// Required: true
+	// Read Only: true
+	Codes []*GroupCode `json:"codes"`
+}
+
+// Validate validates this group code list
+func (m *GroupCodeList) Validate(formats strfmt.Registry) error {
+	if err := validate.Required("codes", "body", m.Codes); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Codes); i++ {
+		if m.Codes[i] == nil {
+			continue
+		}
+		if err := m.Codes[i].Validate(formats); err != nil {
+			if ve, ok := err.(*apiErrors.Validation); ok {
+				return ve.ValidateName("codes" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
 	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *GroupCodeList) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
 
-	// Invalidate old codes
-	oldCodes := GroupCode{ValidUntil: strfmt.DateTime(time.Now().UTC())}
-	if _, err := x.Where("group_uid = ?", g.UID).Update(&oldCodes); err != nil {
-		groupLog.Errorf(`Can't update validUntil date of other (old) codes for group "%s"'; Err: "%s"`, uid, err)
+// UnmarshalBinary interface implementation
+func (m *GroupCodeList) UnmarshalBinary(b []byte) error {
+	var res GroupCodeList
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// CreateGroupCode creates a new code for the group. The label, the expiry and
+// the maximum number of uses are taken from "opts" if given. The code is valid
+// for GroupCodeValidDays by default. Earlier codes stay valid.
+func CreateGroupCode(uid strfmt.UUID, createdBy string, opts *GroupCode) (*GroupCode, error) {
+	g, err := GetGroupByUID(uid)
+	if err != nil {
 		return nil, err
 	}
 
 	var (
 		code       = base.GetRandomAlphaNumCode(GroupCodeLength, true)
-		validUntil = time.Now().UTC().AddDate(0, 0, GroupCodeValidDays)
+		validUntil = strfmt.DateTime(time.Now().UTC().AddDate(0, 0, GroupCodeValidDays))
 	)
 
 	groupCode := &GroupCode{
 		GroupUID:   &g.UID,
 		Code:       &code,
-		ValidUntil: strfmt.DateTime(validUntil),
+		ValidUntil: validUntil,
+		CreatedBy:  createdBy,
+	}
+	if opts != nil {
+		groupCode.Label = opts.Label
+		groupCode.MaxUses = opts.MaxUses
+		if !time.Time(opts.ValidUntil).IsZero() {
+			groupCode.ValidUntil = opts.ValidUntil
+		}
 	}
 
-	// Insert new code into database
-	if _, err := x.InsertOne(groupCode); err != nil {
+	if _, err = x.InsertOne(groupCode); err != nil {
 		return nil, err
 	}
 
 	return groupCode, nil
 }
 
+// GetGroupCodesByGroupUID returns all codes of the group. Codes that
+// are valid the longest come first.
+func GetGroupCodesByGroupUID(guid strfmt.UUID) ([]*GroupCode, error) {
+	codes := make([]*GroupCode, 0, 5)
+	err := x.
+		Where(`group_uid=?`, guid).
+		Desc(`valid_until`).
+		Find(&codes)
+	return codes, err
+}
+
+// isUsableAt returns true if the code isn't expired and not used up.
+func (m *GroupCode) isUsableAt(now time.Time) bool {
+	return !now.After(time.Time(m.ValidUntil)) && (m.MaxUses == 0 || m.Uses < m.MaxUses)
+}
+
+// useGroupCode counts a use of the code. It fails if the code is used up.
+func useGroupCode(sess *xorm.Session, c string) error {
+	affected, err := sess.
+		ID(c).
+		And(`(max_uses = 0 OR uses < max_uses)`).
+		Incr(`uses`).
+		Update(new(GroupCode))
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrGroupCodeNotExist{Code: c}
+	}
+	return nil
+}
+
 func GetGroupCode(c string) (*GroupCode, error) {
 	code := new(GroupCode)
 	if has, err := x.ID(c).Get(code); err != nil {
@@ -144,6 +276,18 @@ func GetGroupCode(c string) (*GroupCode, error) {
 	return code, nil
 }
 
+// RevokeGroupCode invalidates the code so that it can't be used to join a group anymore.
+func RevokeGroupCode(c string) error {
+	code, err := GetGroupCode(c)
+	if err != nil {
+		return err
+	}
+
+	code.ValidUntil = strfmt.DateTime(time.Now().UTC())
+	_, err = x.ID(c).Cols(`valid_until`).Update(code)
+	return err
+}
+
 func IsGroupCodeValid(c string) (bool, *GroupCode) {
 	if len(c) != 12 {
 		return false, nil
@@ -154,7 +298,7 @@ func IsGroupCodeValid(c string) (bool, *GroupCode) {
 
 	if code, err = GetGroupCode(c); err != nil || code == nil {
 		return false, nil
-	} else if time.Now().After(time.Time(code.ValidUntil)) {
+	} else if !code.isUsableAt(time.Now()) {
 		return false, nil
 	}
 	return true, code
@@ -2,7 +2,9 @@ package models
 
 import (
 	"testing"
+	"time"
 
+	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/stretchr/testify/assert"
 )
@@ -10,16 +12,77 @@ import (
 func TestCreateGroupCode(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 
-	code1, err1 := CreateGroupCode("00112233-4455-6677-8899-000000000000")
+	code1, err1 := CreateGroupCode("00112233-4455-6677-8899-000000000000", "", nil)
 	assert.Error(t, err1)
 	assert.Nil(t, code1)
 	AssertCount(t, new(GroupCode), 1)
 
-	code2, err2 := CreateGroupCode("00112233-4455-6677-8899-aabbccddeeff")
+	code2, err2 := CreateGroupCode("00112233-4455-6677-8899-aabbccddeeff", "1234567890fakefirebaseid0001", nil)
 	assert.NoError(t, err2)
 	assert.NotNil(t, code2)
 	AssertExistsAndLoadBean(t, &GroupCode{Code: code2.Code})
 	AssertCount(t, new(GroupCode), 2)
+
+	// Earlier codes stay valid
+	valid, _ := IsGroupCodeValid("ABCDEFGHI123")
+	assert.True(t, valid)
+
+	validUntil := strfmt.DateTime(time.Now().UTC().Add(time.Hour).Truncate(time.Second))
+	code3, err3 := CreateGroupCode("00112233-4455-6677-8899-aabbccddeeff", "1234567890fakefirebaseid0001",
+		&GroupCode{Label: "Flyer", ValidUntil: validUntil, MaxUses: 2})
+	assert.NoError(t, err3)
+	code3 = AssertExistsAndLoadBean(t, &GroupCode{Code: code3.Code}).(*GroupCode)
+	assert.Equal(t, "Flyer", code3.Label)
+	assert.EqualValues(t, 2, code3.MaxUses)
+	assert.EqualValues(t, 0, code3.Uses)
+	assert.Equal(t, "1234567890fakefirebaseid0001", code3.CreatedBy)
+	assert.True(t, time.Time(validUntil).Equal(time.Time(code3.ValidUntil)))
+}
+
+func TestGetGroupCodesByGroupUID(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	_, err := CreateGroupCode("00112233-4455-6677-8899-aabbccddeeff", "", nil)
+	assert.NoError(t, err)
+
+	codes, err := GetGroupCodesByGroupUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+	if assert.Len(t, codes, 2) {
+		// The fixture code is valid the longest
+		assert.Equal(t, "ABCDEFGHI123", *codes[0].Code)
+	}
+
+	codes, err = GetGroupCodesByGroupUID("00112233-4455-6677-8899-aabbccddeef0")
+	assert.NoError(t, err)
+	assert.Empty(t, codes)
+}
+
+func TestUser_JoinGroupWithCodeMaxUses(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	u3 := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0003")}).(*User)
+	u4 := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0004")}).(*User)
+
+	code, err := CreateGroupCode("00112233-4455-6677-8899-aabbccddeeff", "", &GroupCode{MaxUses: 1})
+	assert.NoError(t, err)
+
+	_, err = u3.JoinGroupWithCode(*code.Code)
+	assert.NoError(t, err)
+	code = AssertExistsAndLoadBean(t, &GroupCode{Code: code.Code}).(*GroupCode)
+	assert.EqualValues(t, 1, code.Uses)
+
+	// The code is used up
+	valid, _ := IsGroupCodeValid(*code.Code)
+	assert.False(t, valid)
+	_, err = u4.JoinGroupWithCode(*code.Code)
+	assert.True(t, IsErrGroupCodeNotExist(err))
+	u4 = AssertExistsAndLoadBean(t, &User{UID: u4.UID}).(*User)
+	assert.Equal(t, strfmt.UUID("00112233-4455-6677-8899-aabbccddeef0"), u4.GroupUID)
+
+	// Unlimited codes count their uses, too
+	_, err = u4.JoinGroupWithCode("ABCDEFGHI123")
+	assert.NoError(t, err)
+	fixture := AssertExistsAndLoadBean(t, &GroupCode{Code: swag.String("ABCDEFGHI123")}).(*GroupCode)
+	assert.EqualValues(t, 1, fixture.Uses)
 }
 
 func TestGetGroupCode(t *testing.T) {
@@ -54,6 +117,16 @@ func TestIsGroupCodeValid(t *testing.T) {
 	assert.NotNil(t, code3)
 }
 
+func TestRevokeGroupCode(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	assert.True(t, IsErrGroupCodeNotExist(RevokeGroupCode("EZ14BAG6T3RG")))
+
+	assert.NoError(t, RevokeGroupCode("ABCDEFGHI123"))
+	valid, _ := IsGroupCodeValid("ABCDEFGHI123")
+	assert.False(t, valid)
+}
+
 func TestGroupCode_MarshalBinary(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 
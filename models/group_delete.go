@@ -0,0 +1,253 @@
+package models
+
+import (
+	"os"
+	"path"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-xorm/xorm"
+)
+
+// groupDeletionGracePeriod returns the configured period in which deleted groups can be restored.
+func groupDeletionGracePeriod() time.Duration {
+	if setting.AppConfig == nil {
+		return 0
+	}
+	return setting.AppConfig.Group.DeletionGracePeriodDuration
+}
+
+// DeleteGroup deletes the group and all of its data. If a grace period
+// is configured, the group is only marked as deleted and can be restored
+// by one of its admins until PurgeDeletedGroups removes it.
+func DeleteGroup(g *Group) error {
+	if groupDeletionGracePeriod() > 0 {
+		return withTx(func(sess *xorm.Session) error {
+			return softDeleteGroup(sess, g)
+		})
+	}
+	return PurgeGroup(g.UID)
+}
+
+// softDeleteGroup marks the group as deleted. The members lose their group
+// until it's restored.
+func softDeleteGroup(sess *xorm.Session, g *Group) error {
+	if _, err := sess.ID(g.UID).Cols(`former_members`).
+		Update(&Group{FormerMembers: g.Members}); err != nil {
+		return err
+	}
+	if _, err := sess.
+		Where(`group_uid=?`, g.UID).
+		Cols(`group_uid`).
+		Incr(`version`).
+		Update(&User{GroupUID: ""}); err != nil {
+		return err
+	}
+	if _, err := sess.ID(g.UID).Delete(new(Group)); err != nil {
+		return err
+	}
+	return recordChange(sess, g.UID, SyncTypeGroup, string(g.UID), true)
+}
+
+// PurgeGroup removes the group, its members' association, codes, list items,
+// templates, bills, balances and tasks in one transaction. The group's image
+// directory is removed afterwards.
+func PurgeGroup(guid strfmt.UUID) error {
+	err := withTx(func(sess *xorm.Session) error {
+		return purgeGroup(sess, guid)
+	})
+	if err != nil {
+		return err
+	}
+
+	imageDir := path.Dir(GetGroupImagePath(guid))
+	if err := os.RemoveAll(imageDir); err != nil {
+		groupLog.Errorf(`Can't remove image directory of group "%s": %s`, guid, err)
+	}
+
+	groupLog.Infof(`Purged group "%s"`, guid)
+	return nil
+}
+
+func purgeGroup(sess *xorm.Session, guid strfmt.UUID) error {
+	// Members stay users but lose their group
+	if _, err := sess.
+		Where(`group_uid=?`, guid).
+		Cols(`group_uid`).
+		Incr(`version`).
+		Update(&User{GroupUID: ""}); err != nil {
+		return err
+	}
+
+	beans := []interface{}{
+		new(GroupCode),
+		new(GroupInvite),
+		new(ListItem),
+		new(ListItemTemplate),
+		new(Purchase),
+		new(Bill),
+		new(MemberBalance),
+		new(Task),
+		new(TaskCompletion),
+		new(SyncChange),
+		new(SyncSequence),
+	}
+	for _, bean := range beans {
+		if _, err := sess.Where(`group_uid=?`, guid).Delete(bean); err != nil {
+			return err
+		}
+	}
+
+	if err := failpoint("PurgeGroup"); err != nil {
+		return err
+	}
+
+	_, err := sess.Unscoped().ID(guid).Delete(new(Group))
+	return err
+}
+
+// GetDeletedGroupByUID returns a group that was deleted but not purged yet.
+func GetDeletedGroupByUID(uid strfmt.UUID) (*Group, error) {
+	if !strfmt.IsUUID(string(uid)) {
+		return nil, ErrGroupInvalidUUID{UID: string(uid)}
+	}
+
+	g := new(Group)
+
+	if has, err := x.Unscoped().ID(uid).And(`deleted_at IS NOT NULL`).Get(g); err != nil {
+		return nil, err
+
+	} else if !has {
+		return nil, ErrGroupNotExist{UID: uid}
+	}
+
+	return g, nil
+}
+
+// RestoreGroup restores a deleted group. The user has to be one of the
+// group's admins and becomes a member again. If he joined another group in
+// the meantime, he leaves it like with Leave. Former members who didn't
+// join another group get the group back as well.
+func RestoreGroup(guid strfmt.UUID, u *User) (*Group, error) {
+	g, err := GetDeletedGroupByUID(guid)
+	if err != nil {
+		return nil, err
+	}
+	if !g.HasAdmin(*u.UID) {
+		return nil, ErrGroupNotMember{UID: guid, UserUID: *u.UID}
+	}
+
+	var current *Group
+	if u.GroupUID != "" && u.GroupUID != guid {
+		if current, err = GetGroupByUID(u.GroupUID); err != nil && !IsErrGroupNotExist(err) {
+			return nil, err
+		}
+	}
+
+	err = withTx(func(sess *xorm.Session) error {
+		if _, err := sess.Table(new(Group)).Unscoped().ID(guid).
+			Update(map[string]interface{}{"deleted_at": nil, "former_members": nil}); err != nil {
+			return err
+		}
+		if err := recordChange(sess, guid, SyncTypeGroup, string(guid), false); err != nil {
+			return err
+		}
+		if err := restoreFormerMembers(sess, g, *u.UID); err != nil {
+			return err
+		}
+
+		if u.GroupUID == guid {
+			return nil
+		}
+		if current != nil {
+			if err := current.removeMemberInTx(sess, u, current.adminsAfterLeaving(*u.UID)); err != nil {
+				return err
+			}
+		} else if u.GroupUID != "" {
+			if err := recordMemberChange(sess, u.GroupUID, *u.UID, true); err != nil {
+				return err
+			}
+		}
+		if _, err := sess.ID(*u.UID).Cols(`group_uid`).Incr(`version`).Update(&User{GroupUID: guid}); err != nil {
+			return err
+		}
+		return recordMemberChange(sess, guid, *u.UID, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.GroupUID = guid
+
+	// He was the last member of the other group
+	if current != nil {
+		current.Members = base.RemoveStringFromSlice(current.Members, *u.UID)
+		if len(current.Members) == 0 {
+			if err := DeleteGroup(current); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return GetGroupByUID(guid)
+}
+
+// restoreFormerMembers adds the former members of the group except "uid"
+// again, unless they joined another group in the meantime.
+func restoreFormerMembers(sess *xorm.Session, g *Group, uid string) error {
+	if len(g.FormerMembers) == 0 {
+		return nil
+	}
+
+	var uids []string
+	if err := sess.Table(new(User)).
+		In(`uid`, g.FormerMembers).
+		And(`uid<>?`, uid).
+		And(`(group_uid IS NULL OR group_uid = ?)`, "").
+		Cols(`uid`).
+		Find(&uids); err != nil {
+		return err
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+
+	if _, err := sess.In(`uid`, uids).Cols(`group_uid`).Incr(`version`).
+		Update(&User{GroupUID: g.UID}); err != nil {
+		return err
+	}
+	for _, m := range uids {
+		if err := recordMemberChange(sess, g.UID, m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeDeletedGroups purges all groups that were deleted before
+// the grace period. It returns the number of purged groups.
+func PurgeDeletedGroups(now time.Time) (int, error) {
+	grace := groupDeletionGracePeriod()
+	if grace <= 0 {
+		return 0, nil
+	}
+
+	groups := make([]*Group, 0, 5)
+	if err := x.Unscoped().
+		Cols(`uid`).
+		Where(`deleted_at IS NOT NULL`).
+		And(`deleted_at < ?`, now.Add(-grace)).
+		Find(&groups); err != nil {
+		return 0, err
+	}
+
+	for i, g := range groups {
+		if err := PurgeGroup(g.UID); err != nil {
+			return i, err
+		}
+	}
+
+	return len(groups), nil
+}
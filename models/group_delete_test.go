@@ -0,0 +1,137 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeGroup(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	groupUID := strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+
+	sess := x.NewSession()
+	defer sess.Close()
+	assert.NoError(t, sess.Begin())
+	assert.NoError(t, purgeGroup(sess, groupUID))
+	assert.NoError(t, sess.Commit())
+
+	AssertNotExistsBean(t, &Group{UID: groupUID})
+	AssertNotExistsBean(t, &ListItem{GroupUID: groupUID})
+	AssertNotExistsBean(t, &Bill{GroupUID: groupUID})
+	AssertNotExistsBean(t, &Task{GroupUID: groupUID})
+	AssertNotExistsBean(t, &ListItemTemplate{GroupUID: groupUID})
+	AssertNotExistsBean(t, &User{GroupUID: groupUID})
+
+	// Other groups are untouched
+	AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeef0"})
+}
+
+func TestRestoreGroup(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	groupUID := strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+
+	// Soft delete
+	_, err := x.ID(groupUID).Delete(new(Group))
+	assert.NoError(t, err)
+
+	_, err = GetGroupByUID(groupUID)
+	assert.True(t, IsErrGroupNotExist(err))
+
+	g, err := GetDeletedGroupByUID(groupUID)
+	assert.NoError(t, err)
+	assert.Equal(t, groupUID, g.UID)
+
+	// Only admins can restore a group
+	member, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+	_, err = RestoreGroup(groupUID, member)
+	assert.True(t, IsErrGroupNotMember(err))
+
+	admin, err := GetUserByUID("1234567890fakefirebaseid0001")
+	assert.NoError(t, err)
+	g, err = RestoreGroup(groupUID, admin)
+	assert.NoError(t, err)
+	assert.Len(t, g.Members, 2)
+
+	// Groups that aren't deleted can't be restored
+	_, err = GetDeletedGroupByUID(groupUID)
+	assert.True(t, IsErrGroupNotExist(err))
+}
+
+func TestRestoreGroupLeavesCurrentGroup(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	groupUID := strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+	otherUID := strfmt.UUID("00112233-4455-6677-8899-aabbccddeef0")
+	adminUID := "1234567890fakefirebaseid0001"
+
+	_, err := x.ID(groupUID).Delete(new(Group))
+	assert.NoError(t, err)
+
+	// The admin joined another group in the meantime
+	_, err = x.ID(adminUID).Cols("group_uid").Update(&User{GroupUID: otherUID})
+	assert.NoError(t, err)
+
+	admin, err := GetUserByUID(adminUID)
+	assert.NoError(t, err)
+	g, err := RestoreGroup(groupUID, admin)
+	assert.NoError(t, err)
+	assert.Contains(t, g.Members, adminUID)
+	assert.Equal(t, groupUID, admin.GroupUID)
+
+	other, err := GetGroupByUID(otherUID)
+	assert.NoError(t, err)
+	assert.NotContains(t, other.Members, adminUID)
+	AssertExistsAndLoadBean(t, &SyncChange{GroupUID: otherUID, EntityType: SyncTypeUser, EntityID: adminUID, Deleted: true})
+}
+
+func TestSoftDeleteGroup(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	groupUID := strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+
+	g, err := GetGroupByUID(groupUID)
+	assert.NoError(t, err)
+	assert.NoError(t, withTx(func(sess *xorm.Session) error {
+		return softDeleteGroup(sess, g)
+	}))
+
+	// The members lose their group
+	AssertNotExistsBean(t, &User{GroupUID: groupUID})
+	g, err = GetDeletedGroupByUID(groupUID)
+	assert.NoError(t, err)
+	assert.Len(t, g.FormerMembers, 2)
+
+	// and get it back if it's restored
+	admin, err := GetUserByUID("1234567890fakefirebaseid0001")
+	assert.NoError(t, err)
+	g, err = RestoreGroup(groupUID, admin)
+	assert.NoError(t, err)
+	assert.Len(t, g.Members, 2)
+	AssertExistsAndLoadBean(t, &SyncChange{GroupUID: groupUID, EntityType: SyncTypeUser, EntityID: "1234567890fakefirebaseid0002"})
+}
+
+func TestRestoreGroupAfterLastMemberLeft(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	groupUID := strfmt.UUID("00112233-4455-6677-8899-aabbccddeef0")
+	uid := "1234567890fakefirebaseid0004"
+
+	u, err := GetUserByUID(uid)
+	assert.NoError(t, err)
+	g, err := GetGroupByUID(groupUID)
+	assert.NoError(t, err)
+	assert.NoError(t, g.Leave(u))
+	assert.Empty(t, g.Members)
+	assert.Equal(t, []string{uid}, g.Admins)
+
+	assert.NoError(t, withTx(func(sess *xorm.Session) error {
+		return softDeleteGroup(sess, g)
+	}))
+
+	// The last member can restore the group
+	g, err = RestoreGroup(groupUID, u)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{uid}, g.Members)
+	assert.Equal(t, groupUID, u.GroupUID)
+}
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
+)
+
+// GroupInviteValidDays is the number of days an invite can be used.
+const GroupInviteValidDays = 7
+
+// States of group invites. They are derived from the invite's dates.
+const (
+	GroupInviteStatePending = "pending"
+	GroupInviteStateUsed    = "used"
+	GroupInviteStateRevoked = "revoked"
+	GroupInviteStateExpired = "expired"
+)
+
+// GroupInvite group invite
+// swagger:model GroupInvite
+type GroupInvite struct {
+	// id
+	// Read Only: true
+	ID int64 `xorm:"pk autoincr" json:"id,omitempty"`
+
+	// group UID
+	// Read Only: true
+	GroupUID strfmt.UUID `xorm:"VARCHAR(36) INDEX" json:"groupUID,omitempty"`
+
+	// email
+	// Required: true
+	Email strfmt.Email `xorm:"NOT NULL" json:"email"`
+
+	// locale of the invite mail (defaults to the locale of the inviting admin)
+	// Max Length: 5
+	Locale string `xorm:"VARCHAR(5)" json:"locale,omitempty"`
+
+	// token (single-use code that is only sent by mail)
+	Token string `xorm:"VARCHAR(12) UNIQUE" json:"-"`
+
+	// state (one of pending, used, revoked, expired)
+	// Read Only: true
+	State string `xorm:"-" json:"state,omitempty"`
+
+	// created by
+	// Read Only: true
+	CreatedBy string `xorm:"VARCHAR(28)" json:"createdBy,omitempty"`
+
+	// used by
+	// Read Only: true
+	UsedBy string `xorm:"VARCHAR(28)" json:"usedBy,omitempty"`
+
+	// valid until
+	// Read Only: true
+	ValidUntil strfmt.DateTime `json:"validUntil,omitempty"`
+
+	// used at
+	// Read Only: true
+	UsedAt *time.Time `xorm:"NULL" json:"usedAt,omitempty"`
+
+	// revoked at
+	// Read Only: true
+	RevokedAt *time.Time `xorm:"NULL" json:"revokedAt,omitempty"`
+
+	// created at
+	// Read Only: true
+	CreatedAt strfmt.DateTime `xorm:"created" json:"createdAt,omitempty"`
+}
+
+// AfterLoad is invoked from XORM after setting the values of all fields of this object.
+func (m *GroupInvite) AfterLoad() {
+	m.State = m.stateAt(time.Now())
+}
+
+func (m *GroupInvite) stateAt(now time.Time) string {
+	switch {
+	case m.UsedAt != nil:
+		return GroupInviteStateUsed
+	case m.RevokedAt != nil:
+		return GroupInviteStateRevoked
+	case now.After(time.Time(m.ValidUntil)):
+		return GroupInviteStateExpired
+	default:
+		return GroupInviteStatePending
+	}
+}
+
+// Validate validates this group invite
+func (m *GroupInvite) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := m.validateEmail(formats); err != nil {
+		res = append(res, err)
+	}
+	if err := m.validateLocale(formats); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *GroupInvite) validateEmail(formats strfmt.Registry) error {
+	if err := validate.RequiredString("email", "body", string(m.Email)); err != nil {
+		return err
+	}
+	if err := validate.FormatOf("email", "body", "email", m.Email.String(), formats); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *GroupInvite) validateLocale(formats strfmt.Registry) error {
+	if swag.IsZero(m.Locale) { // not required
+		return nil
+	}
+	if err := validate.MaxLength("locale", "body", string(m.Locale), 5); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *GroupInvite) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *GroupInvite) UnmarshalBinary(b []byte) error {
+	var res GroupInvite
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// GroupInviteList group invite list
+// swagger:model GroupInviteList
+type GroupInviteList struct {
+	// count
+	// Required: true
+	// Read Only: true
+	Count int64 `json:"count"`
+
+	// invites
+	// Required: true
+	// Read Only: true
+	Invites []*GroupInvite `json:"invites"`
+}
+
+// Validate validates this group invite list
+func (m *GroupInviteList) Validate(formats strfmt.Registry) error {
+	if err := validate.Required("invites", "body", m.Invites); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Invites); i++ {
+		if m.Invites[i] == nil {
+			continue
+		}
+		if err := m.Invites[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("invites" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *GroupInviteList) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *GroupInviteList) UnmarshalBinary(b []byte) error {
+	var res GroupInviteList
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// CreateGroupInvite creates an invite with a new single-use token for the email address.
+// Shared group codes are not affected.
+func CreateGroupInvite(g *Group, createdBy string, email strfmt.Email, locale string) (*GroupInvite, error) {
+	invite := &GroupInvite{
+		GroupUID:   g.UID,
+		Email:      email,
+		Locale:     locale,
+		Token:      base.GetRandomAlphaNumCode(GroupCodeLength, true),
+		CreatedBy:  createdBy,
+		ValidUntil: strfmt.DateTime(time.Now().UTC().AddDate(0, 0, GroupInviteValidDays)),
+	}
+
+	if _, err := x.InsertOne(invite); err != nil {
+		return nil, err
+	}
+	invite.State = GroupInviteStatePending
+	return invite, nil
+}
+
+// GetGroupInvitesByGroupUID returns all invites of the group, newest first.
+func GetGroupInvitesByGroupUID(guid strfmt.UUID) ([]*GroupInvite, error) {
+	invites := make([]*GroupInvite, 0, 5)
+	err := x.
+		Where(`group_uid=?`, guid).
+		Desc(`id`).
+		Find(&invites)
+	return invites, err
+}
+
+// GetGroupInviteByIDs returns the invite of the group.
+func GetGroupInviteByIDs(guid strfmt.UUID, id int64) (*GroupInvite, error) {
+	invite := &GroupInvite{}
+	if has, err := x.Where(`group_uid=?`, guid).And(`id=?`, id).Get(invite); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrGroupInviteNotExist{GroupUID: guid, ID: id}
+	}
+	return invite, nil
+}
+
+// Revoke revokes the invite so that its token can't be used anymore.
+// Used invites can't be revoked.
+func (m *GroupInvite) Revoke() error {
+	if m.UsedAt != nil {
+		return ErrGroupInviteUsed{ID: m.ID}
+	}
+	if m.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	m.RevokedAt = &now
+	if _, err := x.ID(m.ID).Cols(`revoked_at`).Update(m); err != nil {
+		return err
+	}
+	m.State = GroupInviteStateRevoked
+	return nil
+}
+
+// useGroupInvite consumes the pending invite with the token for the user
+// and returns the group of the invite.
+func useGroupInvite(sess *xorm.Session, token, uid string) (strfmt.UUID, error) {
+	invite := &GroupInvite{}
+	if has, err := sess.Where(`token=?`, token).Get(invite); err != nil {
+		return "", err
+	} else if !has || invite.stateAt(time.Now()) != GroupInviteStatePending {
+		return "", ErrGroupCodeNotExist{Code: token}
+	}
+
+	// Only one user can use the token
+	now := time.Now().UTC()
+	affected, err := sess.
+		Where(`id=?`, invite.ID).
+		And(`used_at IS NULL`).
+		And(`revoked_at IS NULL`).
+		Cols(`used_by`, `used_at`).
+		Update(&GroupInvite{UsedBy: uid, UsedAt: &now})
+	if err != nil {
+		return "", err
+	} else if affected == 0 {
+		return "", ErrGroupCodeNotExist{Code: token}
+	}
+	return invite.GroupUID, nil
+}
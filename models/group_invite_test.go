@@ -0,0 +1,110 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateGroupInvite(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g := AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*Group)
+
+	invite, err := CreateGroupInvite(g, "1234567890fakefirebaseid0001", "arne@example.com", "de")
+	assert.NoError(t, err)
+	assert.Len(t, invite.Token, GroupCodeLength)
+	assert.Equal(t, GroupInviteStatePending, invite.State)
+
+	// Shared codes stay valid
+	valid, _ := IsGroupCodeValid("ABCDEFGHI123")
+	assert.True(t, valid)
+
+	invites, err := GetGroupInvitesByGroupUID(g.UID)
+	assert.NoError(t, err)
+	if assert.Len(t, invites, 1) {
+		assert.Equal(t, strfmt.Email("arne@example.com"), invites[0].Email)
+		assert.Equal(t, GroupInviteStatePending, invites[0].State)
+	}
+
+	invites, err = GetGroupInvitesByGroupUID("00112233-4455-6677-8899-aabbccddeef0")
+	assert.NoError(t, err)
+	assert.Empty(t, invites)
+}
+
+func TestGetGroupInviteByIDs(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g := AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*Group)
+
+	invite, err := CreateGroupInvite(g, "1234567890fakefirebaseid0001", "arne@example.com", "")
+	assert.NoError(t, err)
+
+	_, err = GetGroupInviteByIDs(g.UID, invite.ID)
+	assert.NoError(t, err)
+
+	// Invites of other groups
+	_, err = GetGroupInviteByIDs("00112233-4455-6677-8899-aabbccddeef0", invite.ID)
+	assert.True(t, IsErrGroupInviteNotExist(err))
+}
+
+func TestUser_JoinGroupWithInvite(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g := AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*Group)
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0003")}).(*User)
+
+	invite, err := CreateGroupInvite(g, "1234567890fakefirebaseid0001", u.Email, "")
+	assert.NoError(t, err)
+
+	joined, err := u.JoinGroupWithCode(invite.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, g.UID, joined.UID)
+
+	invite = AssertExistsAndLoadBean(t, &GroupInvite{ID: invite.ID}).(*GroupInvite)
+	assert.Equal(t, GroupInviteStateUsed, invite.State)
+	assert.Equal(t, *u.UID, invite.UsedBy)
+
+	// Tokens can only be used once
+	u4 := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0004")}).(*User)
+	_, err = u4.JoinGroupWithCode(invite.Token)
+	assert.True(t, IsErrGroupCodeNotExist(err))
+
+	// Used invites can't be revoked
+	assert.True(t, IsErrGroupInviteUsed(invite.Revoke()))
+}
+
+func TestGroupInvite_Revoke(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g := AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*Group)
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0003")}).(*User)
+
+	invite, err := CreateGroupInvite(g, "1234567890fakefirebaseid0001", u.Email, "")
+	assert.NoError(t, err)
+	assert.NoError(t, invite.Revoke())
+	assert.Equal(t, GroupInviteStateRevoked, invite.State)
+
+	_, err = u.JoinGroupWithCode(invite.Token)
+	assert.True(t, IsErrGroupCodeNotExist(err))
+	u = AssertExistsAndLoadBean(t, &User{UID: u.UID}).(*User)
+	assert.Empty(t, u.GroupUID)
+}
+
+func TestGroupInvite_Expired(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g := AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*Group)
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0003")}).(*User)
+
+	invite, err := CreateGroupInvite(g, "1234567890fakefirebaseid0001", u.Email, "")
+	assert.NoError(t, err)
+
+	invite.ValidUntil = strfmt.DateTime(time.Now().UTC().Add(-time.Minute))
+	_, err = x.ID(invite.ID).Cols(`valid_until`).Update(invite)
+	assert.NoError(t, err)
+
+	_, err = u.JoinGroupWithCode(invite.Token)
+	assert.True(t, IsErrGroupCodeNotExist(err))
+
+	invite = AssertExistsAndLoadBean(t, &GroupInvite{ID: invite.ID}).(*GroupInvite)
+	assert.Equal(t, GroupInviteStateExpired, invite.State)
+}
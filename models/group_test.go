@@ -47,3 +47,61 @@ func TestGroup_UnmarshalBinary(t *testing.T) {
 	assert.NoError(t, err1b)
 	assert.Equal(t, g1a, g1b)
 }
+
+func TestGroup_AddRemoveAdmin(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+
+	// The last admin can't be demoted
+	assert.True(t, IsErrGroupLastAdmin(g.RemoveAdmin("1234567890fakefirebaseid0001")))
+
+	// Only members can be promoted
+	assert.True(t, IsErrGroupNotMember(g.AddAdmin("1234567890fakefirebaseid0003")))
+
+	assert.NoError(t, g.AddAdmin("1234567890fakefirebaseid0002"))
+	assert.NoError(t, g.RemoveAdmin("1234567890fakefirebaseid0001"))
+
+	g = AssertExistsAndLoadBean(t, &Group{UID: g.UID}).(*Group)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0002"}, g.Admins)
+}
+
+func TestGroup_RemoveAdminConcurrently(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+	assert.NoError(t, g.AddAdmin("1234567890fakefirebaseid0002"))
+
+	// Both admins are demoted at the same time
+	other := *g
+	assert.NoError(t, g.RemoveAdmin("1234567890fakefirebaseid0001"))
+	assert.True(t, IsErrVersionMismatch(other.RemoveAdmin("1234567890fakefirebaseid0002")))
+
+	g = AssertExistsAndLoadBean(t, &Group{UID: g.UID}).(*Group)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0002"}, g.Admins)
+
+	// Only admins can transfer their rights
+	assert.True(t, IsErrGroupNotAdmin(g.TransferAdmin("1234567890fakefirebaseid0001", "1234567890fakefirebaseid0002")))
+}
+
+func TestGroup_RemoveMember(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+
+	admin, err := GetUserByUID("1234567890fakefirebaseid0001")
+	assert.NoError(t, err)
+	assert.True(t, IsErrGroupLastAdmin(g.RemoveMember(admin)))
+
+	other, err := GetUserByUID("1234567890fakefirebaseid0003")
+	assert.NoError(t, err)
+	assert.True(t, IsErrGroupNotMember(g.RemoveMember(other)))
+
+	member, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+	assert.NoError(t, g.RemoveMember(member))
+	assert.Equal(t, []string{"1234567890fakefirebaseid0001"}, g.Members)
+
+	member = AssertExistsAndLoadBean(t, &User{UID: member.UID}).(*User)
+	assert.Empty(t, member.GroupUID)
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKeyTTL is the time the response of a request with an
+// "Idempotency-Key" header is kept for retries.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyKeyLease is the time a request holds its key. If it doesn't
+// finish in time (e.g. because the server crashed), a retry takes over.
+const IdempotencyKeyLease = time.Minute
+
+// IdempotencyKey is the stored response of a request that was sent with an
+// "Idempotency-Key" header. Retries with the same key get this response
+// instead of being applied again.
+type IdempotencyKey struct {
+	ID         int64  `xorm:"pk autoincr"`
+	UserUID    string `xorm:"VARCHAR(28) UNIQUE(user_key) NOT NULL"`
+	RequestKey string `xorm:"VARCHAR(64) UNIQUE(user_key) NOT NULL"`
+
+	// RequestHash identifies the operation and body of the request.
+	RequestHash string `xorm:"VARCHAR(64) NOT NULL"`
+
+	// StatusCode is 0 while the request is in progress.
+	StatusCode int    `xorm:"DEFAULT 0"`
+	Response   string `xorm:"TEXT"`
+
+	// LockedUntil is the end of the lease of the request in progress (unix time).
+	LockedUntil int64     `xorm:"NOT NULL DEFAULT 0"`
+	CreatedAt   time.Time `xorm:"created INDEX"`
+}
+
+// IsInProgress returns true if the first request with the key hasn't finished yet.
+func (k *IdempotencyKey) IsInProgress() bool {
+	return k.StatusCode == 0
+}
+
+// isLeaseExpired returns true if the request in progress didn't finish in time.
+func (k *IdempotencyKey) isLeaseExpired(now time.Time) bool {
+	return k.IsInProgress() && k.LockedUntil <= now.Unix()
+}
+
+// takeOver moves the lease of an abandoned request to the caller. It returns
+// false if another request finished or took over the key in the meantime.
+func (k *IdempotencyKey) takeOver(now time.Time) (bool, error) {
+	lockedUntil := now.Add(IdempotencyKeyLease).Unix()
+	affected, err := x.
+		ID(k.ID).
+		And(`status_code = 0`).
+		And(`locked_until = ?`, k.LockedUntil).
+		Cols(`locked_until`).
+		Update(&IdempotencyKey{LockedUntil: lockedUntil})
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	k.LockedUntil = lockedUntil
+	return true, nil
+}
+
+// BeginIdempotentRequest reserves the key of the user for a request. If the
+// key was used before, the stored request is returned together with false.
+// A request with the same hash takes over the key if the lease of the
+// request in progress expired.
+func BeginIdempotentRequest(uid, key, hash string) (*IdempotencyKey, bool, error) {
+	now := time.Now().UTC()
+
+	existing := &IdempotencyKey{UserUID: uid, RequestKey: key}
+	if has, err := x.Get(existing); err != nil {
+		return nil, false, err
+	} else if has {
+		if existing.CreatedAt.After(now.Add(-IdempotencyKeyTTL)) {
+			if existing.RequestHash == hash && existing.isLeaseExpired(now) {
+				if ok, err := existing.takeOver(now); err != nil {
+					return nil, false, err
+				} else if ok {
+					return existing, true, nil
+				}
+				// Someone else was faster
+				if _, err := x.ID(existing.ID).Get(existing); err != nil {
+					return nil, false, err
+				}
+			}
+			return existing, false, nil
+		}
+		if err := existing.Release(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	k := &IdempotencyKey{
+		UserUID:     uid,
+		RequestKey:  key,
+		RequestHash: hash,
+		LockedUntil: now.Add(IdempotencyKeyLease).Unix(),
+	}
+	if _, err := x.InsertOne(k); err != nil {
+		// The key may have been reserved by a concurrent request
+		existing = &IdempotencyKey{UserUID: uid, RequestKey: key}
+		if has, errGet := x.Get(existing); errGet == nil && has {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+	return k, true, nil
+}
+
+// Finish stores the response of the request for retries.
+func (k *IdempotencyKey) Finish(statusCode int, response []byte) error {
+	k.StatusCode = statusCode
+	k.Response = string(response)
+	_, err := x.ID(k.ID).Cols(`status_code`, `response`).Update(k)
+	return err
+}
+
+// Release deletes the key, so that the request can be sent again,
+// e.g. after an internal error.
+func (k *IdempotencyKey) Release() error {
+	_, err := x.ID(k.ID).Delete(new(IdempotencyKey))
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys deletes the keys created before "before".
+func DeleteExpiredIdempotencyKeys(before time.Time) (int64, error) {
+	return x.Where(`created_at < ?`, before).Delete(new(IdempotencyKey))
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBeginIdempotentRequest(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	const uid = "1234567890fakefirebaseid0001"
+
+	k, isNew, err := BeginIdempotentRequest(uid, "key", "hash")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+
+	// In progress until finished
+	existing, isNew, err := BeginIdempotentRequest(uid, "key", "hash")
+	assert.NoError(t, err)
+	assert.False(t, isNew)
+	assert.True(t, existing.IsInProgress())
+
+	assert.NoError(t, k.Finish(200, []byte(`{"status":200}`)))
+	existing, isNew, err = BeginIdempotentRequest(uid, "key", "other")
+	assert.NoError(t, err)
+	assert.False(t, isNew)
+	assert.Equal(t, "hash", existing.RequestHash)
+	assert.Equal(t, 200, existing.StatusCode)
+	assert.Equal(t, `{"status":200}`, existing.Response)
+
+	// Keys belong to a user
+	_, isNew, err = BeginIdempotentRequest("1234567890fakefirebaseid0002", "key", "hash")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+
+	// Released keys can be used again
+	assert.NoError(t, existing.Release())
+	_, isNew, err = BeginIdempotentRequest(uid, "key", "hash")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+}
+
+func TestBeginIdempotentRequestLease(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	const uid = "1234567890fakefirebaseid0001"
+
+	k, isNew, err := BeginIdempotentRequest(uid, "key", "hash")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+	assert.True(t, k.LockedUntil > time.Now().Unix())
+
+	// The request crashed and its lease expired
+	_, err = x.ID(k.ID).Cols(`locked_until`).Update(&IdempotencyKey{LockedUntil: time.Now().Add(-time.Second).Unix()})
+	assert.NoError(t, err)
+
+	// Only retries of the same request take over
+	existing, isNew, err := BeginIdempotentRequest(uid, "key", "other")
+	assert.NoError(t, err)
+	assert.False(t, isNew)
+	assert.Equal(t, "hash", existing.RequestHash)
+
+	retry, isNew, err := BeginIdempotentRequest(uid, "key", "hash")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+	assert.Equal(t, k.ID, retry.ID)
+	assert.True(t, retry.LockedUntil > time.Now().Unix())
+
+	// The new lease is held
+	existing, isNew, err = BeginIdempotentRequest(uid, "key", "hash")
+	assert.NoError(t, err)
+	assert.False(t, isNew)
+	assert.True(t, existing.IsInProgress())
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	_, _, err := BeginIdempotentRequest("1234567890fakefirebaseid0001", "key", "hash")
+	assert.NoError(t, err)
+
+	n, err := DeleteExpiredIdempotencyKeys(time.Now().UTC().Add(-IdempotencyKeyTTL))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+
+	n, err = DeleteExpiredIdempotencyKeys(time.Now().UTC().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	AssertNotExistsBean(t, &IdempotencyKey{RequestKey: "key"})
+}
@@ -0,0 +1,279 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-xorm/xorm"
+)
+
+// ledgerRounding returns the configured rounding mode for remainder cents.
+func ledgerRounding() string {
+	if setting.AppConfig == nil || setting.AppConfig.Ledger.Rounding == "" {
+		return setting.RoundingPayer
+	}
+	return setting.AppConfig.Ledger.Rounding
+}
+
+// splitAmount splits "amount" (in cents) among "debtors". The shares always sum
+// up to "amount". Remainder cents are assigned depending on "rounding".
+// "offset" rotates the debtors that get a remainder cent for setting.RoundingSpread.
+func splitAmount(amount int64, debtors []string, payer string, rounding string, offset int) map[string]int64 {
+	shares := make(map[string]int64, len(debtors)+1)
+	if amount == 0 || len(debtors) == 0 {
+		return shares
+	}
+
+	n := int64(len(debtors))
+	share, remainder := amount/n, amount%n
+
+	for _, d := range debtors {
+		shares[d] += share
+	}
+
+	step := int64(1)
+	if remainder < 0 {
+		step, remainder = -1, -remainder
+	}
+
+	switch rounding {
+	case setting.RoundingFirst:
+		for i := int64(0); i < remainder; i++ {
+			shares[debtors[i]] += step
+		}
+	case setting.RoundingSpread:
+		for i := int64(0); i < remainder; i++ {
+			shares[debtors[(int64(offset)+i)%n]] += step
+		}
+	default:
+		shares[payer] += step * remainder
+	}
+
+	return shares
+}
+
+// computeBalances calculates the net balance of every user for the given items.
+// The buyer of an item is credited with its total price and every user in
+// "requestedFor" is debited with his share. Shares that were already paid
+// ("payments" maps a bill's uid to the users that paid it) are settled.
+func computeBalances(items []*ListItem, payments map[strfmt.UUID][]string, rounding string) map[string]int64 {
+	balances := make(map[string]int64)
+
+	for i, item := range items {
+		if item.BoughtBy == "" {
+			continue
+		}
+
+		debtors := base.Unique(item.RequestedFor)
+		if len(debtors) == 0 {
+			debtors = []string{item.BoughtBy}
+		}
+
+		price := item.TotalPrice()
+		balances[item.BoughtBy] += price
+		for uid, share := range splitAmount(price, debtors, item.BoughtBy, rounding, i) {
+			if uid != item.BoughtBy && base.StringInSlice(uid, payments[item.BillUID]) {
+				// The share was paid to the buyer
+				balances[item.BoughtBy] -= share
+				continue
+			}
+			balances[uid] -= share
+		}
+	}
+
+	return balances
+}
+
+// SettleBalances returns a list of transfers that settles all balances.
+// Debtors are greedily matched with creditors (largest amounts first), so
+// that there are at most n-1 transfers for n users with a non-zero balance.
+func SettleBalances(balances map[string]int64) []*Settlement {
+	type entry struct {
+		uid    string
+		amount int64
+	}
+
+	var creditors, debtors []*entry
+	for uid, b := range balances {
+		if b > 0 {
+			creditors = append(creditors, &entry{uid, b})
+		} else if b < 0 {
+			debtors = append(debtors, &entry{uid, -b})
+		}
+	}
+
+	byAmount := func(list []*entry) func(i, j int) bool {
+		return func(i, j int) bool {
+			if list[i].amount == list[j].amount {
+				return list[i].uid < list[j].uid
+			}
+			return list[i].amount > list[j].amount
+		}
+	}
+	sort.Slice(creditors, byAmount(creditors))
+	sort.Slice(debtors, byAmount(debtors))
+
+	settlements := make([]*Settlement, 0, len(debtors))
+
+	for c, d := 0, 0; c < len(creditors) && d < len(debtors); {
+		amount := creditors[c].amount
+		if debtors[d].amount < amount {
+			amount = debtors[d].amount
+		}
+
+		settlements = append(settlements, &Settlement{
+			From:   swag.String(debtors[d].uid),
+			To:     swag.String(creditors[c].uid),
+			Amount: swag.Int64(amount),
+		})
+
+		creditors[c].amount -= amount
+		debtors[d].amount -= amount
+
+		if creditors[c].amount == 0 {
+			c++
+		}
+		if debtors[d].amount == 0 {
+			d++
+		}
+	}
+
+	return settlements
+}
+
+// getBilledListItems returns all items of the group that belong to a bill.
+func getBilledListItems(sess *xorm.Session, guid strfmt.UUID) ([]*ListItem, error) {
+	items := make([]*ListItem, 0, 10)
+	err := sess.
+		Where(`group_uid=?`, guid).
+		And(`bill_uid IS NOT NULL`).
+		And(`bill_uid <> ?`, "").
+		Asc(`bought_at`, `id`).
+		Find(&items)
+	return items, err
+}
+
+// getBillPayments returns the users that paid their share of a bill for every bill of the group.
+func getBillPayments(sess *xorm.Session, guid strfmt.UUID) (map[strfmt.UUID][]string, error) {
+	bills := make([]*Bill, 0, 5)
+	if err := sess.Where(`group_uid=?`, guid).Find(&bills); err != nil {
+		return nil, err
+	}
+
+	payments := make(map[strfmt.UUID][]string, len(bills))
+	for _, b := range bills {
+		payments[b.UID] = b.PayedBy
+	}
+	return payments, nil
+}
+
+// RecomputeGroupBalances recalculates the balances of all users of the group
+// and stores them in the database.
+func RecomputeGroupBalances(guid strfmt.UUID) error {
+	return withTx(func(sess *xorm.Session) error {
+		return recomputeGroupBalances(sess, guid)
+	})
+}
+
+// recomputeGroupBalances recalculates and stores the balances of the group
+// within the transaction of the change that affects them. The group row is
+// locked, so that concurrent recomputes of a group don't interleave.
+func recomputeGroupBalances(sess *xorm.Session, guid strfmt.UUID) error {
+	if _, err := sess.Unscoped().ID(guid).ForUpdate().Get(new(Group)); err != nil {
+		return err
+	}
+
+	items, err := getBilledListItems(sess, guid)
+	if err != nil {
+		return err
+	}
+
+	payments, err := getBillPayments(sess, guid)
+	if err != nil {
+		return err
+	}
+
+	balances := computeBalances(items, payments, ledgerRounding())
+
+	members := make([]string, 0, 5)
+	if err := sess.Table(new(User)).Where(`group_uid=?`, guid).Cols(`uid`).Find(&members); err != nil {
+		return err
+	}
+	for _, m := range members {
+		if _, ok := balances[m]; !ok {
+			balances[m] = 0
+		}
+	}
+
+	uids := make([]string, 0, len(balances))
+	for uid := range balances {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	if err := failpoint("RecomputeGroupBalances"); err != nil {
+		return err
+	}
+
+	if _, err := sess.Where(`group_uid=?`, guid).Delete(new(MemberBalance)); err != nil {
+		return err
+	}
+
+	for _, uid := range uids {
+		_, err := sess.InsertOne(&MemberBalance{
+			GroupUID: guid,
+			UserUID:  swag.String(uid),
+			Balance:  swag.Int64(balances[uid]),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetGroupBalances returns the stored balances of the group's members
+// and the transfers that are needed to settle them.
+// Former members are only included if their balance is not settled.
+func GetGroupBalances(g *Group) (*GroupBalances, error) {
+	rows := make([]*MemberBalance, 0, len(g.Members))
+	if err := x.Where(`group_uid=?`, g.UID).Asc(`user_uid`).Find(&rows); err != nil {
+		return nil, err
+	}
+
+	result := &GroupBalances{
+		GroupUID:    g.UID,
+		Currency:    g.Currency,
+		Balances:    make([]*MemberBalance, 0, len(rows)),
+		Settlements: []*Settlement{},
+	}
+
+	balances := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		uid, balance := swag.StringValue(row.UserUID), swag.Int64Value(row.Balance)
+		if balance == 0 && !base.StringInSlice(uid, g.Members) {
+			continue
+		}
+		balances[uid] = balance
+		result.Balances = append(result.Balances, row)
+	}
+
+	for _, m := range g.Members {
+		if _, ok := balances[m]; !ok {
+			balances[m] = 0
+			result.Balances = append(result.Balances, &MemberBalance{
+				GroupUID: g.UID,
+				UserUID:  swag.String(m),
+				Balance:  swag.Int64(0),
+			})
+		}
+	}
+
+	result.Settlements = append(result.Settlements, SettleBalances(balances)...)
+
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitAmount(t *testing.T) {
+	debtors := []string{"a", "b", "c"}
+
+	shares1 := splitAmount(100, debtors, "c", setting.RoundingPayer, 0)
+	assert.Equal(t, map[string]int64{"a": 33, "b": 33, "c": 34}, shares1)
+
+	shares2 := splitAmount(101, debtors, "c", setting.RoundingFirst, 0)
+	assert.Equal(t, map[string]int64{"a": 34, "b": 34, "c": 33}, shares2)
+
+	shares3 := splitAmount(100, debtors, "c", setting.RoundingSpread, 2)
+	assert.Equal(t, map[string]int64{"a": 33, "b": 33, "c": 34}, shares3)
+
+	shares4 := splitAmount(-100, debtors, "a", setting.RoundingPayer, 0)
+	assert.Equal(t, map[string]int64{"a": -34, "b": -33, "c": -33}, shares4)
+
+	shares5 := splitAmount(100, []string{}, "a", setting.RoundingPayer, 0)
+	assert.Empty(t, shares5)
+}
+
+func TestComputeBalances(t *testing.T) {
+	items := []*ListItem{
+		{BillUID: "b1", BoughtBy: "a", Price: 90, RequestedFor: []string{"a", "b", "c"}},
+		{BillUID: "b2", BoughtBy: "b", Price: 40, RequestedFor: []string{"c"}},
+		{BillUID: "b2", BoughtBy: "", Price: 10, RequestedFor: []string{"a"}},
+	}
+
+	balances1 := computeBalances(items, nil, setting.RoundingPayer)
+	assert.Equal(t, map[string]int64{"a": 60, "b": 10, "c": -70}, balances1)
+
+	// "c" paid his share of "b1"
+	balances2 := computeBalances(items, map[strfmt.UUID][]string{"b1": {"a", "c"}}, setting.RoundingPayer)
+	assert.Equal(t, map[string]int64{"a": 30, "b": 10, "c": -40}, balances2)
+}
+
+func TestSettleBalances(t *testing.T) {
+	settlements1 := SettleBalances(map[string]int64{"a": 50, "b": -30, "c": -20, "d": 0})
+	assert.Equal(t, []*Settlement{
+		{From: swag.String("b"), To: swag.String("a"), Amount: swag.Int64(30)},
+		{From: swag.String("c"), To: swag.String("a"), Amount: swag.Int64(20)},
+	}, settlements1)
+
+	settlements2 := SettleBalances(map[string]int64{"a": 0, "b": 0})
+	assert.Empty(t, settlements2)
+}
+
+func TestRecomputeGroupBalances(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	guid := strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+
+	assert.NoError(t, RecomputeGroupBalances(guid))
+	AssertCount(t, &MemberBalance{GroupUID: guid}, 2)
+
+	b1 := AssertExistsAndLoadBean(t, &MemberBalance{UserUID: swag.String("1234567890fakefirebaseid0001")}).(*MemberBalance)
+	b2 := AssertExistsAndLoadBean(t, &MemberBalance{UserUID: swag.String("1234567890fakefirebaseid0002")}).(*MemberBalance)
+	assert.Equal(t, int64(50), *b1.Balance)
+	assert.Equal(t, int64(-50), *b2.Balance)
+}
+
+func TestGetGroupBalances(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+
+	balances, err := GetGroupBalances(g)
+	assert.NoError(t, err)
+	assert.Len(t, balances.Balances, 2)
+	assert.Len(t, balances.Settlements, 1)
+	assert.Equal(t, "1234567890fakefirebaseid0002", *balances.Settlements[0].From)
+	assert.Equal(t, "1234567890fakefirebaseid0001", *balances.Settlements[0].To)
+	assert.Equal(t, int64(50), *balances.Settlements[0].Amount)
+}
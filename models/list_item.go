@@ -1,14 +1,51 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/go-openapi/errors"
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
 )
 
+// Units of list items
+const (
+	UnitPieces     = "pcs"
+	UnitGram       = "g"
+	UnitKilogram   = "kg"
+	UnitMilliliter = "ml"
+	UnitLiter      = "l"
+	UnitPack       = "pack"
+)
+
+// Price modes of list items
+const (
+	// PriceModeTotal prices are the price of the whole item.
+	PriceModeTotal = "total"
+	// PriceModePerUnit prices are per piece, per pack, per kg or per l.
+	PriceModePerUnit = "perUnit"
+)
+
+// unitInfo describes a unit: units of the same kind can be merged, "factor"
+// converts a quantity to the unit that per unit prices refer to.
+type unitInfo struct {
+	kind   string
+	factor float64
+}
+
+var units = map[string]unitInfo{
+	"":             {"pieces", 1},
+	UnitPieces:     {"pieces", 1},
+	UnitPack:       {"pack", 1},
+	UnitGram:       {"mass", 0.001},
+	UnitKilogram:   {"mass", 1},
+	UnitMilliliter: {"volume", 0.001},
+	UnitLiter:      {"volume", 1},
+}
+
 type ListItem struct {
 	// bill UID
 	// Read Only: true
@@ -37,6 +74,17 @@ type ListItem struct {
 	// price
 	Price int64 `xorm:"DEFAULT 0" json:"price,omitempty"`
 
+	// price mode (total or perUnit; empty means total)
+	// Enum: [total perUnit]
+	PriceMode string `xorm:"VARCHAR(8)" json:"priceMode,omitempty"`
+
+	// unit of the quantity (empty means the count is the quantity)
+	// Enum: [pcs g kg ml l pack]
+	Unit string `xorm:"VARCHAR(8)" json:"unit,omitempty"`
+
+	// quantity in the unit (required if a unit is set)
+	Quantity *float64 `xorm:"DOUBLE NULL" json:"quantity,omitempty"`
+
 	// requested by
 	// Read Only: true
 	RequestedBy string `xorm:"NOT NULL" json:"requestedBy,omitempty"`
@@ -53,6 +101,15 @@ type ListItem struct {
 	// Max Length: 150
 	Title *string `xorm:"NOT NULL" json:"title"`
 
+	// template UID (set if the item was added by a recurring template)
+	// Read Only: true
+	TemplateUID strfmt.UUID `xorm:"VARCHAR(36) INDEX" json:"templateUID,omitempty"`
+
+	// remainder of (set if the item is the remainder of a partial purchase;
+	// the UID of the item that was originally requested)
+	// Read Only: true
+	RemainderOf strfmt.UUID `xorm:"VARCHAR(36) INDEX" json:"remainderOf,omitempty"`
+
 	// created at
 	// Read Only: true
 	CreatedAt strfmt.DateTime `xorm:"created" json:"createdAt,omitempty"`
@@ -60,6 +117,10 @@ type ListItem struct {
 	// updated at
 	// Read Only: true
 	UpdatedAt strfmt.DateTime `xorm:"updated" json:"updatedAt,omitempty"`
+
+	// version (incremented on every change, used as ETag)
+	// Read Only: true
+	Version int64 `xorm:"NOT NULL DEFAULT 1" json:"version,omitempty"`
 }
 
 // AfterLoad is invoked from XORM after setting the values of all fields of this object.
@@ -89,6 +150,18 @@ func (l *ListItem) Validate(formats strfmt.Registry) error {
 		// prop
 		res = append(res, err)
 	}
+	if err := l.validatePriceMode(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+	if err := l.validateUnit(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+	if err := l.validateQuantity(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -126,6 +199,73 @@ func (l *ListItem) validateTitle(formats strfmt.Registry) error {
 	return nil
 }
 
+func (l *ListItem) validatePriceMode(formats strfmt.Registry) error {
+	if swag.IsZero(l.PriceMode) { // not required
+		return nil
+	}
+	if err := validate.Enum("priceMode", "body", l.PriceMode,
+		[]interface{}{PriceModeTotal, PriceModePerUnit}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *ListItem) validateUnit(formats strfmt.Registry) error {
+	if swag.IsZero(l.Unit) { // not required
+		if l.Quantity != nil {
+			// A quantity needs a unit
+			if err := validate.RequiredString("unit", "body", l.Unit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := validate.Enum("unit", "body", l.Unit,
+		[]interface{}{UnitPieces, UnitGram, UnitKilogram, UnitMilliliter, UnitLiter, UnitPack}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *ListItem) validateQuantity(formats strfmt.Registry) error {
+	if swag.IsZero(l.Unit) { // not required
+		return nil
+	}
+	if err := validate.Required("quantity", "body", l.Quantity); err != nil {
+		return err
+	}
+	if err := validate.Minimum("quantity", "body", *l.Quantity, 0, true); err != nil {
+		return err
+	}
+	if l.Unit == UnitPieces || l.Unit == UnitPack {
+		if err := validate.MultipleOf("quantity", "body", *l.Quantity, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roundQuantity rounds a quantity to three decimals to hide float errors.
+func roundQuantity(q float64) float64 {
+	return math.Round(q*1000) / 1000
+}
+
+// amount returns the quantity of the item in the unit that per unit prices refer to.
+func (l *ListItem) amount() float64 {
+	if l.Unit == "" || l.Quantity == nil {
+		return float64(swag.Int64Value(l.Count))
+	}
+	return *l.Quantity * units[l.Unit].factor
+}
+
+// TotalPrice returns the price of the whole item.
+func (l *ListItem) TotalPrice() int64 {
+	if l.PriceMode != PriceModePerUnit {
+		return l.Price
+	}
+	return int64(math.Round(float64(l.Price) * l.amount()))
+}
+
 // MarshalBinary interface implementation
 func (l *ListItem) MarshalBinary() ([]byte, error) {
 	if l == nil {
@@ -144,6 +284,15 @@ func (l *ListItem) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// OriginUID returns the UID of the item that was originally requested. Items
+// that are split by partial purchases share it.
+func (l *ListItem) OriginUID() strfmt.UUID {
+	if l.RemainderOf != "" {
+		return l.RemainderOf
+	}
+	return l.ID
+}
+
 func GetListItemByUIDs(guid, luid strfmt.UUID) (*ListItem, error) {
 	l := &ListItem{
 		GroupUID: guid,
@@ -161,19 +310,84 @@ func GetListItemByUIDs(guid, luid strfmt.UUID) (*ListItem, error) {
 }
 
 func CreateListItem(item *ListItem) error {
-	_, err := x.InsertOne(item)
-	return err
+	return withTx(func(sess *xorm.Session) error {
+		if _, err := sess.InsertOne(item); err != nil {
+			return err
+		}
+		return recordChange(sess, item.GroupUID, SyncTypeListItem, string(item.ID), false)
+	})
 }
 
 func UpdateListItem(l *ListItem) error {
-	_, err := x.AllCols().Update(l)
-	return err
+	return UpdateListItemColsIfVersion(l, 0)
 }
 
 func UpdateListItemCols(l *ListItem, cols ...string) error {
-	_, err := x.Cols(cols...).
-		Where(`group_uid=?`, l.GroupUID).
-		And(`id=?`, l.ID).
-		Update(l)
-	return err
+	return UpdateListItemColsIfVersion(l, 0, cols...)
+}
+
+// UpdateListItemColsIfVersion updates the columns of the item if it still has
+// the given version. A version of 0 matches every version. Items that were
+// already bought or are part of a bill can't be updated, as their price is
+// part of the balances.
+func UpdateListItemColsIfVersion(l *ListItem, version int64, cols ...string) error {
+	return withTx(func(sess *xorm.Session) error {
+		n, err := updateVersioned(sess.
+			Where(`group_uid=?`, l.GroupUID).
+			And(`id=?`, l.ID).
+			And(`bought_at IS NULL`).
+			And(`(bill_uid IS NULL OR bill_uid = ?)`, ""), l, version, cols...)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			stored := new(ListItem)
+			if has, err := sess.Where(`group_uid=?`, l.GroupUID).And(`id=?`, l.ID).Get(stored); err != nil {
+				return err
+			} else if !has {
+				return ErrListItemNotExist{GroupUID: l.GroupUID, ID: l.ID}
+			} else if stored.BillUID != "" {
+				return ErrListItemHasBill{ID: l.ID, GroupUID: l.GroupUID}
+			} else if stored.BoughtAt != nil {
+				return ErrListItemIsBought{ID: l.ID, GroupUID: l.GroupUID}
+			}
+			return ErrVersionMismatch{Type: "list item", ID: string(l.ID), Version: version}
+		}
+		return recordChange(sess, l.GroupUID, SyncTypeListItem, string(l.ID), false)
+	})
+}
+
+// DeleteListItem deletes the item. Items that were
+// already bought or are part of a bill can't be deleted.
+// ErrListItemNotExist is returned if it was deleted in the meantime.
+func DeleteListItem(l *ListItem) error {
+	if l.BillUID != "" {
+		return ErrListItemHasBill{ID: l.ID, GroupUID: l.GroupUID}
+	}
+	if l.BoughtAt != nil || l.BoughtBy != "" {
+		return ErrListItemIsBought{ID: l.ID, GroupUID: l.GroupUID}
+	}
+
+	return withTx(func(sess *xorm.Session) error {
+		n, err := sess.
+			Where(`group_uid=?`, l.GroupUID).
+			And(`id=?`, l.ID).
+			And(`bought_at IS NULL`).
+			And(`(bill_uid IS NULL OR bill_uid = ?)`, "").
+			Delete(new(ListItem))
+		if err != nil {
+			return err
+		} else if n == 0 {
+			// Changed since it was read
+			stored := new(ListItem)
+			if has, err := sess.Where(`group_uid=?`, l.GroupUID).And(`id=?`, l.ID).Get(stored); err != nil {
+				return err
+			} else if !has {
+				return ErrListItemNotExist{GroupUID: l.GroupUID, ID: l.ID}
+			} else if stored.BillUID != "" {
+				return ErrListItemHasBill{ID: l.ID, GroupUID: l.GroupUID}
+			}
+			return ErrListItemIsBought{ID: l.ID, GroupUID: l.GroupUID}
+		}
+		return recordChange(sess, l.GroupUID, SyncTypeListItem, string(l.ID), true)
+	})
 }
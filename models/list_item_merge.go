@@ -0,0 +1,129 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+
+	"github.com/go-openapi/swag"
+	"github.com/go-xorm/xorm"
+)
+
+// canMergeListItems returns true if "other" can be added to "item": both are
+// unbought, have the same title and units of the same kind.
+func canMergeListItems(item, other *ListItem) bool {
+	if item.BoughtAt != nil || item.BillUID != "" {
+		return false
+	}
+	if !strings.EqualFold(strings.TrimSpace(swag.StringValue(item.Title)),
+		strings.TrimSpace(swag.StringValue(other.Title))) {
+		return false
+	}
+	unit, ok := units[item.Unit]
+	otherUnit, otherOk := units[other.Unit]
+	return ok && otherOk && unit.kind == otherUnit.kind
+}
+
+// quantityIn returns the quantity of the item converted to "unit", which has
+// to be of the same kind.
+func (l *ListItem) quantityIn(unit string) float64 {
+	q := float64(swag.Int64Value(l.Count))
+	if l.Unit != "" && l.Quantity != nil {
+		q = *l.Quantity
+	}
+	return roundQuantity(q * units[l.Unit].factor / units[unit].factor)
+}
+
+// mergeListItem adds the quantity, price and "requestedFor" of "other" to
+// "item". The quantity is kept in the unit of "item", e.g. 500 g + 1 kg are
+// 1500 g. Per unit prices are only kept if both items have the same one,
+// otherwise the price becomes the sum of both total prices.
+func mergeListItem(item, other *ListItem) {
+	samePerUnitPrice := item.PriceMode == PriceModePerUnit &&
+		other.PriceMode == PriceModePerUnit && item.Price == other.Price
+	total := item.TotalPrice() + other.TotalPrice()
+
+	if item.Unit == "" {
+		item.Count = swag.Int64(swag.Int64Value(item.Count) + int64(other.quantityIn(UnitPieces)))
+	} else {
+		item.Quantity = swag.Float64(roundQuantity(item.quantityIn(item.Unit) + other.quantityIn(item.Unit)))
+	}
+
+	if !samePerUnitPrice {
+		item.Price = total
+		if item.PriceMode == PriceModePerUnit {
+			item.PriceMode = PriceModeTotal
+		}
+	}
+
+	for _, uid := range other.RequestedFor {
+		if !base.StringInSlice(uid, item.RequestedFor) {
+			item.RequestedFor = append(item.RequestedFor, uid)
+		}
+	}
+}
+
+// mergeRetries is the number of attempts to merge an item into an item that
+// is changed concurrently.
+const mergeRetries = 3
+
+// MergeOrCreateListItem adds the item to the oldest unbought item of its group
+// with the same title and a unit of the same kind. If there is none, the item
+// is created. The stored item is returned together with true if it was merged.
+func MergeOrCreateListItem(item *ListItem) (stored *ListItem, merged bool, err error) {
+	for i := 0; i < mergeRetries; i++ {
+		if stored, merged, err = mergeOrCreateListItem(item); !IsErrVersionMismatch(err) {
+			break
+		}
+	}
+	return stored, merged, err
+}
+
+func mergeOrCreateListItem(item *ListItem) (*ListItem, bool, error) {
+	var stored *ListItem
+
+	err := withTx(func(sess *xorm.Session) error {
+		candidates := make([]*ListItem, 0, 5)
+		if err := sess.Where(`group_uid=?`, item.GroupUID).
+			And(`bought_at IS NULL`).
+			And(`(bill_uid IS NULL OR bill_uid = ?)`, "").
+			Asc(`created_at`).
+			Find(&candidates); err != nil {
+			return err
+		}
+
+		for _, candidate := range candidates {
+			if canMergeListItems(candidate, item) {
+				stored = candidate
+				break
+			}
+		}
+
+		if stored == nil {
+			if _, err := sess.InsertOne(item); err != nil {
+				return err
+			}
+			stored = item
+			return recordChange(sess, item.GroupUID, SyncTypeListItem, string(item.ID), false)
+		}
+
+		mergeListItem(stored, item)
+		n, err := updateVersioned(sess.Where(`group_uid=?`, stored.GroupUID).And(`id=?`, stored.ID),
+			stored, stored.Version, `count`, `quantity`, `price`, `price_mode`, `requested_for`)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			return ErrVersionMismatch{Type: "list item", ID: string(stored.ID), Version: stored.Version}
+		}
+		return recordChange(sess, stored.GroupUID, SyncTypeListItem, string(stored.ID), false)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if stored == item {
+		return item, false, nil
+	}
+	stored, err = GetListItemByUIDs(stored.GroupUID, stored.ID)
+	return stored, err == nil, err
+}
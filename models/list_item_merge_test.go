@@ -0,0 +1,95 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFlour(unit string, quantity float64) *ListItem {
+	return &ListItem{
+		ID:           "00112233-4455-6677-8899-000000000020",
+		GroupUID:     testGroupUID,
+		Title:        swag.String("Flour"),
+		Category:     swag.String("Groceries"),
+		Count:        swag.Int64(1),
+		Unit:         unit,
+		Quantity:     swag.Float64(quantity),
+		RequestedBy:  "1234567890fakefirebaseid0001",
+		RequestedFor: []string{"1234567890fakefirebaseid0001"},
+	}
+}
+
+func TestMergeOrCreateListItem(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// Nothing to merge with
+	item, merged, err := MergeOrCreateListItem(newFlour(UnitGram, 500))
+	assert.NoError(t, err)
+	assert.False(t, merged)
+	AssertExistsAndLoadBean(t, &ListItem{ID: item.ID})
+
+	// 500 g + 1 kg
+	other := newFlour(UnitKilogram, 1)
+	other.ID = "00112233-4455-6677-8899-000000000021"
+	other.Title = swag.String(" flour ")
+	other.RequestedFor = []string{"1234567890fakefirebaseid0002"}
+	item, merged, err = MergeOrCreateListItem(other)
+	assert.NoError(t, err)
+	assert.True(t, merged)
+	assert.EqualValues(t, "00112233-4455-6677-8899-000000000020", item.ID)
+	assert.Equal(t, UnitGram, item.Unit)
+	assert.Equal(t, 1500.0, *item.Quantity)
+	assert.EqualValues(t, 2, item.Version)
+	assert.ElementsMatch(t, []string{"1234567890fakefirebaseid0001", "1234567890fakefirebaseid0002"}, item.RequestedFor)
+	AssertNotExistsBean(t, &ListItem{ID: other.ID})
+
+	// Units of another kind aren't merged
+	other = newFlour(UnitLiter, 1)
+	other.ID = "00112233-4455-6677-8899-000000000022"
+	_, merged, err = MergeOrCreateListItem(other)
+	assert.NoError(t, err)
+	assert.False(t, merged)
+
+	// Counts of items without a unit are added, as well as their prices
+	apples := &ListItem{
+		ID:           "00112233-4455-6677-8899-000000000023",
+		GroupUID:     testGroupUID,
+		Title:        swag.String("Apples"),
+		Category:     swag.String("Groceries"),
+		Count:        swag.Int64(5),
+		Price:        20,
+		RequestedBy:  "1234567890fakefirebaseid0001",
+		RequestedFor: []string{"1234567890fakefirebaseid0001"},
+	}
+	item, merged, err = MergeOrCreateListItem(apples)
+	assert.NoError(t, err)
+	assert.True(t, merged)
+	assert.EqualValues(t, "00112233-4455-6677-8899-000000000002", item.ID)
+	assert.EqualValues(t, 20, *item.Count)
+	assert.EqualValues(t, 100, item.Price)
+}
+
+func TestMergeListItemPrices(t *testing.T) {
+	item := newFlour(UnitKilogram, 1)
+	item.Price = 80
+	item.PriceMode = PriceModePerUnit
+
+	// The same per unit price is kept
+	other := newFlour(UnitGram, 500)
+	other.Price = 80
+	other.PriceMode = PriceModePerUnit
+	mergeListItem(item, other)
+	assert.Equal(t, 1.5, *item.Quantity)
+	assert.Equal(t, PriceModePerUnit, item.PriceMode)
+	assert.EqualValues(t, 120, item.TotalPrice())
+
+	// Otherwise the totals are added
+	other = newFlour(UnitGram, 500)
+	other.Price = 100
+	mergeListItem(item, other)
+	assert.Equal(t, 2.0, *item.Quantity)
+	assert.Equal(t, PriceModeTotal, item.PriceMode)
+	assert.EqualValues(t, 220, item.TotalPrice())
+}
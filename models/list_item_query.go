@@ -0,0 +1,311 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+// States of list items that can be queried
+const (
+	ListItemStateUnbought = "unbought"
+	ListItemStateBought   = "bought"
+	ListItemStateBilled   = "billed"
+	ListItemStateAll      = "all"
+)
+
+// Sort orders of list item queries
+const (
+	ListItemSortCreatedAt = "createdAt"
+	ListItemSortTitle     = "title"
+	ListItemSortCategory  = "category"
+	ListItemSortPrice     = "price"
+)
+
+// Limits of list item queries. Queries without a limit and cursor
+// return all items.
+const (
+	ListItemQueryDefaultLimit = 100
+	ListItemQueryMaxLimit     = 500
+)
+
+// listItemSortColumns maps sort options to columns. Items are sorted
+// by their TotalPrice, so that the order matches the displayed prices.
+var listItemSortColumns = map[string]string{
+	ListItemSortCreatedAt: "created_at",
+	ListItemSortTitle:     "title",
+	ListItemSortCategory:  "category",
+	ListItemSortPrice:     totalPriceExpr(),
+}
+
+// totalPriceExpr returns a SQL expression that calculates ListItem.TotalPrice.
+func totalPriceExpr() string {
+	names := make([]string, 0, len(units))
+	for name := range units {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	factors := make([]string, 0, len(names))
+	for _, name := range names {
+		factors = append(factors, fmt.Sprintf(`WHEN '%s' THEN %g`, name, units[name].factor))
+	}
+
+	amount := `CASE WHEN unit IS NULL OR unit = '' OR quantity IS NULL THEN COALESCE(count, 0) ` +
+		`ELSE quantity * (CASE unit ` + strings.Join(factors, " ") + ` ELSE 0 END) END`
+	return `(CASE WHEN price_mode = '` + PriceModePerUnit + `' THEN ROUND(price * (` + amount + `)) ` +
+		`ELSE price END)`
+}
+
+// ListItemQuery contains filters, sort options and the pagination
+// cursor for a query of a group's list items.
+type ListItemQuery struct {
+	Category     string
+	RequestedBy  string
+	RequestedFor string
+	// State is one of ListItemStateUnbought (default), ListItemStateBought,
+	// ListItemStateBilled or ListItemStateAll
+	State string
+
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	BoughtAfter   *time.Time
+	BoughtBefore  *time.Time
+
+	// Sort is one of ListItemSortCreatedAt (default), ListItemSortTitle,
+	// ListItemSortCategory or ListItemSortPrice
+	Sort string
+	Desc bool
+
+	Limit  int
+	Cursor string
+}
+
+// listItemCursor points at the last item of a page.
+type listItemCursor struct {
+	Sort  string      `json:"s"`
+	Desc  bool        `json:"d"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"i"`
+}
+
+// encodeCursor returns the cursor for the page after "item".
+func (q *ListItemQuery) encodeCursor(item *ListItem) string {
+	c := listItemCursor{Sort: q.Sort, Desc: q.Desc, ID: string(item.ID)}
+
+	switch q.Sort {
+	case ListItemSortTitle:
+		c.Value = *item.Title
+	case ListItemSortCategory:
+		c.Value = *item.Category
+	case ListItemSortPrice:
+		c.Value = item.TotalPrice()
+	default:
+		c.Value = time.Time(item.CreatedAt).Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes the query's cursor and returns the value of the sort column.
+func (q *ListItemQuery) decodeCursor() (*listItemCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, nil, ErrListItemInvalidCursor{Cursor: q.Cursor}
+	}
+
+	c := new(listItemCursor)
+	if err = json.Unmarshal(data, c); err != nil || c.ID == "" {
+		return nil, nil, ErrListItemInvalidCursor{Cursor: q.Cursor}
+	}
+
+	// The cursor is only valid for the same sort order.
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, nil, ErrListItemInvalidCursor{Cursor: q.Cursor}
+	}
+
+	switch v := c.Value.(type) {
+	case float64:
+		if q.Sort != ListItemSortPrice {
+			return nil, nil, ErrListItemInvalidCursor{Cursor: q.Cursor}
+		}
+		return c, int64(v), nil
+
+	case string:
+		if q.Sort == ListItemSortPrice {
+			return nil, nil, ErrListItemInvalidCursor{Cursor: q.Cursor}
+		}
+		if q.Sort == ListItemSortCreatedAt {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, nil, ErrListItemInvalidCursor{Cursor: q.Cursor}
+			}
+			return c, formatDBTime(t), nil
+		}
+		return c, v, nil
+	}
+
+	return nil, nil, ErrListItemInvalidCursor{Cursor: q.Cursor}
+}
+
+// normalize sets default values and validates the query.
+func (q *ListItemQuery) normalize() error {
+	if q.State == "" {
+		q.State = ListItemStateUnbought
+	}
+	if q.Sort == "" {
+		q.Sort = ListItemSortCreatedAt
+	}
+	if _, ok := listItemSortColumns[q.Sort]; !ok {
+		return ErrListItemInvalidQuery{Field: "sort"}
+	}
+	if q.Limit <= 0 && q.Cursor != "" {
+		q.Limit = ListItemQueryDefaultLimit
+	} else if q.Limit < 0 {
+		q.Limit = 0
+	} else if q.Limit > ListItemQueryMaxLimit {
+		q.Limit = ListItemQueryMaxLimit
+	}
+	return nil
+}
+
+// formatDBTime formats the time the way xorm stores it, so that
+// it can be compared with the values of time columns.
+func formatDBTime(t time.Time) string {
+	return t.In(x.DatabaseTZ).Format("2006-01-02 15:04:05")
+}
+
+// listItemCond is a SQL condition with its arguments.
+type listItemCond struct {
+	query string
+	args  []interface{}
+}
+
+// filterConds returns the conditions of all filters (without the cursor).
+func (q *ListItemQuery) filterConds(g *Group) ([]listItemCond, error) {
+	conds := []listItemCond{{`group_uid=?`, []interface{}{g.UID}}}
+
+	if q.Category != "" {
+		conds = append(conds, listItemCond{`category=?`, []interface{}{q.Category}})
+	}
+	if q.RequestedBy != "" {
+		conds = append(conds, listItemCond{`requested_by=?`, []interface{}{q.RequestedBy}})
+	}
+	if q.RequestedFor != "" {
+		// "requested_for" is stored as a JSON array
+		conds = append(conds, listItemCond{`requested_for LIKE ?`, []interface{}{`%"` + q.RequestedFor + `"%`}})
+	}
+
+	switch q.State {
+	case ListItemStateUnbought:
+		conds = append(conds, listItemCond{`bought_at IS NULL`, nil})
+	case ListItemStateBought:
+		conds = append(conds, listItemCond{`bought_at IS NOT NULL AND (bill_uid IS NULL OR bill_uid = ?)`, []interface{}{""}})
+	case ListItemStateBilled:
+		conds = append(conds, listItemCond{`bill_uid IS NOT NULL AND bill_uid <> ?`, []interface{}{""}})
+	case ListItemStateAll:
+	default:
+		return nil, ErrListItemInvalidQuery{Field: "state"}
+	}
+
+	if q.CreatedAfter != nil {
+		conds = append(conds, listItemCond{`created_at >= ?`, []interface{}{formatDBTime(*q.CreatedAfter)}})
+	}
+	if q.CreatedBefore != nil {
+		conds = append(conds, listItemCond{`created_at < ?`, []interface{}{formatDBTime(*q.CreatedBefore)}})
+	}
+	if q.BoughtAfter != nil {
+		conds = append(conds, listItemCond{`bought_at >= ?`, []interface{}{formatDBTime(*q.BoughtAfter)}})
+	}
+	if q.BoughtBefore != nil {
+		conds = append(conds, listItemCond{`bought_at < ?`, []interface{}{formatDBTime(*q.BoughtBefore)}})
+	}
+
+	return conds, nil
+}
+
+// cursorCond returns the condition that selects all items after the cursor.
+func (q *ListItemQuery) cursorCond() (listItemCond, error) {
+	c, value, err := q.decodeCursor()
+	if err != nil {
+		return listItemCond{}, err
+	}
+
+	col, op := listItemSortColumns[q.Sort], ">"
+	if q.Desc {
+		op = "<"
+	}
+
+	return listItemCond{
+		query: `(` + col + ` ` + op + ` ? OR (` + col + ` = ? AND id ` + op + ` ?))`,
+		args:  []interface{}{value, value, c.ID},
+	}, nil
+}
+
+// newListItemSession returns a session with all conditions applied.
+func newListItemSession(conds []listItemCond) *xorm.Session {
+	sess := x.Where(conds[0].query, conds[0].args...)
+	for _, c := range conds[1:] {
+		sess = sess.And(c.query, c.args...)
+	}
+	return sess
+}
+
+// FindListItems returns a page of the group's list items that match the query.
+// "Count" of the result is the total number of matching items. If there are more
+// items, "NextCursor" can be used to get the next page.
+func (g *Group) FindListItems(q *ListItemQuery) (*ShoppingList, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+
+	conds, err := q.filterConds(g)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := newListItemSession(conds).Count(new(ListItem))
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Cursor != "" {
+		cursorCond, err := q.cursorCond()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cursorCond)
+	}
+
+	// The price is sorted by an expression, which Asc and Desc would quote
+	order := "ASC"
+	if q.Desc {
+		order = "DESC"
+	}
+	sess := newListItemSession(conds).
+		OrderBy(listItemSortColumns[q.Sort] + " " + order + ", id " + order)
+
+	if q.Limit > 0 {
+		sess = sess.Limit(q.Limit + 1)
+	}
+	items := make([]*ListItem, 0, q.Limit+1)
+	if err = sess.Find(&items); err != nil {
+		return nil, err
+	}
+
+	list := &ShoppingList{Count: total, ListItems: items}
+	if q.Limit > 0 && len(items) > q.Limit {
+		list.ListItems = items[:q.Limit]
+		list.NextCursor = q.encodeCursor(list.ListItems[q.Limit-1])
+	}
+
+	return list, nil
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func listItemIDs(list *ShoppingList) []strfmt.UUID {
+	ids := make([]strfmt.UUID, 0, len(list.ListItems))
+	for _, item := range list.ListItems {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestGroup_FindListItemsFilters(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID(testGroupUID)
+	assert.NoError(t, err)
+
+	list1, err1 := g.FindListItems(&ListItemQuery{})
+	assert.NoError(t, err1)
+	assert.Equal(t, int64(2), list1.Count)
+	assert.Empty(t, list1.NextCursor)
+
+	list2, err2 := g.FindListItems(&ListItemQuery{State: ListItemStateAll})
+	assert.NoError(t, err2)
+	assert.Equal(t, int64(5), list2.Count)
+
+	list3, err3 := g.FindListItems(&ListItemQuery{State: ListItemStateBilled})
+	assert.NoError(t, err3)
+	assert.Equal(t, int64(2), list3.Count)
+
+	list4, err4 := g.FindListItems(&ListItemQuery{State: ListItemStateBought})
+	assert.NoError(t, err4)
+	assert.Equal(t, []strfmt.UUID{"00112233-4455-6677-8899-000000000004"}, listItemIDs(list4))
+
+	list5, err5 := g.FindListItems(&ListItemQuery{State: ListItemStateAll, RequestedFor: "1234567890fakefirebaseid0002"})
+	assert.NoError(t, err5)
+	assert.Equal(t, int64(3), list5.Count)
+
+	list6, err6 := g.FindListItems(&ListItemQuery{State: ListItemStateAll, RequestedBy: "1234567890fakefirebaseid0002"})
+	assert.NoError(t, err6)
+	assert.Equal(t, int64(1), list6.Count)
+
+	list7, err7 := g.FindListItems(&ListItemQuery{State: ListItemStateAll, Category: "Household"})
+	assert.NoError(t, err7)
+	assert.Equal(t, int64(0), list7.Count)
+
+	boughtAfter := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	list8, err8 := g.FindListItems(&ListItemQuery{State: ListItemStateAll, BoughtAfter: &boughtAfter})
+	assert.NoError(t, err8)
+	assert.Equal(t, []strfmt.UUID{"00112233-4455-6677-8899-000000000003"}, listItemIDs(list8))
+
+	_, err9 := g.FindListItems(&ListItemQuery{State: "invalid"})
+	assert.True(t, IsErrListItemInvalidQuery(err9))
+}
+
+func TestGroup_FindListItemsPagination(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID(testGroupUID)
+	assert.NoError(t, err)
+
+	query := &ListItemQuery{State: ListItemStateAll, Sort: ListItemSortPrice, Desc: true, Limit: 2}
+
+	page1, err := g.FindListItems(query)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), page1.Count)
+	assert.Equal(t, []strfmt.UUID{
+		"00112233-4455-6677-8899-000000000003",
+		"00112233-4455-6677-8899-000000000004",
+	}, listItemIDs(page1))
+	assert.NotEmpty(t, page1.NextCursor)
+
+	query.Cursor = page1.NextCursor
+	page2, err := g.FindListItems(query)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), page2.Count)
+	assert.Equal(t, []strfmt.UUID{
+		"00112233-4455-6677-8899-000000000001",
+		"00112233-4455-6677-8899-000000000005",
+	}, listItemIDs(page2))
+
+	query.Cursor = page2.NextCursor
+	page3, err := g.FindListItems(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []strfmt.UUID{"00112233-4455-6677-8899-000000000002"}, listItemIDs(page3))
+	assert.Empty(t, page3.NextCursor)
+
+	// Cursor of another sort order
+	_, err = g.FindListItems(&ListItemQuery{State: ListItemStateAll, Sort: ListItemSortTitle, Cursor: page1.NextCursor})
+	assert.True(t, IsErrListItemInvalidCursor(err))
+
+	_, err = g.FindListItems(&ListItemQuery{Cursor: "invalid"})
+	assert.True(t, IsErrListItemInvalidCursor(err))
+}
+
+func TestGroup_FindListItemsSortByTitle(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID(testGroupUID)
+	assert.NoError(t, err)
+
+	query := &ListItemQuery{State: ListItemStateAll, Sort: ListItemSortTitle, Limit: 3}
+	page1, err := g.FindListItems(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []strfmt.UUID{
+		"00112233-4455-6677-8899-000000000002",
+		"00112233-4455-6677-8899-000000000005",
+		"00112233-4455-6677-8899-000000000003",
+	}, listItemIDs(page1))
+
+	query.Cursor = page1.NextCursor
+	page2, err := g.FindListItems(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []strfmt.UUID{
+		"00112233-4455-6677-8899-000000000004",
+		"00112233-4455-6677-8899-000000000001",
+	}, listItemIDs(page2))
+}
+
+func TestGroup_FindListItemsSortByTotalPrice(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// 15 Apples for 80 each, 0.5 kg Apples for 80 per kg
+	_, err := x.Where(`id=?`, "00112233-4455-6677-8899-000000000002").Cols(`price_mode`).
+		Update(&ListItem{PriceMode: PriceModePerUnit})
+	assert.NoError(t, err)
+	_, err = x.Where(`id=?`, "00112233-4455-6677-8899-000000000005").Cols(`price_mode`, `unit`, `quantity`).
+		Update(&ListItem{PriceMode: PriceModePerUnit, Unit: UnitKilogram, Quantity: swag.Float64(0.5)})
+	assert.NoError(t, err)
+
+	g, err := GetGroupByUID(testGroupUID)
+	assert.NoError(t, err)
+
+	query := &ListItemQuery{State: ListItemStateAll, Sort: ListItemSortPrice, Desc: true, Limit: 3}
+	page1, err := g.FindListItems(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []strfmt.UUID{
+		"00112233-4455-6677-8899-000000000002",
+		"00112233-4455-6677-8899-000000000003",
+		"00112233-4455-6677-8899-000000000004",
+	}, listItemIDs(page1))
+	assert.Equal(t, int64(1200), page1.ListItems[0].TotalPrice())
+
+	query.Cursor = page1.NextCursor
+	page2, err := g.FindListItems(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []strfmt.UUID{
+		"00112233-4455-6677-8899-000000000001",
+		"00112233-4455-6677-8899-000000000005",
+	}, listItemIDs(page2))
+	assert.Equal(t, int64(40), page2.ListItems[1].TotalPrice())
+}
+
+func TestGroup_FindListItemsSortByCreatedAt(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID(testGroupUID)
+	assert.NoError(t, err)
+
+	// All items were created at the same time
+	_, err = x.Exec("UPDATE list_item SET created_at = ? WHERE group_uid = ?",
+		formatDBTime(time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)), testGroupUID)
+	assert.NoError(t, err)
+
+	query := &ListItemQuery{State: ListItemStateAll, Limit: 2}
+	var ids []strfmt.UUID
+	for i := 0; i < 3; i++ {
+		page, err := g.FindListItems(query)
+		assert.NoError(t, err)
+		ids = append(ids, listItemIDs(page)...)
+		query.Cursor = page.NextCursor
+	}
+	assert.Empty(t, query.Cursor)
+	assert.Equal(t, []strfmt.UUID{
+		"00112233-4455-6677-8899-000000000001",
+		"00112233-4455-6677-8899-000000000002",
+		"00112233-4455-6677-8899-000000000003",
+		"00112233-4455-6677-8899-000000000004",
+		"00112233-4455-6677-8899-000000000005",
+	}, ids)
+}
+
+func TestListItemQuery_NormalizeLimit(t *testing.T) {
+	// Without paging, all items are returned
+	q := &ListItemQuery{}
+	assert.NoError(t, q.normalize())
+	assert.Equal(t, 0, q.Limit)
+
+	q = &ListItemQuery{Cursor: "abc"}
+	assert.NoError(t, q.normalize())
+	assert.Equal(t, ListItemQueryDefaultLimit, q.Limit)
+
+	q = &ListItemQuery{Limit: 1000}
+	assert.NoError(t, q.normalize())
+	assert.Equal(t, ListItemQueryMaxLimit, q.Limit)
+}
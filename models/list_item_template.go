@@ -0,0 +1,398 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
+	"github.com/satori/go.uuid"
+)
+
+// Recurrence rules of list item templates. A template is due every
+// "interval" days, weeks or months.
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+// listItemTemplateRecurrenceEnum contains all valid recurrence rules
+var listItemTemplateRecurrenceEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["daily","weekly","monthly"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		listItemTemplateRecurrenceEnum = append(listItemTemplateRecurrenceEnum, v)
+	}
+}
+
+// ListItemTemplate list item template
+// swagger:model ListItemTemplate
+type ListItemTemplate struct {
+	// id
+	// Read Only: true
+	ID strfmt.UUID `xorm:"pk VARCHAR(36)" json:"id,omitempty"`
+
+	// group UID
+	// Read Only: true
+	GroupUID strfmt.UUID `xorm:"VARCHAR(36) INDEX" json:"groupUID,omitempty"`
+
+	// title
+	// Required: true
+	// Max Length: 150
+	Title *string `xorm:"NOT NULL" json:"title"`
+
+	// category
+	// Required: true
+	Category *string `json:"category"`
+
+	// count
+	// Required: true
+	Count *int64 `xorm:"DEFAULT 0" json:"count"`
+
+	// price estimate
+	Price int64 `xorm:"DEFAULT 0" json:"price,omitempty"`
+
+	// requested by
+	// Read Only: true
+	RequestedBy string `xorm:"NOT NULL" json:"requestedBy,omitempty"`
+
+	// requested for
+	RequestedFor []string `xorm:"NOT NULL" json:"requestedFor"`
+
+	// recurrence (one of daily, weekly, monthly)
+	// Required: true
+	Recurrence *string `xorm:"VARCHAR(16) NOT NULL" json:"recurrence"`
+
+	// interval of the recurrence, e.g. 2 with "weekly" means every two weeks
+	// Minimum: 1
+	Interval int64 `xorm:"DEFAULT 1" json:"interval,omitempty"`
+
+	// next due at
+	NextDueAt *time.Time `xorm:"NULL INDEX" json:"nextDueAt,omitempty"`
+
+	// last added at
+	// Read Only: true
+	LastAddedAt *time.Time `xorm:"NULL" json:"lastAddedAt,omitempty"`
+
+	// created at
+	// Read Only: true
+	CreatedAt strfmt.DateTime `xorm:"created" json:"createdAt,omitempty"`
+
+	// updated at
+	// Read Only: true
+	UpdatedAt strfmt.DateTime `xorm:"updated" json:"updatedAt,omitempty"`
+}
+
+// Validate validates this list item template
+func (m *ListItemTemplate) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := m.validateTitle(formats); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.Required("category", "body", m.Category); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.Required("count", "body", m.Count); err != nil {
+		res = append(res, err)
+	}
+	if err := m.validateRecurrence(formats); err != nil {
+		res = append(res, err)
+	}
+	if err := m.validateInterval(formats); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ListItemTemplate) validateTitle(formats strfmt.Registry) error {
+	if err := validate.Required("title", "body", m.Title); err != nil {
+		return err
+	}
+	if err := validate.MaxLength("title", "body", string(*m.Title), 150); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *ListItemTemplate) validateRecurrence(formats strfmt.Registry) error {
+	if err := validate.Required("recurrence", "body", m.Recurrence); err != nil {
+		return err
+	}
+	if err := validate.Enum("recurrence", "body", *m.Recurrence, listItemTemplateRecurrenceEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *ListItemTemplate) validateInterval(formats strfmt.Registry) error {
+	if swag.IsZero(m.Interval) { // not required
+		return nil
+	}
+	if err := validate.MinimumInt("interval", "body", int64(m.Interval), 1, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *ListItemTemplate) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *ListItemTemplate) UnmarshalBinary(b []byte) error {
+	var res ListItemTemplate
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// ListItemTemplateList list item template list
+// swagger:model ListItemTemplateList
+type ListItemTemplateList struct {
+	// count
+	// Required: true
+	// Read Only: true
+	Count int64 `json:"count"`
+
+	// templates
+	// Required: true
+	// Read Only: true
+	Templates []*ListItemTemplate `json:"templates"`
+}
+
+// Validate validates this list item template list
+func (m *ListItemTemplateList) Validate(formats strfmt.Registry) error {
+	if err := validate.Required("templates", "body", m.Templates); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Templates); i++ {
+		if m.Templates[i] == nil {
+			continue
+		}
+		if err := m.Templates[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("templates" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *ListItemTemplateList) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *ListItemTemplateList) UnmarshalBinary(b []byte) error {
+	var res ListItemTemplateList
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// NextDueAfter returns the point in time after "from" when the template is due again.
+func (m *ListItemTemplate) NextDueAfter(from time.Time) time.Time {
+	interval := int(m.Interval)
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch swag.StringValue(m.Recurrence) {
+	case RecurrenceWeekly:
+		return from.AddDate(0, 0, 7*interval)
+	case RecurrenceMonthly:
+		return from.AddDate(0, interval, 0)
+	default:
+		return from.AddDate(0, 0, interval)
+	}
+}
+
+// IsDue returns true if the template is due at the given point in time.
+func (m *ListItemTemplate) IsDue(now time.Time) bool {
+	return m.NextDueAt != nil && !m.NextDueAt.After(now)
+}
+
+// hasOpenListItem returns true if an item of the template has not been bought yet.
+func (m *ListItemTemplate) hasOpenListItem(sess *xorm.Session) (bool, error) {
+	return sess.
+		Where(`group_uid=?`, m.GroupUID).
+		And(`template_uid=?`, m.ID).
+		And(`bought_at IS NULL`).
+		Exist(new(ListItem))
+}
+
+// AddDueListItem adds an item to the group's shopping list if the template is due.
+// The template is skipped if there is still an unbought item of it. In both cases
+// the template is scheduled again. The new item is returned (nil if skipped).
+func (m *ListItemTemplate) AddDueListItem(now time.Time) (*ListItem, error) {
+	if !m.IsDue(now) {
+		return nil, nil
+	}
+
+	// Don't add the missed instances after a downtime of the server.
+	next := *m.NextDueAt
+	for !next.After(now) {
+		next = m.NextDueAfter(next)
+	}
+
+	var item *ListItem
+	err := withTx(func(sess *xorm.Session) error {
+		// Another run might have scheduled the template already
+		n, err := sess.ID(m.ID).
+			And(`next_due_at <= ?`, formatDBTime(now)).
+			Cols(`next_due_at`).
+			Update(&ListItemTemplate{NextDueAt: &next})
+		if err != nil || n == 0 {
+			return err
+		}
+
+		if hasOpenItem, err := m.hasOpenListItem(sess); err != nil || hasOpenItem {
+			return err
+		}
+
+		itemUID, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		item = &ListItem{
+			ID:           strfmt.UUID(itemUID.String()),
+			GroupUID:     m.GroupUID,
+			TemplateUID:  m.ID,
+			Title:        m.Title,
+			Category:     m.Category,
+			Count:        m.Count,
+			Price:        m.Price,
+			RequestedBy:  m.RequestedBy,
+			RequestedFor: m.RequestedFor,
+		}
+		if _, err := sess.InsertOne(item); err != nil {
+			return err
+		}
+		if err := recordChange(sess, item.GroupUID, SyncTypeListItem, string(item.ID), false); err != nil {
+			return err
+		}
+
+		if err := failpoint("ListItemTemplate.AddDueListItem"); err != nil {
+			return err
+		}
+
+		_, err = sess.ID(m.ID).Cols(`last_added_at`).Update(&ListItemTemplate{LastAddedAt: &now})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.NextDueAt = &next
+	if item != nil {
+		m.LastAddedAt = &now
+	}
+	return item, nil
+}
+
+// GetDueListItemTemplates returns all templates (of all groups) that are due.
+// Templates of deleted groups are skipped.
+func GetDueListItemTemplates(now time.Time) ([]*ListItemTemplate, error) {
+	templates := make([]*ListItemTemplate, 0, 10)
+	err := x.
+		Where(`next_due_at IS NOT NULL`).
+		And(`next_due_at <= ?`, now).
+		And(`group_uid NOT IN (SELECT uid FROM ` + x.Quote("group") + ` WHERE deleted_at IS NOT NULL)`).
+		Asc(`next_due_at`).
+		Find(&templates)
+	return templates, err
+}
+
+// GetListItemTemplatesByGroupUID returns all templates of the group.
+func GetListItemTemplatesByGroupUID(guid strfmt.UUID) ([]*ListItemTemplate, error) {
+	templates := make([]*ListItemTemplate, 0, 10)
+	err := x.
+		Where(`group_uid=?`, guid).
+		Asc(`title`).
+		Find(&templates)
+	return templates, err
+}
+
+// GetListItemTemplateByUIDs returns the template with the given uid that belongs to the group.
+func GetListItemTemplateByUIDs(guid, tuid strfmt.UUID) (*ListItemTemplate, error) {
+	t := new(ListItemTemplate)
+
+	if has, err := x.Where(`group_uid=?`, guid).And(`id=?`, tuid).Get(t); err != nil {
+		return nil, err
+
+	} else if !has {
+		return nil, ErrListItemTemplateNotExist{ID: tuid, GroupUID: guid}
+	}
+
+	return t, nil
+}
+
+// CreateListItemTemplate inserts a new template. If no due date is given,
+// the template is due immediately.
+func CreateListItemTemplate(t *ListItemTemplate) error {
+	if t.Interval < 1 {
+		t.Interval = 1
+	}
+	if t.NextDueAt == nil {
+		t.NextDueAt = swag.Time(time.Now().UTC())
+	}
+
+	_, err := x.InsertOne(t)
+	return err
+}
+
+// UpdateListItemTemplateCols updates the given columns of the template.
+func UpdateListItemTemplateCols(t *ListItemTemplate, cols ...string) error {
+	if t.Interval < 1 {
+		t.Interval = 1
+	}
+
+	_, err := x.Cols(cols...).
+		Where(`group_uid=?`, t.GroupUID).
+		And(`id=?`, t.ID).
+		Update(t)
+	return err
+}
+
+// DeleteListItemTemplateByUIDs deletes the template. Items that were
+// already added to the shopping list are kept.
+func DeleteListItemTemplateByUIDs(guid, tuid strfmt.UUID) error {
+	affected, err := x.
+		Where(`group_uid=?`, guid).
+		And(`id=?`, tuid).
+		Delete(new(ListItemTemplate))
+
+	if err != nil {
+		return err
+
+	} else if affected == 0 {
+		return ErrListItemTemplateNotExist{ID: tuid, GroupUID: guid}
+	}
+
+	return nil
+}
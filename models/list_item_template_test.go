@@ -0,0 +1,144 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListItemTemplate_NextDueAfter(t *testing.T) {
+	from := time.Date(2018, 1, 31, 8, 0, 0, 0, time.UTC)
+
+	daily := ListItemTemplate{Recurrence: swag.String(RecurrenceDaily), Interval: 3}
+	assert.Equal(t, time.Date(2018, 2, 3, 8, 0, 0, 0, time.UTC), daily.NextDueAfter(from))
+
+	weekly := ListItemTemplate{Recurrence: swag.String(RecurrenceWeekly)}
+	assert.Equal(t, time.Date(2018, 2, 7, 8, 0, 0, 0, time.UTC), weekly.NextDueAfter(from))
+
+	monthly := ListItemTemplate{Recurrence: swag.String(RecurrenceMonthly), Interval: 2}
+	assert.Equal(t, time.Date(2018, 3, 31, 8, 0, 0, 0, time.UTC), monthly.NextDueAfter(from))
+}
+
+func TestListItemTemplate_Validate(t *testing.T) {
+	valid := ListItemTemplate{
+		Title:      swag.String("Milk"),
+		Category:   swag.String("Groceries"),
+		Count:      swag.Int64(1),
+		Recurrence: swag.String(RecurrenceWeekly),
+	}
+	assert.NoError(t, valid.Validate(strfmt.Default))
+
+	invalidRecurrence := valid
+	invalidRecurrence.Recurrence = swag.String("yearly")
+	assert.Error(t, invalidRecurrence.Validate(strfmt.Default))
+
+	invalidInterval := valid
+	invalidInterval.Interval = -1
+	assert.Error(t, invalidInterval.Validate(strfmt.Default))
+}
+
+func TestGetDueListItemTemplates(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	templates, err := GetDueListItemTemplates(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, templates, 2)
+}
+
+func TestListItemTemplate_AddDueListItem(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t1, err := GetListItemTemplateByUIDs(testGroupUID, "00112233-4455-6677-8899-456000000001")
+	assert.NoError(t, err)
+
+	item, err := t1.AddDueListItem(now)
+	assert.NoError(t, err)
+	if assert.NotNil(t, item) {
+		assert.Equal(t, t1.ID, item.TemplateUID)
+		assert.Equal(t, "Milk", *item.Title)
+		AssertExistsAndLoadBean(t, &ListItem{ID: item.ID, TemplateUID: t1.ID})
+	}
+	assert.True(t, t1.NextDueAt.After(now))
+	assert.False(t, t1.IsDue(now))
+
+	// Not due anymore
+	item, err = t1.AddDueListItem(now)
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+
+	// "Apples" have not been bought yet
+	t2, err := GetListItemTemplateByUIDs(testGroupUID, "00112233-4455-6677-8899-456000000002")
+	assert.NoError(t, err)
+
+	item, err = t2.AddDueListItem(now)
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.True(t, t2.NextDueAt.After(now))
+	AssertCount(t, &ListItem{TemplateUID: t2.ID}, 1)
+}
+
+func TestListItemTemplate_AddDueListItemTwice(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Two runs read the due template
+	t1, err := GetListItemTemplateByUIDs(testGroupUID, "00112233-4455-6677-8899-456000000001")
+	assert.NoError(t, err)
+	stale := *t1
+
+	item, err := t1.AddDueListItem(now)
+	assert.NoError(t, err)
+	assert.NotNil(t, item)
+
+	item, err = stale.AddDueListItem(now)
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	AssertCount(t, &ListItem{TemplateUID: t1.ID}, 1)
+}
+
+func TestGetDueListItemTemplatesOfDeletedGroup(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	_, err := x.ID(testGroupUID).Delete(new(Group))
+	assert.NoError(t, err)
+
+	templates, err := GetDueListItemTemplates(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Empty(t, templates)
+}
+
+func TestListItemTemplate_CRUD(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	templates, err := GetListItemTemplatesByGroupUID(testGroupUID)
+	assert.NoError(t, err)
+	assert.Len(t, templates, 3)
+
+	tpl := &ListItemTemplate{
+		ID:           "00112233-4455-6677-8899-456000000004",
+		GroupUID:     testGroupUID,
+		Title:        swag.String("Bread"),
+		Category:     swag.String("Groceries"),
+		Count:        swag.Int64(1),
+		RequestedBy:  "1234567890fakefirebaseid0001",
+		RequestedFor: []string{"1234567890fakefirebaseid0001"},
+		Recurrence:   swag.String(RecurrenceDaily),
+	}
+	assert.NoError(t, CreateListItemTemplate(tpl))
+	assert.Equal(t, int64(1), tpl.Interval)
+	assert.NotNil(t, tpl.NextDueAt)
+
+	tpl.Title = swag.String("Whole grain bread")
+	assert.NoError(t, UpdateListItemTemplateCols(tpl, `title`))
+	AssertExistsAndLoadBean(t, &ListItemTemplate{ID: tpl.ID, Title: swag.String("Whole grain bread")})
+
+	assert.NoError(t, DeleteListItemTemplateByUIDs(testGroupUID, tpl.ID))
+	AssertNotExistsBean(t, &ListItemTemplate{ID: tpl.ID})
+
+	err = DeleteListItemTemplateByUIDs(testGroupUID, tpl.ID)
+	assert.True(t, IsErrListItemTemplateNotExist(err))
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,3 +22,138 @@ func TestGetListItemByUIDs(t *testing.T) {
 	assert.Error(t, err2)
 	assert.Nil(t, item2)
 }
+
+func TestDeleteListItem(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	groupUID := strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+
+	// Billed item
+	billed, err := GetListItemByUIDs(groupUID, "00112233-4455-6677-8899-000000000001")
+	assert.NoError(t, err)
+	assert.True(t, IsErrListItemHasBill(DeleteListItem(billed)))
+
+	// Bought item
+	bought, err := GetListItemByUIDs(groupUID, "00112233-4455-6677-8899-000000000004")
+	assert.NoError(t, err)
+	assert.True(t, IsErrListItemIsBought(DeleteListItem(bought)))
+	AssertExistsAndLoadBean(t, &ListItem{ID: bought.ID})
+
+	item, err := GetListItemByUIDs(groupUID, "00112233-4455-6677-8899-000000000002")
+	assert.NoError(t, err)
+	assert.NoError(t, DeleteListItem(item))
+	AssertNotExistsBean(t, &ListItem{ID: item.ID})
+
+	// Deleted in the meantime
+	assert.True(t, IsErrListItemNotExist(DeleteListItem(item)))
+
+	// Bought in the meantime
+	other, err := GetListItemByUIDs(groupUID, "00112233-4455-6677-8899-000000000005")
+	assert.NoError(t, err)
+	stale := *other
+	buyer, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+	assert.NoError(t, buyer.BuyListItemsByUIDs([]strfmt.UUID{other.ID}))
+	assert.True(t, IsErrListItemIsBought(DeleteListItem(&stale)))
+	AssertExistsAndLoadBean(t, &ListItem{ID: other.ID})
+}
+
+func TestUpdateListItemColsIfVersion(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	assert.EqualValues(t, 1, item.Version)
+
+	item.Title = swag.String("Pears")
+	assert.NoError(t, UpdateListItemColsIfVersion(item, 1, `title`))
+	item = AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	assert.Equal(t, "Pears", *item.Title)
+	assert.EqualValues(t, 2, item.Version)
+
+	// Stale version
+	item.Title = swag.String("Plums")
+	err := UpdateListItemColsIfVersion(item, 1, `title`)
+	assert.True(t, IsErrVersionMismatch(err))
+	item = AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	assert.Equal(t, "Pears", *item.Title)
+
+	// Any version
+	item.Title = swag.String("Plums")
+	assert.NoError(t, UpdateListItemCols(item, `title`))
+	item = AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	assert.EqualValues(t, 3, item.Version)
+
+	// Buying changes the version as well
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0001")}).(*User)
+	assert.NoError(t, u.BuyListItemsByUIDs([]strfmt.UUID{item.ID}))
+	item = AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	assert.EqualValues(t, 4, item.Version)
+
+	// Bought and billed items can't be changed
+	item.Title = swag.String("Cherries")
+	assert.True(t, IsErrListItemIsBought(UpdateListItemColsIfVersion(item, 4, `title`)))
+	billed := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000003"}).(*ListItem)
+	assert.True(t, IsErrListItemHasBill(UpdateListItemCols(billed, `title`)))
+
+	item.ID = "00112233-4455-6677-8899-000000000099"
+	err = UpdateListItemColsIfVersion(item, 4, `title`)
+	assert.True(t, IsErrListItemNotExist(err))
+}
+
+func TestListItem_ValidateUnits(t *testing.T) {
+	newItem := func(unit string, quantity *float64, priceMode string) *ListItem {
+		return &ListItem{
+			Title:     swag.String("Flour"),
+			Category:  swag.String("Groceries"),
+			Count:     swag.Int64(1),
+			Unit:      unit,
+			Quantity:  quantity,
+			PriceMode: priceMode,
+		}
+	}
+
+	assert.NoError(t, newItem("", nil, "").Validate(strfmt.Default))
+	assert.NoError(t, newItem(UnitGram, swag.Float64(500), PriceModePerUnit).Validate(strfmt.Default))
+	assert.NoError(t, newItem(UnitLiter, swag.Float64(1.5), PriceModeTotal).Validate(strfmt.Default))
+	assert.NoError(t, newItem(UnitPack, swag.Float64(2), "").Validate(strfmt.Default))
+
+	assert.Error(t, newItem("oz", swag.Float64(1), "").Validate(strfmt.Default))
+	assert.Error(t, newItem(UnitGram, nil, "").Validate(strfmt.Default))
+	assert.Error(t, newItem(UnitGram, swag.Float64(0), "").Validate(strfmt.Default))
+	assert.Error(t, newItem("", swag.Float64(1), "").Validate(strfmt.Default))
+	assert.Error(t, newItem(UnitPieces, swag.Float64(1.5), "").Validate(strfmt.Default))
+	assert.Error(t, newItem("", nil, "each").Validate(strfmt.Default))
+}
+
+func TestListItem_TotalPrice(t *testing.T) {
+	for _, c := range []struct {
+		item     ListItem
+		expected int64
+	}{
+		{ListItem{Count: swag.Int64(3), Price: 150}, 150},
+		{ListItem{Count: swag.Int64(3), Price: 150, PriceMode: PriceModePerUnit}, 450},
+		{ListItem{Count: swag.Int64(1), Price: 120, PriceMode: PriceModePerUnit, Unit: UnitGram, Quantity: swag.Float64(500)}, 60},
+		{ListItem{Count: swag.Int64(1), Price: 99, PriceMode: PriceModePerUnit, Unit: UnitLiter, Quantity: swag.Float64(1.5)}, 149},
+		{ListItem{Count: swag.Int64(1), Price: 250, PriceMode: PriceModeTotal, Unit: UnitKilogram, Quantity: swag.Float64(2)}, 250},
+	} {
+		assert.Equal(t, c.expected, c.item.TotalPrice())
+	}
+}
+
+func TestBillSumWithUnitPrices(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// 250 g chocolate for 12.00 per kg
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000003"}).(*ListItem)
+	item.Unit = UnitGram
+	item.Quantity = swag.Float64(250)
+	item.Price = 1200
+	item.PriceMode = PriceModePerUnit
+	_, err := x.Where(`id=?`, item.ID).Cols(`unit`, `quantity`, `price`, `price_mode`).Update(item)
+	assert.NoError(t, err)
+
+	bills, err := GetBillsByGroupUIDWithBoughtItems(testGroupUID)
+	assert.NoError(t, err)
+	if assert.Len(t, bills, 1) {
+		assert.EqualValues(t, 100+300, bills[0].Sum)
+	}
+}
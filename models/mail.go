@@ -0,0 +1,143 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+)
+
+// Mail types users can opt out of. Group invites are always sent
+// since the receiver is not a user yet.
+const (
+	MailTypeBillSent        = "bill_sent"
+	MailTypePaymentReminder = "payment_reminder"
+	MailTypeGroupDigest     = "group_digest"
+)
+
+var userMailOptOutEnum []interface{}
+
+func init() {
+	for _, t := range []string{MailTypeBillSent, MailTypePaymentReminder, MailTypeGroupDigest} {
+		userMailOptOutEnum = append(userMailOptOutEnum, t)
+	}
+}
+
+func (u *User) validateMailOptOut(formats strfmt.Registry) error {
+	for i, t := range u.MailOptOut {
+		if err := validate.Enum(fmt.Sprintf("mailOptOut.%d", i), "body", t, userMailOptOutEnum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WantsMail returns true if the user has an email address
+// and didn't opt out of the mail type.
+func (u *User) WantsMail(mailType string) bool {
+	return u.Email != "" && !base.StringInSlice(mailType, u.MailOptOut)
+}
+
+// dueDateLayouts are the accepted formats of a bill's due date. Dates
+// without time are due at midnight UTC.
+var dueDateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05Z07:00", "2006-01-02"}
+
+// DueTime returns the parsed due date of the bill.
+func (m *Bill) DueTime() (time.Time, error) {
+	var err error
+	for _, layout := range dueDateLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, m.DueDate); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// GetBillsForPaymentReminder returns all unpaid bills that are due after "now"
+// but not later than "until" and whose recipients haven't been reminded yet.
+func GetBillsForPaymentReminder(now, until time.Time) ([]*Bill, error) {
+	bills := make([]*Bill, 0, 5)
+	if err := x.
+		In(`state`, BillStateSent, BillStatePartial).
+		And(`reminder_sent_at IS NULL`).
+		And(`due_date <> ''`).
+		Find(&bills); err != nil {
+		return nil, err
+	}
+
+	due := bills[:0]
+	for _, b := range bills {
+		t, err := b.DueTime()
+		if err != nil || !t.After(now) || t.After(until) {
+			continue
+		}
+		if err = b.loadItemsAndSum(); err != nil {
+			return nil, err
+		}
+		due = append(due, b)
+	}
+	return due, nil
+}
+
+// MarkReminderSent records that the recipients of the bill were reminded.
+func (m *Bill) MarkReminderSent(now time.Time) error {
+	m.ReminderSentAt = &now
+	_, err := x.ID(m.UID).Cols(`reminder_sent_at`).Update(m)
+	return err
+}
+
+// GetGroupsForDigest returns all groups whose last digest was sent
+// before "before" or that never got a digest.
+func GetGroupsForDigest(before time.Time) ([]*Group, error) {
+	groups := make([]*Group, 0, 5)
+	err := x.
+		Where(`digest_sent_at IS NULL`).
+		Or(`digest_sent_at <= ?`, before).
+		Find(&groups)
+	return groups, err
+}
+
+// MarkDigestSent records that the digest of the group was sent.
+func (g *Group) MarkDigestSent(now time.Time) error {
+	g.DigestSentAt = &now
+	_, err := x.ID(g.UID).Cols(`digest_sent_at`).Update(g)
+	return err
+}
+
+// GetItemsBoughtSince returns the items of the group that were bought after "since".
+func (g *Group) GetItemsBoughtSince(since time.Time) ([]*ListItem, error) {
+	items := make([]*ListItem, 0, 10)
+	err := x.
+		Where(`group_uid=?`, g.UID).
+		And(`bought_at > ?`, since).
+		Asc(`bought_at`).
+		Find(&items)
+	return items, err
+}
+
+// GetOpenBillsForUser returns the bills of the group the user still has to pay.
+func (g *Group) GetOpenBillsForUser(uid string) ([]*Bill, error) {
+	bills := make([]*Bill, 0, 5)
+	if err := x.
+		Where(`group_uid=?`, g.UID).
+		In(`state`, BillStateSent, BillStatePartial).
+		Find(&bills); err != nil {
+		return nil, err
+	}
+
+	open := bills[:0]
+	for _, b := range bills {
+		if !base.StringInSlice(uid, b.SentTo) || b.IsPaidBy(uid) {
+			continue
+		}
+		if err := b.loadItemsAndSum(); err != nil {
+			return nil, err
+		}
+		open = append(open, b)
+	}
+	return open, nil
+}
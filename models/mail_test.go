@@ -0,0 +1,123 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_WantsMail(t *testing.T) {
+	u := &User{Email: "john@example.com", MailOptOut: []string{MailTypeGroupDigest}}
+	assert.True(t, u.WantsMail(MailTypeBillSent))
+	assert.False(t, u.WantsMail(MailTypeGroupDigest))
+
+	u.Email = ""
+	assert.False(t, u.WantsMail(MailTypeBillSent))
+}
+
+func TestUser_ValidateMailOptOut(t *testing.T) {
+	u := &User{UID: swag.String("1234567890fakefirebaseid0001"), DisplayName: swag.String("John Doe")}
+
+	u.MailOptOut = []string{MailTypeBillSent, MailTypePaymentReminder, MailTypeGroupDigest}
+	assert.NoError(t, u.Validate(strfmt.Default))
+
+	u.MailOptOut = []string{"spam"}
+	assert.Error(t, u.Validate(strfmt.Default))
+}
+
+func TestBill_DueTime(t *testing.T) {
+	due, err := (&Bill{DueDate: "2017-11-17T19:43:40.000+01:00"}).DueTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2017, 11, 17, 18, 43, 40, 0, time.UTC), due.UTC())
+
+	due, err = (&Bill{DueDate: "2019-06-07"}).DueTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 6, 7, 0, 0, 0, 0, time.UTC), due)
+
+	_, err = (&Bill{DueDate: "tomorrow"}).DueTime()
+	assert.Error(t, err)
+}
+
+func TestGetBillsForPaymentReminder(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	now := time.Now().UTC()
+
+	b := &Bill{
+		UID:       "00112233-4455-6677-8899-123000000002",
+		GroupUID:  "00112233-4455-6677-8899-aabbccddeeff",
+		CreatedBy: swag.String("1234567890fakefirebaseid0001"),
+		SentTo:    []string{"1234567890fakefirebaseid0001", "1234567890fakefirebaseid0002"},
+		PayedBy:   []string{"1234567890fakefirebaseid0001"},
+		DueDate:   now.Add(12 * time.Hour).Format(time.RFC3339),
+		State:     swag.String(BillStateSent),
+	}
+	_, err := x.Insert(b)
+	assert.NoError(t, err)
+
+	// Not due within the next hour
+	bills, err := GetBillsForPaymentReminder(now, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, bills)
+
+	// The bill of the fixtures is overdue
+	bills, err = GetBillsForPaymentReminder(now, now.Add(24*time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, bills, 1) {
+		assert.Equal(t, b.UID, bills[0].UID)
+	}
+
+	// Recipients are only reminded once
+	assert.NoError(t, b.MarkReminderSent(now))
+	bills, err = GetBillsForPaymentReminder(now, now.Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, bills)
+}
+
+func TestGetGroupsForDigest(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	now := time.Now().UTC()
+
+	groups, err := GetGroupsForDigest(now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, groups, 2)
+
+	assert.NoError(t, groups[0].MarkDigestSent(now))
+	groups, err = GetGroupsForDigest(now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+
+	groups, err = GetGroupsForDigest(now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, groups, 2)
+}
+
+func TestGroup_GetItemsBoughtSince(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g := AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*Group)
+
+	items, err := g.GetItemsBoughtSince(time.Date(2017, 11, 8, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "Eggs", *items[0].Title)
+		assert.Equal(t, "Chocolate", *items[1].Title)
+	}
+}
+
+func TestGroup_GetOpenBillsForUser(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	g := AssertExistsAndLoadBean(t, &Group{UID: "00112233-4455-6677-8899-aabbccddeeff"}).(*Group)
+
+	bills, err := g.GetOpenBillsForUser("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+	if assert.Len(t, bills, 1) {
+		assert.EqualValues(t, 270, bills[0].Sum)
+	}
+
+	// The creator has paid
+	bills, err = g.GetOpenBillsForUser("1234567890fakefirebaseid0001")
+	assert.NoError(t, err)
+	assert.Empty(t, bills)
+}
@@ -0,0 +1,229 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-xorm/xorm"
+	"github.com/op/go-logging"
+)
+
+var migrationLog = logging.MustGetLogger("Migration")
+
+const (
+	// lockTimeout is the time to wait for another instance to finish its migrations.
+	lockTimeout = 2 * time.Minute
+	// lockStaleAfter is the time after which a lock is considered stale,
+	// e.g. because the instance holding it crashed.
+	lockStaleAfter = 15 * time.Minute
+)
+
+// Migration describes one database migration.
+type Migration interface {
+	Description() string
+	Migrate(*xorm.Engine) error
+}
+
+type migration struct {
+	description string
+	migrate     func(*xorm.Engine) error
+}
+
+// NewMigration creates a new migration.
+func NewMigration(desc string, fn func(*xorm.Engine) error) Migration {
+	return &migration{desc, fn}
+}
+
+// Description returns the migration's description.
+func (m *migration) Description() string {
+	return m.description
+}
+
+// Migrate executes the migration.
+func (m *migration) Migrate(x *xorm.Engine) error {
+	return m.migrate(x)
+}
+
+// Version describes the version table. There's only one row with ID == 1.
+type Version struct {
+	ID      int64 `xorm:"pk autoincr"`
+	Version int64
+}
+
+// MigrationLock makes sure that only one instance runs the migrations.
+type MigrationLock struct {
+	ID       int64 `xorm:"pk"`
+	Owner    string
+	LockedAt time.Time
+}
+
+// This is a sequence of migrations. Add new migrations to the bottom of the list.
+// If you want to "retire" a migration, remove it from the top of the list and
+// update minDBVersion accordingly.
+var migrations = []Migration{
+	// v0 -> v1
+	NewMigration("create initial tables", createInitialTables),
+	// v1 -> v2
+	NewMigration("add member balances", addMemberBalances),
+	// v2 -> v3
+	NewMigration("widen bill state and convert todo bills", convertBillStates),
+	// v3 -> v4
+	NewMigration("add list item templates", addListItemTemplates),
+	// v4 -> v5
+	NewMigration("add tasks and task completions", addTasks),
+	// v5 -> v6
+	NewMigration("add deleted_at to groups", addGroupDeletedAt),
+	// v6 -> v7
+	NewMigration("add notification outbox", addNotificationOutbox),
+	// v7 -> v8
+	NewMigration("add mail preferences, bill reminders and group digests", addMailNotifications),
+	// v8 -> v9
+	NewMigration("add group invites", addGroupInvites),
+	// v9 -> v10
+	NewMigration("add labels and usage limits to group codes", addGroupCodeLimits),
+	// v10 -> v11
+	NewMigration("add sync change log", addSyncChanges),
+	// v11 -> v12
+	NewMigration("add versions to list items, groups, users and bills", addVersions),
+	// v12 -> v13
+	NewMigration("add idempotency keys", addIdempotencyKeys),
+	// v13 -> v14
+	NewMigration("add purchases and remainders of list items", addPurchases),
+	// v14 -> v15
+	NewMigration("add units and quantities to list items", addListItemUnits),
+	// v15 -> v16
+	NewMigration("add per group sequences to the sync change log", addSyncSequences),
+	// v16 -> v17
+	NewMigration("add leases to idempotency keys", addIdempotencyKeyLeases),
+	// v17 -> v18
+	NewMigration("add former members to groups", addGroupFormerMembers),
+	// v18 -> v19
+	NewMigration("add versions to tasks", addTaskVersions),
+}
+
+// ExpectedVersion returns the schema version of this build.
+func ExpectedVersion() int64 {
+	return int64(len(migrations))
+}
+
+// CurrentVersion returns the schema version of the database.
+// A database without version table has version 0.
+func CurrentVersion(x *xorm.Engine) (int64, error) {
+	if exist, err := x.IsTableExist(new(Version)); err != nil {
+		return 0, err
+	} else if !exist {
+		return 0, nil
+	}
+
+	currentVersion := &Version{ID: 1}
+	if has, err := x.Get(currentVersion); err != nil {
+		return 0, fmt.Errorf("get: %v", err)
+	} else if !has {
+		return 0, nil
+	}
+	return currentVersion.Version, nil
+}
+
+// Pending returns the descriptions of all migrations that haven't been run yet.
+func Pending(x *xorm.Engine) ([]string, error) {
+	v, err := CurrentVersion(x)
+	if err != nil {
+		return nil, err
+	}
+
+	if v > ExpectedVersion() {
+		return nil, fmt.Errorf("database version %d is newer than this build (%d)", v, ExpectedVersion())
+	}
+
+	pending := make([]string, 0, len(migrations))
+	for i, m := range migrations[v:] {
+		pending = append(pending, fmt.Sprintf("v%d: %s", v+int64(i)+1, m.Description()))
+	}
+	return pending, nil
+}
+
+// Migrate runs all pending migrations under a lock. If dryRun is set,
+// the pending migrations are only returned but not executed.
+func Migrate(x *xorm.Engine, dryRun bool) ([]string, error) {
+	pending, err := Pending(x)
+	if err != nil || dryRun || len(pending) == 0 {
+		return pending, err
+	}
+
+	if err = acquireLock(x); err != nil {
+		return nil, err
+	}
+	defer releaseLock(x)
+
+	if err = x.Sync2(new(Version)); err != nil {
+		return nil, fmt.Errorf("sync: %v", err)
+	}
+
+	// Another instance might have migrated while we were waiting for the lock.
+	v, err := CurrentVersion(x)
+	if err != nil {
+		return nil, err
+	}
+	if has, err := x.Exist(&Version{ID: 1}); err != nil {
+		return nil, err
+	} else if !has {
+		if _, err = x.InsertOne(&Version{ID: 1, Version: v}); err != nil {
+			return nil, fmt.Errorf("insert: %v", err)
+		}
+	}
+
+	done := make([]string, 0, len(pending))
+	for i, m := range migrations[v:] {
+		version := v + int64(i) + 1
+		migrationLog.Infof("Migration[%d]: %s", version, m.Description())
+
+		if err = m.Migrate(x); err != nil {
+			return done, fmt.Errorf("do migrate v%d: %v", version, err)
+		}
+		if _, err = x.ID(1).Update(&Version{Version: version}); err != nil {
+			return done, err
+		}
+		done = append(done, fmt.Sprintf("v%d: %s", version, m.Description()))
+	}
+
+	return done, nil
+}
+
+func acquireLock(x *xorm.Engine) error {
+	if err := x.Sync2(new(MigrationLock)); err != nil {
+		return fmt.Errorf("sync lock: %v", err)
+	}
+
+	owner, _ := os.Hostname()
+	owner = fmt.Sprintf("%s:%d", owner, os.Getpid())
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		_, err := x.InsertOne(&MigrationLock{ID: 1, Owner: owner, LockedAt: time.Now().UTC()})
+		if err == nil {
+			return nil
+		}
+
+		// Remove stale locks of crashed instances
+		lock := &MigrationLock{ID: 1}
+		if has, errGet := x.Get(lock); errGet == nil && has &&
+			time.Since(lock.LockedAt) > lockStaleAfter {
+			migrationLog.Warningf("Removing stale migration lock of %q", lock.Owner)
+			releaseLock(x)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("can't acquire migration lock: %v", err)
+		}
+		migrationLog.Info("Waiting for another instance to finish its migrations")
+		time.Sleep(time.Second)
+	}
+}
+
+func releaseLock(x *xorm.Engine) {
+	if _, err := x.ID(1).Delete(new(MigrationLock)); err != nil {
+		migrationLog.Errorf("Can't release migration lock: %v", err)
+	}
+}
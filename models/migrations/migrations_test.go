@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+	_ "github.com/mattn/go-sqlite3" // for the test engine
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEngine(t *testing.T) *xorm.Engine {
+	x, err := xorm.NewEngine("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	x.SetMapper(core.GonicMapper{})
+	// ":memory:" databases only live as long as their connection
+	x.SetMaxOpenConns(1)
+	return x
+}
+
+func TestMigrate(t *testing.T) {
+	x := newTestEngine(t)
+	defer x.Close()
+
+	// Dry run
+	pending, err := Migrate(x, true)
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(migrations))
+	v, err := CurrentVersion(x)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+
+	done, err := Migrate(x, false)
+	assert.NoError(t, err)
+	assert.Equal(t, pending, done)
+
+	v, err = CurrentVersion(x)
+	assert.NoError(t, err)
+	assert.Equal(t, ExpectedVersion(), v)
+
+	for _, table := range []string{"bill", "user", "group", "group_code", "group_invite", "list_item",
+		"member_balance", "list_item_template", "task", "task_completion", "notification", "sync_change", "sync_sequence", "idempotency_key", "purchase"} {
+		exist, err := x.IsTableExist(table)
+		assert.NoError(t, err)
+		assert.True(t, exist, table)
+	}
+
+	// Nothing left to do and the lock was released
+	done, err = Migrate(x, false)
+	assert.NoError(t, err)
+	assert.Empty(t, done)
+	has, err := x.Exist(&MigrationLock{ID: 1})
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestMigrateNewerDatabase(t *testing.T) {
+	x := newTestEngine(t)
+	defer x.Close()
+
+	assert.NoError(t, x.Sync2(new(Version)))
+	_, err := x.InsertOne(&Version{ID: 1, Version: ExpectedVersion() + 1})
+	assert.NoError(t, err)
+
+	_, err = Migrate(x, false)
+	assert.Error(t, err)
+}
+
+func TestConvertBillStates(t *testing.T) {
+	x := newTestEngine(t)
+	defer x.Close()
+
+	// Migrate to v2 and insert bills of the old schema
+	for _, m := range migrations[:2] {
+		assert.NoError(t, m.Migrate(x))
+	}
+	bills := map[string]string{
+		"b1": `INSERT INTO bill (uid, created_by, sent_to, payed_by, state) VALUES ('b1', 'a', '[]', '[]', 'todo')`,
+		"b2": `INSERT INTO bill (uid, created_by, sent_to, payed_by, state) VALUES ('b2', 'a', '["a","b","c"]', '["b"]', 'todo')`,
+		"b3": `INSERT INTO bill (uid, created_by, sent_to, payed_by, state) VALUES ('b3', 'a', '["a","b"]', '["b"]', 'todo')`,
+		"b4": `INSERT INTO bill (uid, created_by, sent_to, payed_by, state) VALUES ('b4', 'a', '["b"]', '[]', 'todo')`,
+	}
+	for _, stmt := range bills {
+		_, err := x.Exec(stmt)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, convertBillStates(x))
+
+	expected := map[string]string{"b1": "draft", "b2": "partial", "b3": "paid", "b4": "sent"}
+	for uid, state := range expected {
+		results, err := x.QueryString("SELECT state FROM bill WHERE uid=?", uid)
+		assert.NoError(t, err)
+		assert.Equal(t, state, results[0]["state"], uid)
+	}
+}
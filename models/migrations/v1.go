@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func createInitialTables(x *xorm.Engine) error {
+	type Bill struct {
+		UID       string   `xorm:"pk"`
+		CreatedBy *string  `xorm:"VARCHAR(28)"`
+		SentTo    []string `xorm:"VARCHAR(28)"`
+		PayedBy   []string `xorm:"VARCHAR(28)"`
+		DueDate   string
+		GroupUID  string
+		State     *string   `xorm:"VARCHAR(5)"`
+		CreatedAt time.Time `xorm:"created"`
+		UpdatedAt time.Time `xorm:"updated"`
+	}
+
+	type User struct {
+		DisplayName        *string
+		Email              string
+		FirebaseInstanceID string    `xorm:"VARCHAR(152)"`
+		GroupUID           string    `xorm:"VARCHAR(36) INDEX"`
+		Locale             string    `xorm:"VARCHAR(5)"`
+		UID                *string   `xorm:"varchar(28) pk"`
+		CreatedAt          time.Time `xorm:"created"`
+		UpdatedAt          time.Time `xorm:"updated"`
+	}
+
+	type Group struct {
+		Admins      []string
+		Currency    string `xorm:"varchar(5) default '€'"`
+		DisplayName *string
+		UID         string    `xorm:"varchar(36) pk"`
+		CreatedAt   time.Time `xorm:"created"`
+		UpdatedAt   time.Time `xorm:"updated"`
+	}
+
+	type GroupCode struct {
+		Code       *string `xorm:"pk"`
+		GroupUID   *string
+		ValidUntil time.Time
+	}
+
+	type ListItem struct {
+		BillUID      string
+		BoughtAt     *time.Time `xorm:"NULL"`
+		Category     *string
+		Count        *int64    `xorm:"DEFAULT 0"`
+		GroupUID     string    `xorm:"index(uid) unique(uid)"`
+		ID           string    `xorm:"index(uid) unique(uid)"`
+		Price        int64     `xorm:"DEFAULT 0"`
+		RequestedBy  string    `xorm:"NOT NULL"`
+		BoughtBy     string    `xorm:"NULL"`
+		RequestedFor []string  `xorm:"NOT NULL"`
+		Title        *string   `xorm:"NOT NULL"`
+		CreatedAt    time.Time `xorm:"created"`
+		UpdatedAt    time.Time `xorm:"updated"`
+	}
+
+	return x.Sync2(new(Bill), new(User), new(Group), new(GroupCode), new(ListItem))
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addGroupCodeLimits(x *xorm.Engine) error {
+	type GroupCode struct {
+		Label     string    `xorm:"VARCHAR(50)"`
+		MaxUses   int64     `xorm:"DEFAULT 0"`
+		Uses      int64     `xorm:"DEFAULT 0"`
+		CreatedBy string    `xorm:"VARCHAR(28)"`
+		CreatedAt time.Time `xorm:"created"`
+	}
+
+	return x.Sync2(new(GroupCode))
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addSyncChanges(x *xorm.Engine) error {
+	type SyncChange struct {
+		ID         int64  `xorm:"pk autoincr"`
+		GroupUID   string `xorm:"VARCHAR(36) INDEX NOT NULL"`
+		EntityType string `xorm:"VARCHAR(16) NOT NULL"`
+		EntityID   string `xorm:"VARCHAR(36) NOT NULL"`
+		Deleted    bool
+		CreatedAt  time.Time `xorm:"created"`
+	}
+
+	return x.Sync2(new(SyncChange))
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"github.com/go-xorm/xorm"
+)
+
+func addVersions(x *xorm.Engine) error {
+	type ListItem struct {
+		Version int64 `xorm:"NOT NULL DEFAULT 1"`
+	}
+	type Group struct {
+		Version int64 `xorm:"NOT NULL DEFAULT 1"`
+	}
+	type User struct {
+		Version int64 `xorm:"NOT NULL DEFAULT 1"`
+	}
+	type Bill struct {
+		Version int64 `xorm:"NOT NULL DEFAULT 1"`
+	}
+
+	return x.Sync2(new(ListItem), new(Group), new(User), new(Bill))
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addIdempotencyKeys(x *xorm.Engine) error {
+	type IdempotencyKey struct {
+		ID          int64     `xorm:"pk autoincr"`
+		UserUID     string    `xorm:"VARCHAR(28) UNIQUE(user_key) NOT NULL"`
+		RequestKey  string    `xorm:"VARCHAR(64) UNIQUE(user_key) NOT NULL"`
+		RequestHash string    `xorm:"VARCHAR(64) NOT NULL"`
+		StatusCode  int       `xorm:"DEFAULT 0"`
+		Response    string    `xorm:"TEXT"`
+		CreatedAt   time.Time `xorm:"created INDEX"`
+	}
+
+	return x.Sync2(new(IdempotencyKey))
+}
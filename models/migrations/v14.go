@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+	"github.com/satori/go.uuid"
+)
+
+func addPurchases(x *xorm.Engine) error {
+	type ListItem struct {
+		RemainderOf string `xorm:"VARCHAR(36) INDEX"`
+	}
+	type Purchase struct {
+		ID          string    `xorm:"VARCHAR(36) pk"`
+		GroupUID    string    `xorm:"VARCHAR(36) INDEX NOT NULL"`
+		ListItemUID string    `xorm:"VARCHAR(36) INDEX NOT NULL"`
+		OriginUID   string    `xorm:"VARCHAR(36) INDEX NOT NULL"`
+		BoughtBy    string    `xorm:"VARCHAR(28) NOT NULL"`
+		BoughtAt    time.Time `xorm:"NOT NULL"`
+		Count       int64     `xorm:"NOT NULL"`
+		Price       int64     `xorm:"DEFAULT 0"`
+		Partial     bool
+		CreatedAt   time.Time `xorm:"created"`
+	}
+
+	if err := x.Sync2(new(ListItem), new(Purchase)); err != nil {
+		return err
+	}
+
+	// Every bought item has been bought at once.
+	type boughtItem struct {
+		ID       string
+		GroupUID string
+		BoughtBy string
+		BoughtAt time.Time
+		Count    int64
+		Price    int64
+	}
+
+	items := make([]*boughtItem, 0, 10)
+	if err := x.Table("list_item").
+		Cols("id", "group_uid", "bought_by", "bought_at", "count", "price").
+		Where("bought_at IS NOT NULL").
+		Find(&items); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		purchaseUID, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		if _, err := x.InsertOne(&Purchase{
+			ID:          purchaseUID.String(),
+			GroupUID:    item.GroupUID,
+			ListItemUID: item.ID,
+			OriginUID:   item.ID,
+			BoughtBy:    item.BoughtBy,
+			BoughtAt:    item.BoughtAt,
+			Count:       item.Count,
+			Price:       item.Price,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/go-xorm/xorm"
+)
+
+func addListItemUnits(x *xorm.Engine) error {
+	type ListItem struct {
+		PriceMode string   `xorm:"VARCHAR(8)"`
+		Unit      string   `xorm:"VARCHAR(8)"`
+		Quantity  *float64 `xorm:"DOUBLE NULL"`
+	}
+	type Purchase struct {
+		Unit     string   `xorm:"VARCHAR(8)"`
+		Quantity *float64 `xorm:"DOUBLE NULL"`
+	}
+
+	return x.Sync2(new(ListItem), new(Purchase))
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/go-xorm/xorm"
+)
+
+func addSyncSequences(x *xorm.Engine) error {
+	type SyncChange struct {
+		Seq int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
+	}
+	type SyncSequence struct {
+		GroupUID string `xorm:"VARCHAR(36) pk"`
+		Seq      int64  `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	if err := x.Sync2(new(SyncChange), new(SyncSequence)); err != nil {
+		return err
+	}
+
+	// Existing cursors contain the ID of the change and stay valid.
+	if _, err := x.Exec("UPDATE sync_change SET seq = id"); err != nil {
+		return err
+	}
+	_, err := x.Exec("INSERT INTO sync_sequence (group_uid, seq) " +
+		"SELECT group_uid, MAX(seq) FROM sync_change GROUP BY group_uid")
+	return err
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import (
+	"github.com/go-xorm/xorm"
+)
+
+func addIdempotencyKeyLeases(x *xorm.Engine) error {
+	type IdempotencyKey struct {
+		LockedUntil int64 `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync2(new(IdempotencyKey))
+}
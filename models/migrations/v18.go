@@ -0,0 +1,13 @@
+package migrations
+
+import (
+	"github.com/go-xorm/xorm"
+)
+
+func addGroupFormerMembers(x *xorm.Engine) error {
+	type Group struct {
+		FormerMembers []string `xorm:"TEXT"`
+	}
+
+	return x.Sync2(new(Group))
+}
@@ -0,0 +1,13 @@
+package migrations
+
+import (
+	"github.com/go-xorm/xorm"
+)
+
+func addTaskVersions(x *xorm.Engine) error {
+	type Task struct {
+		Version int64 `xorm:"NOT NULL DEFAULT 1"`
+	}
+
+	return x.Sync2(new(Task))
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addMemberBalances(x *xorm.Engine) error {
+	type MemberBalance struct {
+		ID        int64     `xorm:"pk autoincr"`
+		GroupUID  string    `xorm:"VARCHAR(36) INDEX unique(member)"`
+		UserUID   *string   `xorm:"VARCHAR(28) unique(member)"`
+		Balance   *int64    `xorm:"NOT NULL DEFAULT 0"`
+		UpdatedAt time.Time `xorm:"updated"`
+	}
+
+	// Balances are recomputed when bills change,
+	// so existing groups start without balances.
+	return x.Sync2(new(MemberBalance))
+}
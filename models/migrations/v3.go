@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+)
+
+func convertBillStates(x *xorm.Engine) error {
+	// "partial" and "cancelled" don't fit into VARCHAR(5).
+	switch x.Dialect().DBType() {
+	case core.MYSQL:
+		if _, err := x.Exec("ALTER TABLE `bill` MODIFY `state` VARCHAR(16)"); err != nil {
+			return fmt.Errorf("modify column: %v", err)
+		}
+	case core.POSTGRES:
+		if _, err := x.Exec(`ALTER TABLE "bill" ALTER COLUMN "state" TYPE VARCHAR(16)`); err != nil {
+			return fmt.Errorf("modify column: %v", err)
+		}
+	}
+
+	// Bills used to have the state "todo" until all recipients paid.
+	type bill struct {
+		UID       string
+		CreatedBy string
+		SentTo    string
+		PayedBy   string
+	}
+
+	bills := make([]*bill, 0, 10)
+	if err := x.Table("bill").
+		Cols("uid", "created_by", "sent_to", "payed_by").
+		Where("state=?", "todo").
+		Find(&bills); err != nil {
+		return err
+	}
+
+	for _, b := range bills {
+		var sentTo, payedBy []string
+		if b.SentTo != "" {
+			if err := json.Unmarshal([]byte(b.SentTo), &sentTo); err != nil {
+				return fmt.Errorf("bill %s: %v", b.UID, err)
+			}
+		}
+		if b.PayedBy != "" {
+			if err := json.Unmarshal([]byte(b.PayedBy), &payedBy); err != nil {
+				return fmt.Errorf("bill %s: %v", b.UID, err)
+			}
+		}
+
+		state := billStateOf(b.CreatedBy, sentTo, payedBy)
+		if _, err := x.Table("bill").
+			Where("uid=?", b.UID).
+			Update(map[string]interface{}{"state": state}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// billStateOf returns the state of a "todo" bill. The creator of a bill never owes himself.
+func billStateOf(createdBy string, sentTo, payedBy []string) string {
+	if len(sentTo) == 0 {
+		return "draft"
+	}
+
+	paid := make(map[string]bool, len(payedBy)+1)
+	paid[createdBy] = true
+	for _, uid := range payedBy {
+		paid[uid] = true
+	}
+
+	open, payments := 0, 0
+	for _, uid := range sentTo {
+		if !paid[uid] {
+			open++
+		} else if uid != createdBy {
+			payments++
+		}
+	}
+
+	switch {
+	case open == 0:
+		return "paid"
+	case payments > 0:
+		return "partial"
+	default:
+		return "sent"
+	}
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addListItemTemplates(x *xorm.Engine) error {
+	type ListItemTemplate struct {
+		ID           string  `xorm:"pk VARCHAR(36)"`
+		GroupUID     string  `xorm:"VARCHAR(36) INDEX"`
+		Title        *string `xorm:"NOT NULL"`
+		Category     *string
+		Count        *int64     `xorm:"DEFAULT 0"`
+		Price        int64      `xorm:"DEFAULT 0"`
+		RequestedBy  string     `xorm:"NOT NULL"`
+		RequestedFor []string   `xorm:"NOT NULL"`
+		Recurrence   *string    `xorm:"VARCHAR(16) NOT NULL"`
+		Interval     int64      `xorm:"DEFAULT 1"`
+		NextDueAt    *time.Time `xorm:"NULL INDEX"`
+		LastAddedAt  *time.Time `xorm:"NULL"`
+		CreatedAt    time.Time  `xorm:"created"`
+		UpdatedAt    time.Time  `xorm:"updated"`
+	}
+
+	type ListItem struct {
+		TemplateUID string `xorm:"VARCHAR(36) INDEX"`
+	}
+
+	return x.Sync2(new(ListItemTemplate), new(ListItem))
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addTasks(x *xorm.Engine) error {
+	type Task struct {
+		ID          string     `xorm:"pk VARCHAR(36)"`
+		GroupUID    string     `xorm:"VARCHAR(36) INDEX"`
+		Title       *string    `xorm:"NOT NULL"`
+		Description string     `xorm:"TEXT"`
+		Recurrence  *string    `xorm:"VARCHAR(16) NOT NULL"`
+		Interval    int64      `xorm:"DEFAULT 1"`
+		Rotation    []string   `xorm:"TEXT"`
+		Assignee    string     `xorm:"VARCHAR(28) INDEX"`
+		DueAt       *time.Time `xorm:"NULL"`
+		CreatedBy   string     `xorm:"VARCHAR(28)"`
+		CreatedAt   time.Time  `xorm:"created"`
+		UpdatedAt   time.Time  `xorm:"updated"`
+	}
+
+	type TaskCompletion struct {
+		ID          int64      `xorm:"pk autoincr"`
+		TaskUID     string     `xorm:"VARCHAR(36) INDEX"`
+		GroupUID    string     `xorm:"VARCHAR(36) INDEX"`
+		UserUID     string     `xorm:"VARCHAR(28)"`
+		CompletedBy string     `xorm:"VARCHAR(28)"`
+		Action      string     `xorm:"VARCHAR(16)"`
+		DueAt       *time.Time `xorm:"NULL"`
+		CreatedAt   time.Time  `xorm:"created"`
+	}
+
+	return x.Sync2(new(Task), new(TaskCompletion))
+}
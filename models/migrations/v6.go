@@ -0,0 +1,15 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addGroupDeletedAt(x *xorm.Engine) error {
+	type Group struct {
+		DeletedAt time.Time `xorm:"deleted"`
+	}
+
+	return x.Sync2(new(Group))
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addNotificationOutbox(x *xorm.Engine) error {
+	type Notification struct {
+		ID        int64     `xorm:"pk autoincr"`
+		Type      string    `xorm:"VARCHAR(64) NOT NULL"`
+		Data      []string  `xorm:"TEXT"`
+		Receivers []string  `xorm:"TEXT"`
+		State     string    `xorm:"VARCHAR(16) INDEX NOT NULL"`
+		Attempts  int       `xorm:"DEFAULT 0"`
+		LastError string    `xorm:"TEXT"`
+		NextTryAt time.Time `xorm:"INDEX"`
+		CreatedAt time.Time `xorm:"created"`
+		UpdatedAt time.Time `xorm:"updated"`
+	}
+
+	return x.Sync2(new(Notification))
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addMailNotifications(x *xorm.Engine) error {
+	type User struct {
+		MailOptOut []string `xorm:"TEXT"`
+	}
+
+	type Bill struct {
+		ReminderSentAt *time.Time `xorm:"NULL"`
+	}
+
+	type Group struct {
+		DigestSentAt *time.Time `xorm:"NULL"`
+	}
+
+	return x.Sync2(new(User), new(Bill), new(Group))
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+func addGroupInvites(x *xorm.Engine) error {
+	type GroupInvite struct {
+		ID         int64  `xorm:"pk autoincr"`
+		GroupUID   string `xorm:"VARCHAR(36) INDEX"`
+		Email      string `xorm:"NOT NULL"`
+		Locale     string `xorm:"VARCHAR(5)"`
+		Token      string `xorm:"VARCHAR(12) UNIQUE"`
+		CreatedBy  string `xorm:"VARCHAR(28)"`
+		UsedBy     string `xorm:"VARCHAR(28)"`
+		ValidUntil time.Time
+		UsedAt     *time.Time `xorm:"NULL"`
+		RevokedAt  *time.Time `xorm:"NULL"`
+		CreatedAt  time.Time  `xorm:"created"`
+	}
+
+	return x.Sync2(new(GroupInvite))
+}
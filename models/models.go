@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"log"
 	"path"
+	"time"
 
+	"github.com/wgplaner/wg_planer_server/models/migrations"
 	"github.com/wgplaner/wg_planer_server/modules/setting"
 
 	// Load MySQL driver
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/go-xorm/core"
 	"github.com/go-xorm/xorm"
+	"github.com/op/go-logging"
 	// Load PostgreSQL driver
 	_ "github.com/lib/pq"
 	// Load SQLite3 driver
@@ -21,6 +24,8 @@ import (
 var (
 	x      *xorm.Engine
 	tables []interface{}
+
+	dbLog = logging.MustGetLogger("Database")
 )
 
 func init() {
@@ -29,7 +34,17 @@ func init() {
 		new(User),
 		new(Group),
 		new(GroupCode),
+		new(GroupInvite),
 		new(ListItem),
+		new(ListItemTemplate),
+		new(MemberBalance),
+		new(Notification),
+		new(Purchase),
+		new(SyncChange),
+		new(SyncSequence),
+		new(IdempotencyKey),
+		new(Task),
+		new(TaskCompletion),
 	}
 
 	gonicNames := []string{"ID", "UID"}
@@ -45,20 +60,45 @@ func SetEngine() error {
 	return nil
 }
 
-// NewEngine initializes a new xorm.Engine
-func NewEngine() (err error) {
+// InitEngine initializes a new xorm.Engine without touching the database schema
+func InitEngine() (err error) {
 	if err = SetEngine(); err != nil {
 		return err
 	}
-	if err = x.Ping(); err != nil {
+	cfg := setting.AppConfig.Database
+	return pingWithRetry(x, cfg.ConnectRetries, cfg.ConnectBackoffDuration)
+}
+
+// NewEngine initializes a new xorm.Engine and migrates the database schema
+// if "auto_migrate" is enabled. Otherwise it fails if migrations are pending.
+func NewEngine() (err error) {
+	if err = InitEngine(); err != nil {
+		return err
+	}
+
+	if setting.AppConfig.Database.AutoMigrate {
+		if _, err = Migrate(false); err != nil {
+			return fmt.Errorf("migrate: %v", err)
+		}
+		return nil
+	}
+
+	pending, err := migrations.Pending(x)
+	if err != nil {
 		return err
+	} else if len(pending) > 0 {
+		return fmt.Errorf("database schema is outdated (%d pending migrations), "+
+			"run \"wgplaner-api migrate\" or enable \"auto_migrate\"", len(pending))
 	}
-	//if err = x.StoreEngine("InnoDB").Sync2(tables...); err != nil {
-	//	return fmt.Errorf("sync database struct error: %v", err)
-	//}
 	return nil
 }
 
+// Migrate runs all pending database migrations. If dryRun is set, the
+// pending migrations are only returned. It returns the executed migrations.
+func Migrate(dryRun bool) ([]string, error) {
+	return migrations.Migrate(x, dryRun)
+}
+
 func getEngine() *xorm.Engine {
 	var err error
 	var engine *xorm.Engine
@@ -67,6 +107,9 @@ func getEngine() *xorm.Engine {
 	case setting.DriverMySQL:
 		engine, err = getMysqlEngine()
 
+	case setting.DriverPostgres:
+		engine, err = getPostgresEngine()
+
 	case setting.DriverSQLite:
 		filePath := path.Join(setting.AppWorkPath, setting.AppConfig.Database.SqliteFile)
 		engine, err = xorm.NewEngine("sqlite3", filePath)
@@ -83,10 +126,12 @@ func getEngine() *xorm.Engine {
 	engine.SetMapper(core.GonicMapper{})
 	engine.ShowSQL(true)
 
-	if err = engine.Sync(tables...); err != nil {
-		log.Fatal("[SQL] Synchronization failed! ", err)
-		return nil
+	cfg := setting.AppConfig.Database
+	engine.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		engine.SetMaxIdleConns(cfg.MaxIdleConns)
 	}
+	engine.DB().SetConnMaxLifetime(cfg.ConnMaxLifetimeDuration)
 
 	if setting.AppConfig.Database.LogSQL {
 		engine.Logger().SetLevel(core.LOG_DEBUG)
@@ -98,8 +143,23 @@ func getEngine() *xorm.Engine {
 }
 
 func getMysqlEngine() (*xorm.Engine, error) {
-	dataSource := fmt.Sprintf("%s:%s@/%s?charset=utf-8", setting.AppConfig.Database.MysqlServer,
-		setting.AppConfig.Database.MysqlPassword, setting.AppConfig.Database.MysqlDatabaseName)
+	return xorm.NewEngine("mysql", setting.AppConfig.Database.MysqlDataSource())
+}
 
-	return xorm.NewEngine("mysql", dataSource)
+func getPostgresEngine() (*xorm.Engine, error) {
+	return xorm.NewEngine("postgres", setting.AppConfig.Database.PostgresDataSource())
+}
+
+// pingWithRetry pings the database until it's reachable. The time between
+// two attempts starts at "backoff" and is doubled after each failed attempt.
+func pingWithRetry(engine *xorm.Engine, retries int, backoff time.Duration) error {
+	for i := 0; ; i++ {
+		err := engine.Ping()
+		if err == nil || i >= retries {
+			return err
+		}
+		dbLog.Warningf("Database is not ready (%v), retrying in %s", err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
 }
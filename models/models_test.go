@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/wgplaner/wg_planer_server/models/migrations"
+
+	"github.com/go-xorm/core"
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// tableColumns returns the columns of all tables of the database.
+func tableColumns(t *testing.T, engine *xorm.Engine) map[string][]string {
+	metas, err := engine.DBMetas()
+	assert.NoError(t, err)
+
+	columns := make(map[string][]string, len(metas))
+	for _, table := range metas {
+		columns[table.Name] = table.ColumnsSeq()
+	}
+	return columns
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	engine, err := xorm.NewEngine("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(core.GonicMapper{})
+	// ":memory:" databases only live as long as their connection
+	engine.SetMaxOpenConns(1)
+
+	_, err = migrations.Migrate(engine, false)
+	assert.NoError(t, err)
+	migrated := tableColumns(t, engine)
+
+	// Sync2 adds the tables and columns of the models that the migrations missed
+	assert.NoError(t, engine.Sync2(tables...))
+	assert.Equal(t, migrated, tableColumns(t, engine))
+}
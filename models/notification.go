@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+)
+
+// States of an outbox notification
+const (
+	// NotificationStatePending notifications are (re-)delivered once "nextTryAt" is reached.
+	NotificationStatePending = "pending"
+	// NotificationStateSent notifications were delivered to all receivers with a device.
+	NotificationStateSent = "sent"
+	// NotificationStateDead notifications failed too often and are not retried.
+	NotificationStateDead = "dead"
+)
+
+// Notification is a push update in the outbox. Notifications are added
+// after the change was committed and are delivered by background workers.
+type Notification struct {
+	ID int64 `xorm:"pk autoincr"`
+
+	// Type is the push update type, e.g. "ShoppingList-Add".
+	Type string `xorm:"VARCHAR(64) NOT NULL"`
+
+	// Data contains the updated uids.
+	Data []string `xorm:"TEXT"`
+
+	// Receivers are the users which still have to be notified.
+	Receivers []string `xorm:"TEXT"`
+
+	State     string    `xorm:"VARCHAR(16) INDEX NOT NULL"`
+	Attempts  int       `xorm:"DEFAULT 0"`
+	LastError string    `xorm:"TEXT"`
+	NextTryAt time.Time `xorm:"INDEX"`
+	CreatedAt time.Time `xorm:"created"`
+	UpdatedAt time.Time `xorm:"updated"`
+}
+
+// CreateNotification adds a notification to the outbox.
+func CreateNotification(t string, data, receivers []string) (*Notification, error) {
+	n := &Notification{
+		Type:      t,
+		Data:      data,
+		Receivers: receivers,
+		State:     NotificationStatePending,
+		NextTryAt: time.Now().UTC(),
+	}
+	if _, err := x.InsertOne(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// GetDueNotifications returns at most "limit" pending notifications
+// that should be delivered at "now", oldest first.
+func GetDueNotifications(now time.Time, limit int) ([]*Notification, error) {
+	notifications := make([]*Notification, 0, limit)
+	err := x.
+		Where(`state = ?`, NotificationStatePending).
+		And(`next_try_at <= ?`, now).
+		Asc(`id`).
+		Limit(limit).
+		Find(&notifications)
+	return notifications, err
+}
+
+// Claim reserves a due notification for delivery until "until", so that
+// no other worker delivers it. If the worker crashes, the notification
+// is delivered again afterwards. Claim returns false if another worker
+// claimed the notification first.
+func (n *Notification) Claim(now, until time.Time) (bool, error) {
+	affected, err := x.ID(n.ID).
+		Where(`state = ?`, NotificationStatePending).
+		And(`next_try_at <= ?`, now).
+		Cols(`next_try_at`).
+		Update(&Notification{NextTryAt: until})
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	n.NextTryAt = until
+	return true, nil
+}
+
+// MarkSent marks the notification as delivered.
+func (n *Notification) MarkSent() error {
+	n.State = NotificationStateSent
+	n.Attempts++
+	n.LastError = ""
+	_, err := x.ID(n.ID).Cols(`state`, `attempts`, `last_error`).Update(n)
+	return err
+}
+
+// Retry schedules another delivery to "receivers" at "next".
+func (n *Notification) Retry(receivers []string, cause error, next time.Time) error {
+	n.Receivers = receivers
+	n.Attempts++
+	n.LastError = cause.Error()
+	n.NextTryAt = next
+	_, err := x.ID(n.ID).Cols(`receivers`, `attempts`, `last_error`, `next_try_at`).Update(n)
+	return err
+}
+
+// MarkDead stops delivering the notification to the remaining receivers.
+func (n *Notification) MarkDead(receivers []string, cause error) error {
+	n.State = NotificationStateDead
+	n.Receivers = receivers
+	n.Attempts++
+	n.LastError = cause.Error()
+	_, err := x.ID(n.ID).Cols(`state`, `receivers`, `attempts`, `last_error`).Update(n)
+	return err
+}
+
+// DeleteSentNotifications removes delivered notifications which were
+// created before "before". It returns the number of deleted notifications.
+func DeleteSentNotifications(before time.Time) (int64, error) {
+	return x.
+		Where(`state = ?`, NotificationStateSent).
+		And(`created_at < ?`, before).
+		Delete(new(Notification))
+}
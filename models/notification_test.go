@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationDelivery(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	now := time.Now().UTC()
+
+	n, err := CreateNotification("ShoppingList-Add", []string{"item"}, []string{"user1", "user2"})
+	assert.NoError(t, err)
+
+	due, err := GetDueNotifications(now.Add(time.Second), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, []string{"user1", "user2"}, due[0].Receivers)
+	}
+
+	// Only one worker may claim a notification
+	claimed, err := n.Claim(now.Add(time.Second), now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = due[0].Claim(now.Add(time.Second), now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	due, err = GetDueNotifications(now.Add(time.Second), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+
+	// Retry for one receiver
+	assert.NoError(t, n.Retry([]string{"user2"}, errors.New("unavailable"), now.Add(time.Hour)))
+	n = AssertExistsAndLoadBean(t, &Notification{ID: n.ID}).(*Notification)
+	assert.Equal(t, NotificationStatePending, n.State)
+	assert.Equal(t, []string{"user2"}, n.Receivers)
+	assert.Equal(t, 1, n.Attempts)
+	assert.Equal(t, "unavailable", n.LastError)
+
+	due, err = GetDueNotifications(now.Add(2*time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	assert.NoError(t, n.MarkSent())
+	n = AssertExistsAndLoadBean(t, &Notification{ID: n.ID}).(*Notification)
+	assert.Equal(t, NotificationStateSent, n.State)
+	assert.Equal(t, 2, n.Attempts)
+
+	due, err = GetDueNotifications(now.Add(2*time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestNotification_MarkDead(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	n, err := CreateNotification("ShoppingList-Add", nil, []string{"user1"})
+	assert.NoError(t, err)
+	assert.NoError(t, n.MarkDead([]string{"user1"}, errors.New("unavailable")))
+
+	n = AssertExistsAndLoadBean(t, &Notification{ID: n.ID}).(*Notification)
+	assert.Equal(t, NotificationStateDead, n.State)
+
+	due, err := GetDueNotifications(time.Now().UTC().Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestDeleteSentNotifications(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	sent, err := CreateNotification("ShoppingList-Add", nil, []string{"user1"})
+	assert.NoError(t, err)
+	assert.NoError(t, sent.MarkSent())
+	_, err = CreateNotification("ShoppingList-Add", nil, []string{"user1"})
+	assert.NoError(t, err)
+
+	count, err := DeleteSentNotifications(time.Now().UTC().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+
+	count, err = DeleteSentNotifications(time.Now().UTC().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	AssertCount(t, &Notification{}, 1)
+}
@@ -0,0 +1,423 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
+	"github.com/satori/go.uuid"
+)
+
+// Purchase purchase of a list item. Every purchase is recorded, so that the
+// history of an item shows all partial purchases.
+// swagger:model Purchase
+type Purchase struct {
+	// id
+	// Read Only: true
+	ID strfmt.UUID `xorm:"VARCHAR(36) pk" json:"id,omitempty"`
+
+	// group UID
+	// Read Only: true
+	GroupUID strfmt.UUID `xorm:"VARCHAR(36) INDEX NOT NULL" json:"groupUID,omitempty"`
+
+	// list item UID (the bought item)
+	// Required: true
+	ListItemUID strfmt.UUID `xorm:"VARCHAR(36) INDEX NOT NULL" json:"listItemUID"`
+
+	// origin UID (the item that was originally requested)
+	// Required: true
+	OriginUID strfmt.UUID `xorm:"VARCHAR(36) INDEX NOT NULL" json:"originUID"`
+
+	// bought by
+	// Required: true
+	BoughtBy string `xorm:"VARCHAR(28) NOT NULL" json:"boughtBy"`
+
+	// bought at
+	// Required: true
+	BoughtAt strfmt.DateTime `xorm:"NOT NULL" json:"boughtAt"`
+
+	// count
+	// Required: true
+	Count int64 `xorm:"NOT NULL" json:"count"`
+
+	// unit of the quantity
+	Unit string `xorm:"VARCHAR(8)" json:"unit,omitempty"`
+
+	// quantity in the unit
+	Quantity *float64 `xorm:"DOUBLE NULL" json:"quantity,omitempty"`
+
+	// price (total price of the bought part)
+	Price int64 `xorm:"DEFAULT 0" json:"price"`
+
+	// partial is true if only a part of the requested count was bought
+	Partial bool `json:"partial"`
+
+	// created at
+	// Read Only: true
+	CreatedAt strfmt.DateTime `xorm:"created" json:"createdAt,omitempty"`
+}
+
+// Validate validates this purchase
+func (m *Purchase) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := validate.RequiredString("listItemUID", "body", string(m.ListItemUID)); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.RequiredString("originUID", "body", string(m.OriginUID)); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.RequiredString("boughtBy", "body", m.BoughtBy); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *Purchase) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *Purchase) UnmarshalBinary(b []byte) error {
+	var res Purchase
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// PurchaseList purchase list
+// swagger:model PurchaseList
+type PurchaseList struct {
+	// count
+	// Required: true
+	// Read Only: true
+	Count int64 `json:"count"`
+
+	// purchases
+	// Required: true
+	// Read Only: true
+	Purchases []*Purchase `json:"purchases"`
+}
+
+// Validate validates this purchase list
+func (m *PurchaseList) Validate(formats strfmt.Registry) error {
+	if err := validate.Required("purchases", "body", m.Purchases); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Purchases); i++ {
+		if m.Purchases[i] != nil {
+			if err := m.Purchases[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("purchases" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *PurchaseList) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *PurchaseList) UnmarshalBinary(b []byte) error {
+	var res PurchaseList
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// PartialPurchase partial purchase
+// swagger:model PartialPurchase
+type PartialPurchase struct {
+	// count of the bought part
+	// Required: true
+	// Minimum: 1
+	Count *int64 `json:"count"`
+
+	// price of the bought part (prorated if not set)
+	// Minimum: 0
+	Price *int64 `json:"price,omitempty"`
+}
+
+// Validate validates this partial purchase
+func (m *PartialPurchase) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := validate.Required("count", "body", m.Count); err != nil {
+		res = append(res, err)
+	} else if err := validate.MinimumInt("count", "body", *m.Count, 1, false); err != nil {
+		res = append(res, err)
+	}
+	if m.Price != nil {
+		if err := validate.MinimumInt("price", "body", *m.Price, 0, false); err != nil {
+			res = append(res, err)
+		}
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *PartialPurchase) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *PartialPurchase) UnmarshalBinary(b []byte) error {
+	var res PartialPurchase
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// PartialPurchaseResult partial purchase result
+// swagger:model PartialPurchaseResult
+type PartialPurchaseResult struct {
+	// purchase
+	// Required: true
+	Purchase *Purchase `json:"purchase"`
+
+	// the bought item
+	// Required: true
+	ListItem *ListItem `json:"listItem"`
+
+	// the remainder that stays on the list (not set if everything was bought)
+	Remainder *ListItem `json:"remainder,omitempty"`
+}
+
+// Validate validates this partial purchase result
+func (m *PartialPurchaseResult) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := validate.Required("purchase", "body", m.Purchase); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.Required("listItem", "body", m.ListItem); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *PartialPurchaseResult) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *PartialPurchaseResult) UnmarshalBinary(b []byte) error {
+	var res PartialPurchaseResult
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// newPurchase returns the purchase of the item with its current quantity and total price.
+func newPurchase(item *ListItem, boughtBy string, boughtAt time.Time, partial bool) (*Purchase, error) {
+	purchaseUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	return &Purchase{
+		ID:          strfmt.UUID(purchaseUID.String()),
+		GroupUID:    item.GroupUID,
+		ListItemUID: item.ID,
+		OriginUID:   item.OriginUID(),
+		BoughtBy:    boughtBy,
+		BoughtAt:    strfmt.DateTime(boughtAt),
+		Count:       swag.Int64Value(item.Count),
+		Unit:        item.Unit,
+		Quantity:    item.Quantity,
+		Price:       item.TotalPrice(),
+		Partial:     partial,
+	}, nil
+}
+
+// createPurchases records the purchases of the given items.
+func createPurchases(sess *xorm.Session, items []*ListItem, boughtBy string, boughtAt time.Time) error {
+	purchases := make([]*Purchase, 0, len(items))
+	for _, item := range items {
+		p, err := newPurchase(item, boughtBy, boughtAt, false)
+		if err != nil {
+			return err
+		}
+		purchases = append(purchases, p)
+	}
+	if len(purchases) == 0 {
+		return nil
+	}
+	_, err := sess.Insert(&purchases)
+	return err
+}
+
+// getAlreadyBoughtError returns ErrListItemsAlreadyBought with the items
+// that are bought. It has to be called after the transaction was rolled back.
+func getAlreadyBoughtError(guid strfmt.UUID, ids []string) error {
+	errBought := ErrListItemsAlreadyBought{
+		GroupUID: guid,
+		Items:    make([]*ListItem, 0, len(ids)),
+	}
+	if err := x.Where(`group_uid=?`, guid).
+		And(`bought_at IS NOT NULL`).
+		In(`id`, ids).
+		Find(&errBought.Items); err != nil {
+		return err
+	}
+	return errBought
+}
+
+// BuyListItemPartially buys "count" of the item for "price" (in total). Without
+// a price, the bought part costs its share of the total price of the item. The
+// bought part keeps the item, the remainder stays on the list as a new item.
+// The quantity is split in proportion to the count. The remainder keeps a per
+// unit price, a total price is split as well. Buying the whole count buys
+// the item.
+func (u *User) BuyListItemPartially(itemUID strfmt.UUID, count int64, price *int64) (*PartialPurchaseResult, error) {
+	var result *PartialPurchaseResult
+
+	err := withTx(func(sess *xorm.Session) error {
+		item := new(ListItem)
+		if has, err := sess.Where(`group_uid=?`, u.GroupUID).And(`id=?`, itemUID).Get(item); err != nil {
+			return err
+		} else if !has {
+			return ErrListItemNotExist{ID: itemUID, GroupUID: u.GroupUID}
+		} else if item.BoughtAt != nil {
+			return ErrListItemsAlreadyBought{GroupUID: u.GroupUID}
+		}
+
+		total := swag.Int64Value(item.Count)
+		if count < 1 || count > total {
+			return ErrListItemInvalidPurchaseCount{ID: itemUID, Count: count, Max: total}
+		}
+
+		if price == nil {
+			price = swag.Int64(item.TotalPrice() * count / total)
+		}
+
+		now := time.Now().UTC()
+		remainder := &ListItem{
+			Count:     swag.Int64(total - count),
+			Price:     item.Price,
+			PriceMode: item.PriceMode,
+			Unit:      item.Unit,
+		}
+		if item.PriceMode != PriceModePerUnit {
+			remainder.Price = item.Price - item.Price*count/total
+		}
+		if item.Quantity != nil {
+			bought := roundQuantity(*item.Quantity * float64(count) / float64(total))
+			remainder.Quantity = swag.Float64(roundQuantity(*item.Quantity - bought))
+			item.Quantity = &bought
+		}
+
+		item.Count = swag.Int64(count)
+		item.Price = *price
+		item.PriceMode = PriceModeTotal
+		item.BoughtBy = *u.UID
+		item.BoughtAt = &now
+
+		affected, err := sess.Cols(`bought_by`, `bought_at`, `count`, `quantity`, `price`, `price_mode`).
+			Where(`group_uid=?`, u.GroupUID).
+			And(`id=?`, itemUID).
+			And(`bought_at IS NULL`).
+			Incr(`version`).
+			Update(item)
+		if err != nil {
+			return err
+		} else if affected == 0 {
+			return ErrListItemsAlreadyBought{GroupUID: u.GroupUID}
+		}
+
+		result = &PartialPurchaseResult{ListItem: item}
+		changed := []string{string(item.ID)}
+
+		if count < total {
+			remainderUID, err := uuid.NewV4()
+			if err != nil {
+				return err
+			}
+			remainder.ID = strfmt.UUID(remainderUID.String())
+			remainder.GroupUID = item.GroupUID
+			remainder.TemplateUID = item.TemplateUID
+			remainder.RemainderOf = item.OriginUID()
+			remainder.Title = item.Title
+			remainder.Category = item.Category
+			remainder.RequestedBy = item.RequestedBy
+			remainder.RequestedFor = item.RequestedFor
+			result.Remainder = remainder
+			if _, err := sess.InsertOne(result.Remainder); err != nil {
+				return err
+			}
+			changed = append(changed, string(result.Remainder.ID))
+		}
+
+		if result.Purchase, err = newPurchase(item, *u.UID, now, count < total); err != nil {
+			return err
+		}
+		if _, err := sess.InsertOne(result.Purchase); err != nil {
+			return err
+		}
+		return recordChanges(sess, u.GroupUID, SyncTypeListItem, changed, false)
+	})
+
+	if IsErrListItemsAlreadyBought(err) {
+		return nil, getAlreadyBoughtError(u.GroupUID, []string{string(itemUID)})
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Reload to get the new version
+	if result.ListItem, err = GetListItemByUIDs(u.GroupUID, itemUID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetListItemPurchases returns all purchases of the item and of the items
+// it was split into by partial purchases, oldest first.
+func GetListItemPurchases(item *ListItem) (*PurchaseList, error) {
+	purchases := make([]*Purchase, 0, 1)
+	if err := x.Where(`group_uid=?`, item.GroupUID).
+		And(`origin_uid=?`, item.OriginUID()).
+		Asc(`bought_at`).
+		Find(&purchases); err != nil {
+		return nil, err
+	}
+	return &PurchaseList{
+		Count:     int64(len(purchases)),
+		Purchases: purchases,
+	}, nil
+}
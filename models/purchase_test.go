@@ -0,0 +1,109 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_BuyListItemPartially(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	const itemUID = "00112233-4455-6677-8899-000000000005"
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0001")}).(*User)
+
+	// 6 of 20 apples
+	result, err := u.BuyListItemPartially(itemUID, 6, swag.Int64(30))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, result) {
+		return
+	}
+	assert.EqualValues(t, 6, *result.ListItem.Count)
+	assert.EqualValues(t, 30, result.ListItem.Price)
+	assert.Equal(t, *u.UID, result.ListItem.BoughtBy)
+	assert.EqualValues(t, 2, result.ListItem.Version)
+	assert.True(t, result.Purchase.Partial)
+	if assert.NotNil(t, result.Remainder) {
+		assert.EqualValues(t, 14, *result.Remainder.Count)
+		assert.EqualValues(t, 56, result.Remainder.Price)
+		assert.EqualValues(t, itemUID, result.Remainder.RemainderOf)
+		assert.Nil(t, result.Remainder.BoughtAt)
+	}
+
+	// The rest of the remainder
+	rest, err := u.BuyListItemPartially(result.Remainder.ID, 14, swag.Int64(70))
+	assert.NoError(t, err)
+	assert.Nil(t, rest.Remainder)
+	assert.False(t, rest.Purchase.Partial)
+	assert.EqualValues(t, itemUID, rest.Purchase.OriginUID)
+
+	// Both items show the whole history
+	for _, item := range []*ListItem{result.ListItem, rest.ListItem} {
+		purchases, err := GetListItemPurchases(item)
+		assert.NoError(t, err)
+		if assert.Len(t, purchases.Purchases, 2) {
+			assert.EqualValues(t, 6, purchases.Purchases[0].Count)
+			assert.EqualValues(t, 14, purchases.Purchases[1].Count)
+		}
+	}
+
+	_, err = u.BuyListItemPartially(itemUID, 1, nil)
+	assert.True(t, IsErrListItemsAlreadyBought(err))
+
+	for _, count := range []int64{0, 16} {
+		_, err = u.BuyListItemPartially("00112233-4455-6677-8899-000000000002", count, nil)
+		assert.True(t, IsErrListItemInvalidPurchaseCount(err))
+	}
+
+	_, err = u.BuyListItemPartially("00112233-4455-6677-8899-000000000099", 1, nil)
+	assert.True(t, IsErrListItemNotExist(err))
+}
+
+func TestUser_BuyListItemPartiallyProratedPrice(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	const itemUID = "00112233-4455-6677-8899-000000000005"
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0001")}).(*User)
+
+	// 6 of 20 apples for 80 in total
+	result, err := u.BuyListItemPartially(itemUID, 6, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 24, result.ListItem.Price)
+	assert.EqualValues(t, 24, result.Purchase.Price)
+	assert.EqualValues(t, 56, result.Remainder.Price)
+
+	// The whole rest costs the rest of the price
+	rest, err := u.BuyListItemPartially(result.Remainder.ID, 14, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 56, rest.ListItem.Price)
+	assert.Nil(t, rest.Remainder)
+
+	// A per unit price is multiplied
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	item.PriceMode = PriceModePerUnit
+	item.Price = 10
+	assert.NoError(t, UpdateListItemCols(item, `price`, `price_mode`))
+	result, err = u.BuyListItemPartially(item.ID, 15, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 150, result.ListItem.Price)
+	assert.Equal(t, PriceModeTotal, result.ListItem.PriceMode)
+}
+
+func TestPurchasesOfBuyAndRevert(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	const itemUID = "00112233-4455-6677-8899-000000000002"
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0001")}).(*User)
+
+	assert.NoError(t, u.BuyListItemsByUIDs([]strfmt.UUID{itemUID}))
+	p := AssertExistsAndLoadBean(t, &Purchase{ListItemUID: itemUID}).(*Purchase)
+	assert.Equal(t, *u.UID, p.BoughtBy)
+	assert.EqualValues(t, 15, p.Count)
+	assert.EqualValues(t, 80, p.Price)
+	assert.False(t, p.Partial)
+
+	assert.NoError(t, u.RevertListItemPurchaseByUID(itemUID))
+	AssertNotExistsBean(t, &Purchase{ListItemUID: itemUID})
+}
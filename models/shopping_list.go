@@ -21,6 +21,10 @@ type ShoppingList struct {
 	// Required: true
 	// Read Only: true
 	ListItems []*ListItem `json:"listItems"`
+
+	// cursor of the next page (empty if there are no more items)
+	// Read Only: true
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // Validate validates this shopping list
@@ -84,3 +88,63 @@ func (m *ShoppingList) UnmarshalBinary(b []byte) error {
 	*m = res
 	return nil
 }
+
+// BuyConflict buy conflict
+// swagger:model BuyConflict
+type BuyConflict struct {
+	// message
+	// Required: true
+	Message *string `json:"message"`
+
+	// status
+	// Required: true
+	Status *int64 `json:"status"`
+
+	// current state of the items that were already bought by others
+	// Read Only: true
+	ListItems []*ListItem `json:"listItems"`
+}
+
+// Validate validates this buy conflict
+func (m *BuyConflict) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := validate.Required("message", "body", m.Message); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.Required("status", "body", m.Status); err != nil {
+		res = append(res, err)
+	}
+	for i := 0; i < len(m.ListItems); i++ {
+		if m.ListItems[i] != nil {
+			if err := m.ListItems[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					res = append(res, ve.ValidateName("listItems"+"."+strconv.Itoa(i)))
+				} else {
+					res = append(res, err)
+				}
+			}
+		}
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *BuyConflict) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *BuyConflict) UnmarshalBinary(b []byte) error {
+	var res BuyConflict
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
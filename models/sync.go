@@ -0,0 +1,848 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
+)
+
+// Types of entities that are synchronized with clients.
+const (
+	SyncTypeListItem = "ListItem"
+	SyncTypeBill     = "Bill"
+	SyncTypeGroup    = "Group"
+	SyncTypeUser     = "User"
+)
+
+// Operations of offline mutations. Only list items can be changed offline.
+const (
+	SyncOpCreate = "create"
+	SyncOpUpdate = "update"
+	SyncOpDelete = "delete"
+	SyncOpBuy    = "buy"
+	SyncOpRevert = "revert"
+)
+
+// Results of offline mutations.
+const (
+	// SyncStatusApplied means that the mutation was applied.
+	SyncStatusApplied = "applied"
+	// SyncStatusConflict means that the entity was changed or deleted by someone
+	// else since the cursor of the batch. The result contains the server's state.
+	SyncStatusConflict = "conflict"
+	// SyncStatusRejected means that the mutation is invalid or not allowed.
+	SyncStatusRejected = "rejected"
+)
+
+const (
+	// SyncPageSize is the maximum number of changes per sync response.
+	SyncPageSize = 500
+	// SyncMaxMutations is the maximum number of mutations per batch.
+	SyncMaxMutations = 100
+)
+
+// SyncChange records that an entity of a group was created, updated or
+// deleted. It is added in the transaction that changes the entity.
+// Its Seq increases monotonically per group and is part of the sync cursor.
+type SyncChange struct {
+	ID         int64       `xorm:"pk autoincr"`
+	GroupUID   strfmt.UUID `xorm:"VARCHAR(36) INDEX NOT NULL"`
+	Seq        int64       `xorm:"INDEX NOT NULL DEFAULT 0"`
+	EntityType string      `xorm:"VARCHAR(16) NOT NULL"`
+	EntityID   string      `xorm:"VARCHAR(36) NOT NULL"`
+	Deleted    bool
+	CreatedAt  time.Time `xorm:"created"`
+}
+
+// SyncSequence is the last Seq of the change log of a group. The row is
+// locked by each transaction that records a change until it commits. So
+// changes of a group become visible in the order of their Seq and a cursor
+// never skips a change that is committed later.
+type SyncSequence struct {
+	GroupUID strfmt.UUID `xorm:"VARCHAR(36) pk"`
+	Seq      int64       `xorm:"NOT NULL DEFAULT 0"`
+}
+
+// nextSyncSeq increments and returns the sequence of the group.
+func nextSyncSeq(sess *xorm.Session, guid strfmt.UUID) (int64, error) {
+	affected, err := sess.ID(guid).Incr(`seq`).Update(new(SyncSequence))
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		// The first change of the group is recorded with the
+		// creation of the group, so nobody else can see it yet.
+		if _, err := sess.InsertOne(&SyncSequence{GroupUID: guid, Seq: 1}); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	s := new(SyncSequence)
+	if _, err := sess.ID(guid).Get(s); err != nil {
+		return 0, err
+	}
+	return s.Seq, nil
+}
+
+// recordChange adds a change of the entity to the change log of the group.
+func recordChange(sess *xorm.Session, guid strfmt.UUID, entityType, id string, deleted bool) error {
+	if guid == "" {
+		return nil
+	}
+	seq, err := nextSyncSeq(sess, guid)
+	if err != nil {
+		return err
+	}
+	_, err = sess.InsertOne(&SyncChange{
+		GroupUID:   guid,
+		Seq:        seq,
+		EntityType: entityType,
+		EntityID:   id,
+		Deleted:    deleted,
+	})
+	return err
+}
+
+// recordChanges adds a change of each entity to the change log of the group.
+func recordChanges(sess *xorm.Session, guid strfmt.UUID, entityType string, ids []string, deleted bool) error {
+	for _, id := range ids {
+		if err := recordChange(sess, guid, entityType, id, deleted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordUserChange adds a change of the user to the change log of his group.
+func recordUserChange(sess *xorm.Session, uid string) error {
+	u := new(User)
+	if has, err := sess.ID(uid).Cols(`group_uid`).Get(u); err != nil || !has {
+		return err
+	}
+	return recordChange(sess, u.GroupUID, SyncTypeUser, uid, false)
+}
+
+// recordMemberChange records that the user joined or left the group.
+// The group changes as well because of its members.
+func recordMemberChange(sess *xorm.Session, guid strfmt.UUID, uid string, left bool) error {
+	if err := recordChange(sess, guid, SyncTypeUser, uid, left); err != nil {
+		return err
+	}
+	return recordChange(sess, guid, SyncTypeGroup, string(guid), false)
+}
+
+// getLastSyncSeq returns the Seq of the latest committed change of the group.
+func getLastSyncSeq(guid strfmt.UUID) (int64, error) {
+	s := new(SyncSequence)
+	if _, err := x.ID(guid).Get(s); err != nil {
+		return 0, err
+	}
+	return s.Seq, nil
+}
+
+// hasChangedSince returns true if the entity was changed after the change "since".
+func hasChangedSince(guid strfmt.UUID, entityType, id string, since int64) (bool, error) {
+	return x.
+		Where(`group_uid=?`, guid).
+		And(`entity_type=?`, entityType).
+		And(`entity_id=?`, id).
+		And(`seq>?`, since).
+		Exist(new(SyncChange))
+}
+
+// encodeSyncCursor returns the cursor for the changes of the group up to "seq".
+func encodeSyncCursor(guid strfmt.UUID, seq int64) string {
+	return string(guid) + ":" + strconv.FormatInt(seq, 10)
+}
+
+// parseSyncCursor returns the group and the change Seq of a cursor.
+func parseSyncCursor(cursor string) (strfmt.UUID, int64, error) {
+	i := strings.LastIndex(cursor, ":")
+	if i < 0 {
+		return "", 0, ErrSyncInvalidCursor{Cursor: cursor}
+	}
+	id, err := strconv.ParseInt(cursor[i+1:], 10, 64)
+	if err != nil || id < 0 {
+		return "", 0, ErrSyncInvalidCursor{Cursor: cursor}
+	}
+	return strfmt.UUID(cursor[:i]), id, nil
+}
+
+// SyncTombstone tombstone of a deleted entity or a removed member
+// swagger:model SyncTombstone
+type SyncTombstone struct {
+	// type (one of ListItem, Bill, Group, User)
+	// Required: true
+	Type string `json:"type"`
+
+	// id
+	// Required: true
+	ID string `json:"id"`
+}
+
+// Validate validates this sync tombstone
+func (m *SyncTombstone) Validate(formats strfmt.Registry) error {
+	if err := validate.RequiredString("type", "body", m.Type); err != nil {
+		return err
+	}
+	if err := validate.RequiredString("id", "body", m.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *SyncTombstone) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *SyncTombstone) UnmarshalBinary(b []byte) error {
+	var res SyncTombstone
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// SyncChanges sync changes
+// swagger:model SyncChanges
+type SyncChanges struct {
+	// cursor for the next sync request
+	// Required: true
+	// Read Only: true
+	Cursor string `json:"cursor"`
+
+	// full is true if the changes are a snapshot of all data. The client
+	// has to replace its local data.
+	// Read Only: true
+	Full bool `json:"full"`
+
+	// has more is true if there are more changes than fit into one response.
+	// Read Only: true
+	HasMore bool `json:"hasMore"`
+
+	// group (only set if it changed)
+	// Read Only: true
+	Group *Group `json:"group,omitempty"`
+
+	// users
+	// Read Only: true
+	Users []*User `json:"users"`
+
+	// list items
+	// Read Only: true
+	ListItems []*ListItem `json:"listItems"`
+
+	// bills
+	// Read Only: true
+	Bills []*Bill `json:"bills"`
+
+	// tombstones of deleted entities and removed members
+	// Read Only: true
+	Tombstones []*SyncTombstone `json:"tombstones"`
+}
+
+// Validate validates this sync changes
+func (m *SyncChanges) Validate(formats strfmt.Registry) error {
+	if err := validate.RequiredString("cursor", "body", m.Cursor); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *SyncChanges) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *SyncChanges) UnmarshalBinary(b []byte) error {
+	var res SyncChanges
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+func newSyncChanges(guid strfmt.UUID, cursor int64) *SyncChanges {
+	return &SyncChanges{
+		Cursor:     encodeSyncCursor(guid, cursor),
+		Users:      []*User{},
+		ListItems:  []*ListItem{},
+		Bills:      []*Bill{},
+		Tombstones: []*SyncTombstone{},
+	}
+}
+
+// GetSyncChanges returns the changes of the group since the cursor for
+// the user "uid". Each changed entity is returned once with its current
+// state or as a tombstone. Without a cursor, or with a cursor of another
+// group, a snapshot of all data is returned.
+func GetSyncChanges(g *Group, uid, cursor string) (*SyncChanges, error) {
+	if cursor == "" {
+		return getSyncSnapshot(g, uid)
+	}
+
+	guid, since, err := parseSyncCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if guid != g.UID {
+		// The user changed the group
+		return getSyncSnapshot(g, uid)
+	}
+
+	if last, err := getLastSyncSeq(g.UID); err != nil {
+		return nil, err
+	} else if since > last {
+		return nil, ErrSyncInvalidCursor{Cursor: cursor}
+	}
+
+	changes := make([]*SyncChange, 0, SyncPageSize)
+	if err := x.
+		Where(`group_uid=?`, g.UID).
+		And(`seq>?`, since).
+		Asc(`seq`).
+		Limit(SyncPageSize).
+		Find(&changes); err != nil {
+		return nil, err
+	}
+
+	result := newSyncChanges(g.UID, since)
+	if len(changes) == 0 {
+		return result, nil
+	}
+	result.Cursor = encodeSyncCursor(g.UID, changes[len(changes)-1].Seq)
+	result.HasMore = len(changes) == SyncPageSize
+
+	// Only the latest change of each entity matters
+	var (
+		ids     = make(map[string][]string)
+		deleted = make(map[string]bool)
+	)
+	for _, c := range changes {
+		key := c.EntityType + ":" + c.EntityID
+		if _, seen := deleted[key]; !seen {
+			ids[c.EntityType] = append(ids[c.EntityType], c.EntityID)
+		}
+		deleted[key] = c.Deleted
+	}
+
+	// Returns the entities that weren't deleted according to the changes.
+	// Entities that don't exist anymore become tombstones.
+	existing := func(entityType string) []string {
+		alive := make([]string, 0, len(ids[entityType]))
+		for _, id := range ids[entityType] {
+			if deleted[entityType+":"+id] {
+				result.Tombstones = append(result.Tombstones, &SyncTombstone{Type: entityType, ID: id})
+			} else {
+				alive = append(alive, id)
+			}
+		}
+		return alive
+	}
+	found := func(entityType string, wanted []string, got map[string]bool) {
+		for _, id := range wanted {
+			if !got[id] {
+				result.Tombstones = append(result.Tombstones, &SyncTombstone{Type: entityType, ID: id})
+			}
+		}
+	}
+
+	if groupIDs := existing(SyncTypeGroup); len(groupIDs) > 0 {
+		result.Group = g
+	}
+
+	if userIDs := existing(SyncTypeUser); len(userIDs) > 0 {
+		users, err := getSyncUsers(g, uid, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		got := make(map[string]bool)
+		for _, u := range users {
+			got[*u.UID] = true
+		}
+		result.Users = users
+		found(SyncTypeUser, userIDs, got)
+	}
+
+	if itemIDs := existing(SyncTypeListItem); len(itemIDs) > 0 {
+		if err := x.
+			Where(`group_uid=?`, g.UID).
+			In(`id`, itemIDs).
+			Find(&result.ListItems); err != nil {
+			return nil, err
+		}
+		got := make(map[string]bool)
+		for _, item := range result.ListItems {
+			got[string(item.ID)] = true
+		}
+		found(SyncTypeListItem, itemIDs, got)
+	}
+
+	if billIDs := existing(SyncTypeBill); len(billIDs) > 0 {
+		if err := x.
+			Where(`group_uid=?`, g.UID).
+			In(`uid`, billIDs).
+			Find(&result.Bills); err != nil {
+			return nil, err
+		}
+		got := make(map[string]bool)
+		for _, b := range result.Bills {
+			if err := b.loadItemsAndSum(); err != nil {
+				return nil, err
+			}
+			got[string(b.UID)] = true
+		}
+		found(SyncTypeBill, billIDs, got)
+	}
+
+	return result, nil
+}
+
+// getSyncSnapshot returns all list items, bills and members of the group.
+func getSyncSnapshot(g *Group, uid string) (*SyncChanges, error) {
+	// Read the cursor first, so that changes during the
+	// snapshot are returned by the next sync again.
+	last, err := getLastSyncSeq(g.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newSyncChanges(g.UID, last)
+	result.Full = true
+	result.Group = g
+
+	if result.Users, err = getSyncUsers(g, uid, g.Members); err != nil {
+		return nil, err
+	}
+	if err = x.Where(`group_uid=?`, g.UID).Find(&result.ListItems); err != nil {
+		return nil, err
+	}
+	if result.Bills, err = GetBillsByGroupUIDWithBoughtItems(g.UID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// getSyncUsers returns the members of the group with one of the uids. Private
+// data is only returned for the user "uid".
+func getSyncUsers(g *Group, uid string, uids []string) ([]*User, error) {
+	users := make([]*User, 0, len(uids))
+	if len(uids) == 0 {
+		return users, nil
+	}
+	if err := x.Where(`group_uid=?`, g.UID).In(`uid`, uids).Find(&users); err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		u.PhotoURL = strfmt.URI(GetUserImageURL(*u.UID))
+		if *u.UID != uid {
+			u.FirebaseInstanceID = ""
+			u.MailOptOut = nil
+		}
+	}
+	return users, nil
+}
+
+// SyncMutation offline change of an entity
+// swagger:model SyncMutation
+type SyncMutation struct {
+	// id chosen by the client to match the result
+	// Required: true
+	// Max Length: 64
+	ID string `json:"id"`
+
+	// type (only ListItem)
+	// Required: true
+	Type string `json:"type"`
+
+	// op (one of create, update, delete, buy, revert)
+	// Required: true
+	Op string `json:"op"`
+
+	// entity ID (chosen by the client for new items)
+	// Required: true
+	EntityID strfmt.UUID `json:"entityID"`
+
+	// list item (required to create or update an item)
+	ListItem *ListItem `json:"listItem,omitempty"`
+}
+
+var syncMutationOpEnum = []interface{}{SyncOpCreate, SyncOpUpdate, SyncOpDelete, SyncOpBuy, SyncOpRevert}
+
+// Validate validates this sync mutation
+func (m *SyncMutation) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := validate.RequiredString("id", "body", m.ID); err != nil {
+		res = append(res, err)
+	} else if err := validate.MaxLength("id", "body", m.ID, 64); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.RequiredString("type", "body", m.Type); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.RequiredString("op", "body", m.Op); err != nil {
+		res = append(res, err)
+	} else if err := validate.Enum("op", "body", m.Op, syncMutationOpEnum); err != nil {
+		res = append(res, err)
+	}
+	if err := validate.RequiredString("entityID", "body", string(m.EntityID)); err != nil {
+		res = append(res, err)
+	} else if err := validate.FormatOf("entityID", "body", "uuid", m.EntityID.String(), formats); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *SyncMutation) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *SyncMutation) UnmarshalBinary(b []byte) error {
+	var res SyncMutation
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// SyncMutationBatch batch of offline mutations
+// swagger:model SyncMutationBatch
+type SyncMutationBatch struct {
+	// cursor of the last sync before the mutations were made
+	// Required: true
+	Cursor string `json:"cursor"`
+
+	// mutations (applied in order)
+	// Required: true
+	// Max Items: 100
+	Mutations []*SyncMutation `json:"mutations"`
+}
+
+// Validate validates this sync mutation batch
+func (m *SyncMutationBatch) Validate(formats strfmt.Registry) error {
+	if err := validate.RequiredString("cursor", "body", m.Cursor); err != nil {
+		return err
+	}
+	if err := validate.Required("mutations", "body", m.Mutations); err != nil {
+		return err
+	}
+	if err := validate.MaxItems("mutations", "body", int64(len(m.Mutations)), SyncMaxMutations); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Mutations); i++ {
+		if err := validate.Required("mutations"+"."+strconv.Itoa(i), "body", m.Mutations[i]); err != nil {
+			return err
+		}
+		if err := m.Mutations[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("mutations" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *SyncMutationBatch) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *SyncMutationBatch) UnmarshalBinary(b []byte) error {
+	var res SyncMutationBatch
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// SyncMutationResult result of an offline mutation
+// swagger:model SyncMutationResult
+type SyncMutationResult struct {
+	// id of the mutation
+	// Required: true
+	// Read Only: true
+	ID string `json:"id"`
+
+	// status (one of applied, conflict, rejected)
+	// Required: true
+	// Read Only: true
+	Status string `json:"status"`
+
+	// message why the mutation was rejected
+	// Read Only: true
+	Message string `json:"message,omitempty"`
+
+	// deleted is true if the entity does not exist (anymore)
+	// Read Only: true
+	Deleted bool `json:"deleted,omitempty"`
+
+	// list item (the server's state of the item)
+	// Read Only: true
+	ListItem *ListItem `json:"listItem,omitempty"`
+}
+
+// Validate validates this sync mutation result
+func (m *SyncMutationResult) Validate(formats strfmt.Registry) error {
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *SyncMutationResult) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *SyncMutationResult) UnmarshalBinary(b []byte) error {
+	var res SyncMutationResult
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// SyncMutationResults sync mutation results
+// swagger:model SyncMutationResults
+type SyncMutationResults struct {
+	// results in the order of the mutations
+	// Required: true
+	// Read Only: true
+	Results []*SyncMutationResult `json:"results"`
+}
+
+// Validate validates this sync mutation results
+func (m *SyncMutationResults) Validate(formats strfmt.Registry) error {
+	return validate.Required("results", "body", m.Results)
+}
+
+// MarshalBinary interface implementation
+func (m *SyncMutationResults) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *SyncMutationResults) UnmarshalBinary(b []byte) error {
+	var res SyncMutationResults
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// ApplySyncMutations applies the offline mutations of the user in order. An
+// entity that was changed by someone else since the cursor of the batch is
+// not changed but returned as conflict. Mutations are applied independently
+// of each other. On an error, the results of the mutations that were
+// applied before are returned as well.
+func ApplySyncMutations(u *User, g *Group, batch *SyncMutationBatch) ([]*SyncMutationResult, error) {
+	guid, since, err := parseSyncCursor(batch.Cursor)
+	if err != nil {
+		return nil, err
+	} else if guid != g.UID {
+		return nil, ErrSyncInvalidCursor{Cursor: batch.Cursor}
+	}
+
+	// Entities changed by this batch are not conflicts of later mutations.
+	touched := make(map[strfmt.UUID]bool)
+
+	results := make([]*SyncMutationResult, 0, len(batch.Mutations))
+	for _, m := range batch.Mutations {
+		r, err := applySyncMutation(u, g, since, touched, m)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func applySyncMutation(u *User, g *Group, since int64, touched map[strfmt.UUID]bool, m *SyncMutation) (*SyncMutationResult, error) {
+	r := &SyncMutationResult{ID: m.ID, Status: SyncStatusApplied}
+	reject := func(msg string) (*SyncMutationResult, error) {
+		r.Status = SyncStatusRejected
+		r.Message = msg
+		return r, nil
+	}
+
+	if m.Type != SyncTypeListItem {
+		return reject("only list items can be changed offline")
+	}
+
+	item, err := GetListItemByUIDs(g.UID, m.EntityID)
+	if IsErrListItemNotExist(err) {
+		item = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	conflict := func() (*SyncMutationResult, error) {
+		r.Status = SyncStatusConflict
+		if r.ListItem, err = GetListItemByUIDs(g.UID, m.EntityID); IsErrListItemNotExist(err) {
+			r.ListItem = nil
+			r.Deleted = true
+		} else if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	// Conflicts
+	if m.Op == SyncOpCreate && item != nil {
+		r.Status = SyncStatusConflict
+		r.ListItem = item
+		return r, nil
+	}
+	if m.Op != SyncOpCreate {
+		if item == nil {
+			r.Status = SyncStatusConflict
+			r.Deleted = true
+			return r, nil
+		}
+		if !touched[m.EntityID] {
+			if changed, err := hasChangedSince(g.UID, SyncTypeListItem, string(m.EntityID), since); err != nil {
+				return nil, err
+			} else if changed {
+				r.Status = SyncStatusConflict
+				r.ListItem = item
+				return r, nil
+			}
+		}
+	}
+
+	switch m.Op {
+	case SyncOpCreate, SyncOpUpdate:
+		if msg, err := validateSyncListItem(m.ListItem); err != nil {
+			return nil, err
+		} else if msg != "" {
+			return reject(msg)
+		}
+
+		l := &ListItem{
+			ID:           m.EntityID,
+			GroupUID:     g.UID,
+			Title:        m.ListItem.Title,
+			Category:     m.ListItem.Category,
+			Count:        m.ListItem.Count,
+			Price:        m.ListItem.Price,
+			PriceMode:    m.ListItem.PriceMode,
+			Unit:         m.ListItem.Unit,
+			Quantity:     m.ListItem.Quantity,
+			RequestedFor: m.ListItem.RequestedFor,
+		}
+		if m.Op == SyncOpCreate {
+			// The id must not be used by an item of another group
+			if exists, err := x.Where(`id=?`, m.EntityID).Exist(new(ListItem)); err != nil {
+				return nil, err
+			} else if exists {
+				return reject("the id is already used")
+			}
+			l.RequestedBy = *u.UID
+			err = CreateListItem(l)
+		} else {
+			err = UpdateListItemColsIfVersion(l, item.Version,
+				`title`, `category`, `count`, `price`, `price_mode`, `unit`, `quantity`, `requested_for`)
+			if IsErrVersionMismatch(err) || IsErrListItemNotExist(err) {
+				// Changed or deleted since it was read
+				return conflict()
+			}
+		}
+
+	case SyncOpDelete:
+		if item.RequestedBy != *u.UID && !g.HasAdmin(*u.UID) {
+			return reject("only the requester or an admin can delete an item")
+		}
+		if err = DeleteListItem(item); IsErrListItemNotExist(err) {
+			// Deleted since it was read
+			return conflict()
+		}
+
+	case SyncOpBuy:
+		if item.BoughtAt != nil || item.BoughtBy != "" {
+			return reject("the item was already bought")
+		}
+		err = u.BuyListItemsByUIDs([]strfmt.UUID{m.EntityID})
+
+	case SyncOpRevert:
+		err = u.RevertListItemPurchaseByUID(m.EntityID)
+	}
+
+	if IsErrListItemIsBought(err) || IsErrListItemsAlreadyBought(err) || IsErrListItemHasBill(err) {
+		return reject(err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	touched[m.EntityID] = true
+
+	// Return the new state
+	if r.ListItem, err = GetListItemByUIDs(g.UID, m.EntityID); IsErrListItemNotExist(err) {
+		r.ListItem = nil
+		r.Deleted = true
+	} else if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// validateSyncListItem returns a message if the data of a created
+// or updated list item is invalid.
+func validateSyncListItem(l *ListItem) (string, error) {
+	if l == nil {
+		return "the list item is missing", nil
+	}
+	if err := l.Validate(strfmt.Default); err != nil {
+		return err.Error(), nil
+	}
+	if len(l.RequestedFor) == 0 {
+		return "requestedFor must contain at least one user", nil
+	}
+	if exists, err := AreUsersExist(base.Unique(l.RequestedFor)); err != nil {
+		return "", err
+	} else if !exists {
+		return "a requestedFor user does not exist", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSyncChanges(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+
+	snapshot, err := GetSyncChanges(g, "1234567890fakefirebaseid0001", "")
+	assert.NoError(t, err)
+	assert.True(t, snapshot.Full)
+	assert.NotNil(t, snapshot.Group)
+	assert.Len(t, snapshot.ListItems, 5)
+	assert.Len(t, snapshot.Bills, 1)
+	if assert.Len(t, snapshot.Users, 2) {
+		for _, u := range snapshot.Users {
+			if *u.UID != "1234567890fakefirebaseid0001" {
+				assert.Empty(t, u.FirebaseInstanceID)
+			}
+		}
+	}
+
+	// Nothing changed yet
+	changes, err := GetSyncChanges(g, "1234567890fakefirebaseid0001", snapshot.Cursor)
+	assert.NoError(t, err)
+	assert.False(t, changes.Full)
+	assert.Equal(t, snapshot.Cursor, changes.Cursor)
+	assert.Nil(t, changes.Group)
+	assert.Empty(t, changes.ListItems)
+	assert.Empty(t, changes.Tombstones)
+
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	item.Title = swag.String("Pears")
+	assert.NoError(t, UpdateListItemCols(item, `title`))
+	item = AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000005"}).(*ListItem)
+	assert.NoError(t, DeleteListItem(item))
+
+	// Leaving removes the member and his unbilled purchases
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0002")}).(*User)
+	assert.NoError(t, g.Leave(u))
+
+	changes, err = GetSyncChanges(g, "1234567890fakefirebaseid0001", snapshot.Cursor)
+	assert.NoError(t, err)
+	assert.False(t, changes.Full)
+	assert.False(t, changes.HasMore)
+	assert.NotEqual(t, snapshot.Cursor, changes.Cursor)
+	assert.NotNil(t, changes.Group)
+	assert.Empty(t, changes.Users)
+	if assert.Len(t, changes.ListItems, 1) {
+		assert.Equal(t, "Pears", *changes.ListItems[0].Title)
+	}
+	assert.ElementsMatch(t, []*SyncTombstone{
+		{Type: SyncTypeListItem, ID: "00112233-4455-6677-8899-000000000005"},
+		{Type: SyncTypeListItem, ID: "00112233-4455-6677-8899-000000000004"},
+		{Type: SyncTypeUser, ID: "1234567890fakefirebaseid0002"},
+	}, changes.Tombstones)
+
+	// The new cursor has no changes
+	next, err := GetSyncChanges(g, "1234567890fakefirebaseid0001", changes.Cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, changes.Cursor, next.Cursor)
+	assert.Empty(t, next.ListItems)
+	assert.Empty(t, next.Tombstones)
+
+	// Other groups don't see the changes
+	other, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeef0")
+	assert.NoError(t, err)
+	AssertCount(t, &SyncChange{GroupUID: other.UID}, 0)
+}
+
+func TestGetSyncChangesCursor(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+
+	for _, cursor := range []string{"abc", string(g.UID) + ":-1", string(g.UID) + ":x", string(g.UID) + ":999999999"} {
+		_, err = GetSyncChanges(g, "1234567890fakefirebaseid0001", cursor)
+		assert.True(t, IsErrSyncInvalidCursor(err), cursor)
+	}
+
+	// The cursor of another group returns a snapshot
+	changes, err := GetSyncChanges(g, "1234567890fakefirebaseid0001", "00112233-4455-6677-8899-aabbccddeef0:0")
+	assert.NoError(t, err)
+	assert.True(t, changes.Full)
+	assert.Len(t, changes.ListItems, 5)
+}
+
+func TestRecordChangeSeq(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	const (
+		guid  = strfmt.UUID("00112233-4455-6677-8899-aabbccddeeff")
+		other = strfmt.UUID("00112233-4455-6677-8899-aabbccddeef0")
+	)
+	assert.NoError(t, withTx(func(sess *xorm.Session) error {
+		if err := recordChange(sess, guid, SyncTypeListItem, "a", false); err != nil {
+			return err
+		}
+		if err := recordChange(sess, other, SyncTypeListItem, "b", false); err != nil {
+			return err
+		}
+		return recordChange(sess, guid, SyncTypeListItem, "c", true)
+	}))
+
+	// Each group has its own sequence
+	AssertExistsAndLoadBean(t, &SyncChange{GroupUID: guid, EntityID: "a", Seq: 1})
+	AssertExistsAndLoadBean(t, &SyncChange{GroupUID: guid, EntityID: "c", Seq: 2})
+	AssertExistsAndLoadBean(t, &SyncChange{GroupUID: other, EntityID: "b", Seq: 1})
+	AssertExistsAndLoadBean(t, &SyncSequence{GroupUID: guid, Seq: 2})
+
+	// A rolled back change doesn't use up a Seq
+	assert.Error(t, withTx(func(sess *xorm.Session) error {
+		if err := recordChange(sess, guid, SyncTypeListItem, "d", false); err != nil {
+			return err
+		}
+		return errTestFailpoint
+	}))
+	AssertExistsAndLoadBean(t, &SyncSequence{GroupUID: guid, Seq: 2})
+
+	g, err := GetGroupByUID(guid)
+	assert.NoError(t, err)
+	changes, err := GetSyncChanges(g, "1234567890fakefirebaseid0001", string(guid)+":1")
+	assert.NoError(t, err)
+	assert.Equal(t, string(guid)+":2", changes.Cursor)
+}
+
+func TestApplySyncMutations(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeeff")
+	assert.NoError(t, err)
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0001")}).(*User)
+
+	snapshot, err := GetSyncChanges(g, *u.UID, "")
+	assert.NoError(t, err)
+
+	// Someone else changes an item after the snapshot
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000005"}).(*ListItem)
+	item.Title = swag.String("Green apples")
+	assert.NoError(t, UpdateListItemCols(item, `title`))
+
+	newItem := &ListItem{
+		Title:        swag.String("Bread"),
+		Category:     swag.String("Groceries"),
+		Count:        swag.Int64(1),
+		RequestedFor: []string{"1234567890fakefirebaseid0001"},
+	}
+	results, err := ApplySyncMutations(u, g, &SyncMutationBatch{
+		Cursor: snapshot.Cursor,
+		Mutations: []*SyncMutation{
+			{ID: "1", Type: SyncTypeListItem, Op: SyncOpCreate, EntityID: "00112233-4455-6677-8899-000000000010", ListItem: newItem},
+			{ID: "2", Type: SyncTypeListItem, Op: SyncOpBuy, EntityID: "00112233-4455-6677-8899-000000000010"},
+			{ID: "3", Type: SyncTypeListItem, Op: SyncOpUpdate, EntityID: "00112233-4455-6677-8899-000000000005", ListItem: newItem},
+			{ID: "4", Type: SyncTypeListItem, Op: SyncOpDelete, EntityID: "00112233-4455-6677-8899-000000000099"},
+			{ID: "5", Type: SyncTypeListItem, Op: SyncOpDelete, EntityID: "00112233-4455-6677-8899-000000000004"},
+			{ID: "6", Type: SyncTypeListItem, Op: SyncOpRevert, EntityID: "00112233-4455-6677-8899-000000000001"},
+			{ID: "7", Type: SyncTypeListItem, Op: SyncOpUpdate, EntityID: "00112233-4455-6677-8899-000000000002"},
+			{ID: "8", Type: SyncTypeBill, Op: SyncOpDelete, EntityID: "00112233-4455-6677-8899-123000000001"},
+			{ID: "9", Type: SyncTypeListItem, Op: SyncOpCreate, EntityID: "00112233-4455-6677-8899-000000000002", ListItem: newItem},
+		},
+	})
+	assert.NoError(t, err)
+	if !assert.Len(t, results, 9) {
+		return
+	}
+
+	// Created and bought in the same batch
+	assert.Equal(t, SyncStatusApplied, results[0].Status)
+	assert.Equal(t, SyncStatusApplied, results[1].Status)
+	if assert.NotNil(t, results[1].ListItem) {
+		assert.Equal(t, "Bread", *results[1].ListItem.Title)
+		assert.Equal(t, *u.UID, results[1].ListItem.BoughtBy)
+	}
+	created := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000010"}).(*ListItem)
+	assert.Equal(t, *u.UID, created.RequestedBy)
+
+	// Changed by someone else
+	assert.Equal(t, SyncStatusConflict, results[2].Status)
+	if assert.NotNil(t, results[2].ListItem) {
+		assert.Equal(t, "Green apples", *results[2].ListItem.Title)
+	}
+
+	// Deleted item
+	assert.Equal(t, SyncStatusConflict, results[3].Status)
+	assert.True(t, results[3].Deleted)
+
+	// Bought and billed items
+	assert.Equal(t, SyncStatusRejected, results[4].Status)
+	assert.Equal(t, SyncStatusRejected, results[5].Status)
+
+	// Missing data and other types
+	assert.Equal(t, SyncStatusRejected, results[6].Status)
+	assert.Equal(t, SyncStatusRejected, results[7].Status)
+
+	// Item exists already
+	assert.Equal(t, SyncStatusConflict, results[8].Status)
+	assert.NotNil(t, results[8].ListItem)
+
+	AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000004"})
+
+	// The batch needs a cursor of the group
+	_, err = ApplySyncMutations(u, g, &SyncMutationBatch{Cursor: "00112233-4455-6677-8899-aabbccddeef0:0"})
+	assert.True(t, IsErrSyncInvalidCursor(err))
+}
+
+func TestApplySyncMutationsUsedID(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	g, err := GetGroupByUID("00112233-4455-6677-8899-aabbccddeef0")
+	assert.NoError(t, err)
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0004")}).(*User)
+
+	snapshot, err := GetSyncChanges(g, *u.UID, "")
+	assert.NoError(t, err)
+
+	// The id belongs to an item of another group
+	results, err := ApplySyncMutations(u, g, &SyncMutationBatch{
+		Cursor: snapshot.Cursor,
+		Mutations: []*SyncMutation{
+			{ID: "1", Type: SyncTypeListItem, Op: SyncOpCreate, EntityID: "00112233-4455-6677-8899-000000000002", ListItem: &ListItem{
+				Title:        swag.String("Bread"),
+				Category:     swag.String("Groceries"),
+				Count:        swag.Int64(1),
+				RequestedFor: []string{*u.UID},
+			}},
+		},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, SyncStatusRejected, results[0].Status)
+		assert.Nil(t, results[0].ListItem)
+	}
+	AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002", GroupUID: "00112233-4455-6677-8899-aabbccddeeff"})
+}
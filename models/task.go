@@ -0,0 +1,489 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/base"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
+)
+
+// Actions of task completions
+const (
+	TaskActionDone    = "done"
+	TaskActionSkipped = "skipped"
+	TaskActionSwapped = "swapped"
+)
+
+// Task task
+// swagger:model Task
+type Task struct {
+	// id
+	// Read Only: true
+	ID strfmt.UUID `xorm:"pk VARCHAR(36)" json:"id,omitempty"`
+
+	// group UID
+	// Read Only: true
+	GroupUID strfmt.UUID `xorm:"VARCHAR(36) INDEX" json:"groupUID,omitempty"`
+
+	// title
+	// Required: true
+	// Max Length: 100
+	Title *string `xorm:"NOT NULL" json:"title"`
+
+	// description
+	// Max Length: 500
+	Description string `xorm:"TEXT" json:"description,omitempty"`
+
+	// recurrence (one of daily, weekly, monthly)
+	// Required: true
+	Recurrence *string `xorm:"VARCHAR(16) NOT NULL" json:"recurrence"`
+
+	// interval of the recurrence, e.g. 2 with "weekly" means every two weeks
+	// Minimum: 1
+	Interval int64 `xorm:"DEFAULT 1" json:"interval,omitempty"`
+
+	// rotation (ordered user ids, defaults to all group members)
+	Rotation []string `xorm:"TEXT" json:"rotation"`
+
+	// assignee
+	// Read Only: true
+	Assignee string `xorm:"VARCHAR(28) INDEX" json:"assignee,omitempty"`
+
+	// due at
+	DueAt *time.Time `xorm:"NULL" json:"dueAt,omitempty"`
+
+	// upcoming assignments
+	// Read Only: true
+	Upcoming []*TaskAssignment `xorm:"-" json:"upcoming,omitempty"`
+
+	// created by
+	// Read Only: true
+	CreatedBy string `xorm:"VARCHAR(28)" json:"createdBy,omitempty"`
+
+	// created at
+	// Read Only: true
+	CreatedAt strfmt.DateTime `xorm:"created" json:"createdAt,omitempty"`
+
+	// updated at
+	// Read Only: true
+	UpdatedAt strfmt.DateTime `xorm:"updated" json:"updatedAt,omitempty"`
+
+	// version (incremented on every change of the turn or rotation)
+	// Read Only: true
+	Version int64 `xorm:"NOT NULL DEFAULT 1" json:"version,omitempty"`
+}
+
+// Validate validates this task
+func (m *Task) Validate(formats strfmt.Registry) error {
+	var res []error
+	if err := m.validateTitle(formats); err != nil {
+		res = append(res, err)
+	}
+	if err := m.validateDescription(formats); err != nil {
+		res = append(res, err)
+	}
+	if err := m.validateRecurrence(formats); err != nil {
+		res = append(res, err)
+	}
+	if err := m.validateInterval(formats); err != nil {
+		res = append(res, err)
+	}
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *Task) validateTitle(formats strfmt.Registry) error {
+	if err := validate.Required("title", "body", m.Title); err != nil {
+		return err
+	}
+	if err := validate.MaxLength("title", "body", string(*m.Title), 100); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Task) validateDescription(formats strfmt.Registry) error {
+	if swag.IsZero(m.Description) { // not required
+		return nil
+	}
+	if err := validate.MaxLength("description", "body", string(m.Description), 500); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Task) validateRecurrence(formats strfmt.Registry) error {
+	if err := validate.Required("recurrence", "body", m.Recurrence); err != nil {
+		return err
+	}
+	if err := validate.Enum("recurrence", "body", *m.Recurrence, listItemTemplateRecurrenceEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Task) validateInterval(formats strfmt.Registry) error {
+	if swag.IsZero(m.Interval) { // not required
+		return nil
+	}
+	if err := validate.MinimumInt("interval", "body", int64(m.Interval), 1, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *Task) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *Task) UnmarshalBinary(b []byte) error {
+	var res Task
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// TaskAssignment task assignment
+// swagger:model TaskAssignment
+type TaskAssignment struct {
+	// user UID
+	// Required: true
+	UserUID *string `json:"userUID"`
+
+	// due at
+	DueAt *time.Time `json:"dueAt,omitempty"`
+}
+
+// Validate validates this task assignment
+func (m *TaskAssignment) Validate(formats strfmt.Registry) error {
+	if err := validate.Required("userUID", "body", m.UserUID); err != nil {
+		return errors.CompositeValidationError(err)
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *TaskAssignment) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *TaskAssignment) UnmarshalBinary(b []byte) error {
+	var res TaskAssignment
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// TaskList task list
+// swagger:model TaskList
+type TaskList struct {
+	// count
+	// Required: true
+	// Read Only: true
+	Count int64 `json:"count"`
+
+	// tasks
+	// Required: true
+	// Read Only: true
+	Tasks []*Task `json:"tasks"`
+}
+
+// Validate validates this task list
+func (m *TaskList) Validate(formats strfmt.Registry) error {
+	if err := validate.Required("tasks", "body", m.Tasks); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Tasks); i++ {
+		if m.Tasks[i] == nil {
+			continue
+		}
+		if err := m.Tasks[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("tasks" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *TaskList) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *TaskList) UnmarshalBinary(b []byte) error {
+	var res TaskList
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// nextDueAfter returns the point in time after "from" when the task is due again.
+func (m *Task) nextDueAfter(from time.Time) time.Time {
+	t := ListItemTemplate{Recurrence: m.Recurrence, Interval: m.Interval}
+	return t.NextDueAfter(from)
+}
+
+// nextInRotation returns the member that follows "uid" in the rotation.
+// If "uid" is not part of the rotation, the first member is returned.
+func (m *Task) nextInRotation(uid string) string {
+	if len(m.Rotation) == 0 {
+		return ""
+	}
+	for i, r := range m.Rotation {
+		if r == uid {
+			return m.Rotation[(i+1)%len(m.Rotation)]
+		}
+	}
+	return m.Rotation[0]
+}
+
+// LoadUpcoming calculates the next "count" assignments of the task.
+func (m *Task) LoadUpcoming(count int) {
+	m.Upcoming = make([]*TaskAssignment, 0, count)
+	if m.Assignee == "" {
+		return
+	}
+
+	uid, due := m.Assignee, m.DueAt
+	for i := 0; i < count; i++ {
+		m.Upcoming = append(m.Upcoming, &TaskAssignment{
+			UserUID: swag.String(uid),
+			DueAt:   due,
+		})
+		uid = m.nextInRotation(uid)
+		if due != nil {
+			due = swag.Time(m.nextDueAfter(*due))
+		}
+	}
+}
+
+// updateTurn stores the task's assignee, due date and rotation and records the completion.
+// ErrVersionMismatch is returned if the turn was changed since the task was read.
+func (m *Task) updateTurn(c *TaskCompletion) error {
+	err := withTx(func(sess *xorm.Session) error {
+		n, err := updateVersioned(sess.ID(m.ID), m, m.Version, `assignee`, `due_at`, `rotation`)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			return ErrVersionMismatch{Type: "task", ID: string(m.ID), Version: m.Version}
+		}
+
+		if err := failpoint("Task.updateTurn"); err != nil {
+			return err
+		}
+
+		_, err = sess.InsertOne(c)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	m.Version++
+	return nil
+}
+
+// newCompletion returns a completion of the current turn.
+func (m *Task) newCompletion(action, by string) *TaskCompletion {
+	return &TaskCompletion{
+		TaskUID:     m.ID,
+		GroupUID:    m.GroupUID,
+		UserUID:     m.Assignee,
+		CompletedBy: by,
+		Action:      action,
+		DueAt:       m.DueAt,
+	}
+}
+
+// Done marks the current turn as done. The next member of the
+// rotation is assigned and the task is scheduled again.
+func (m *Task) Done(by string) error {
+	if m.Assignee == "" {
+		return ErrTaskNoAssignee{ID: m.ID}
+	}
+
+	c := m.newCompletion(TaskActionDone, by)
+
+	m.Assignee = m.nextInRotation(m.Assignee)
+	if m.DueAt != nil {
+		m.DueAt = swag.Time(m.nextDueAfter(*m.DueAt))
+	}
+
+	return m.updateTurn(c)
+}
+
+// Skip skips the turn of the current assignee. The next member of the
+// rotation takes over the turn (the due date doesn't change).
+func (m *Task) Skip(by string) error {
+	if m.Assignee == "" {
+		return ErrTaskNoAssignee{ID: m.ID}
+	}
+
+	c := m.newCompletion(TaskActionSkipped, by)
+	m.Assignee = m.nextInRotation(m.Assignee)
+
+	return m.updateTurn(c)
+}
+
+// Swap swaps the turn of the current assignee with the given user. The user
+// takes over the current turn and the assignee takes over the user's position
+// in the rotation.
+func (m *Task) Swap(uid, by string) error {
+	if m.Assignee == "" {
+		return ErrTaskNoAssignee{ID: m.ID}
+	}
+	if !base.StringInSlice(uid, m.Rotation) {
+		return ErrTaskNotInRotation{ID: m.ID, UserUID: uid}
+	}
+	if uid == m.Assignee {
+		return nil
+	}
+
+	c := m.newCompletion(TaskActionSwapped, by)
+
+	for i, r := range m.Rotation {
+		switch r {
+		case uid:
+			m.Rotation[i] = m.Assignee
+		case m.Assignee:
+			m.Rotation[i] = uid
+		}
+	}
+	m.Assignee = uid
+
+	return m.updateTurn(c)
+}
+
+// GetHistory returns the completions of the task (newest first).
+func (m *Task) GetHistory() ([]*TaskCompletion, error) {
+	completions := make([]*TaskCompletion, 0, 10)
+	err := x.
+		Where(`task_uid=?`, m.ID).
+		Desc(`created_at`, `id`).
+		Find(&completions)
+	return completions, err
+}
+
+// CreateTask inserts a new task. The first member of the rotation is assigned.
+// If no rotation is given, all group members take turns.
+func CreateTask(t *Task) error {
+	members, err := GetGroupMemberUIDs(t.GroupUID)
+	if err != nil {
+		return err
+	}
+
+	if len(t.Rotation) == 0 {
+		t.Rotation = members
+	}
+	t.Rotation = base.Unique(t.Rotation)
+	for _, uid := range t.Rotation {
+		if !base.StringInSlice(uid, members) {
+			return ErrTaskNotInRotation{ID: t.ID, UserUID: uid}
+		}
+	}
+
+	if t.Interval < 1 {
+		t.Interval = 1
+	}
+	if t.DueAt == nil {
+		t.DueAt = swag.Time(time.Now().UTC())
+	}
+	if len(t.Rotation) > 0 {
+		t.Assignee = t.Rotation[0]
+	}
+
+	_, err = x.InsertOne(t)
+	return err
+}
+
+// GetTaskByUIDs returns the task with the given uid that belongs to the group.
+func GetTaskByUIDs(guid, tuid strfmt.UUID) (*Task, error) {
+	t := new(Task)
+
+	if has, err := x.Where(`group_uid=?`, guid).And(`id=?`, tuid).Get(t); err != nil {
+		return nil, err
+
+	} else if !has {
+		return nil, ErrTaskNotExist{ID: tuid, GroupUID: guid}
+	}
+
+	return t, nil
+}
+
+// GetTasksByGroupUID returns all tasks of the group ordered by their due date.
+func GetTasksByGroupUID(guid strfmt.UUID) ([]*Task, error) {
+	tasks := make([]*Task, 0, 10)
+	err := x.
+		Where(`group_uid=?`, guid).
+		Asc(`due_at`, `title`).
+		Find(&tasks)
+	return tasks, err
+}
+
+// GetTasksAssignedTo returns all tasks of the group that are assigned to the user.
+func GetTasksAssignedTo(guid strfmt.UUID, uid string) ([]*Task, error) {
+	tasks := make([]*Task, 0, 5)
+	err := x.
+		Where(`group_uid=?`, guid).
+		And(`assignee=?`, uid).
+		Find(&tasks)
+	return tasks, err
+}
+
+// removeUserFromTaskRotations removes the user from the rotations of all tasks
+// of the group. Tasks assigned to the user are assigned to the next member.
+func removeUserFromTaskRotations(sess *xorm.Session, guid strfmt.UUID, uid string) error {
+	tasks := make([]*Task, 0, 5)
+	if err := sess.Where(`group_uid=?`, guid).Find(&tasks); err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		if !base.StringInSlice(uid, t.Rotation) && t.Assignee != uid {
+			continue
+		}
+
+		if t.Assignee == uid {
+			t.Assignee = t.nextInRotation(uid)
+			if t.Assignee == uid {
+				t.Assignee = ""
+			}
+		}
+		t.Rotation = base.RemoveStringFromSlice(t.Rotation, uid)
+
+		if _, err := sess.ID(t.ID).Cols(`assignee`, `rotation`).Incr(`version`).Update(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/go-openapi/validate"
+)
+
+// TaskCompletion task completion
+// swagger:model TaskCompletion
+type TaskCompletion struct {
+	// id
+	// Read Only: true
+	ID int64 `xorm:"pk autoincr" json:"id,omitempty"`
+
+	// task UID
+	// Read Only: true
+	TaskUID strfmt.UUID `xorm:"VARCHAR(36) INDEX" json:"taskUID,omitempty"`
+
+	// group UID
+	// Read Only: true
+	GroupUID strfmt.UUID `xorm:"VARCHAR(36) INDEX" json:"groupUID,omitempty"`
+
+	// user whose turn it was
+	// Read Only: true
+	UserUID string `xorm:"VARCHAR(28)" json:"userUID,omitempty"`
+
+	// user that completed, skipped or swapped the turn
+	// Read Only: true
+	CompletedBy string `xorm:"VARCHAR(28)" json:"completedBy,omitempty"`
+
+	// action (one of done, skipped, swapped)
+	// Read Only: true
+	Action string `xorm:"VARCHAR(16)" json:"action,omitempty"`
+
+	// due at
+	// Read Only: true
+	DueAt *time.Time `xorm:"NULL" json:"dueAt,omitempty"`
+
+	// created at
+	// Read Only: true
+	CreatedAt strfmt.DateTime `xorm:"created" json:"createdAt,omitempty"`
+}
+
+// Validate validates this task completion
+func (m *TaskCompletion) Validate(formats strfmt.Registry) error {
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *TaskCompletion) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *TaskCompletion) UnmarshalBinary(b []byte) error {
+	var res TaskCompletion
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
+
+// TaskHistory task history
+// swagger:model TaskHistory
+type TaskHistory struct {
+	// count
+	// Required: true
+	// Read Only: true
+	Count int64 `json:"count"`
+
+	// completions
+	// Required: true
+	// Read Only: true
+	Completions []*TaskCompletion `json:"completions"`
+}
+
+// Validate validates this task history
+func (m *TaskHistory) Validate(formats strfmt.Registry) error {
+	if err := validate.Required("completions", "body", m.Completions); err != nil {
+		return err
+	}
+	for i := 0; i < len(m.Completions); i++ {
+		if m.Completions[i] == nil {
+			continue
+		}
+		if err := m.Completions[i].Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("completions" + "." + strconv.Itoa(i))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary interface implementation
+func (m *TaskHistory) MarshalBinary() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return swag.WriteJSON(m)
+}
+
+// UnmarshalBinary interface implementation
+func (m *TaskHistory) UnmarshalBinary(b []byte) error {
+	var res TaskHistory
+	if err := swag.ReadJSON(b, &res); err != nil {
+		return err
+	}
+	*m = res
+	return nil
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTaskUID1 = strfmt.UUID("00112233-4455-6677-8899-789000000001")
+	testTaskUID2 = strfmt.UUID("00112233-4455-6677-8899-789000000002")
+)
+
+func TestTask_LoadUpcoming(t *testing.T) {
+	due := time.Date(2018, 1, 1, 18, 0, 0, 0, time.UTC)
+	task := Task{
+		Recurrence: swag.String(RecurrenceWeekly),
+		Rotation:   []string{"a", "b", "c"},
+		Assignee:   "b",
+		DueAt:      &due,
+	}
+	task.LoadUpcoming(4)
+
+	assert.Len(t, task.Upcoming, 4)
+	assert.Equal(t, "b", *task.Upcoming[0].UserUID)
+	assert.Equal(t, "c", *task.Upcoming[1].UserUID)
+	assert.Equal(t, "a", *task.Upcoming[2].UserUID)
+	assert.Equal(t, "b", *task.Upcoming[3].UserUID)
+	assert.Equal(t, due.AddDate(0, 0, 14), *task.Upcoming[2].DueAt)
+
+	empty := Task{Recurrence: swag.String(RecurrenceDaily)}
+	empty.LoadUpcoming(3)
+	assert.Empty(t, empty.Upcoming)
+}
+
+func TestCreateTask(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	task1 := &Task{
+		ID:         "00112233-4455-6677-8899-789000000003",
+		GroupUID:   testGroupUID,
+		Title:      swag.String("Vacuum"),
+		Recurrence: swag.String(RecurrenceWeekly),
+	}
+	assert.NoError(t, CreateTask(task1))
+	assert.Len(t, task1.Rotation, 2)
+	assert.Equal(t, task1.Rotation[0], task1.Assignee)
+	assert.Equal(t, int64(1), task1.Interval)
+	assert.NotNil(t, task1.DueAt)
+
+	task2 := &Task{
+		ID:         "00112233-4455-6677-8899-789000000004",
+		GroupUID:   testGroupUID,
+		Title:      swag.String("Vacuum"),
+		Recurrence: swag.String(RecurrenceWeekly),
+		Rotation:   []string{"1234567890fakefirebaseid0003"},
+	}
+	assert.True(t, IsErrTaskNotInRotation(CreateTask(task2)))
+
+	tasks, err := GetTasksByGroupUID(testGroupUID)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 3)
+}
+
+func TestTask_Done(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	task, err := GetTaskByUIDs(testGroupUID, testTaskUID1)
+	assert.NoError(t, err)
+	due := *task.DueAt
+
+	assert.NoError(t, task.Done("1234567890fakefirebaseid0001"))
+	assert.Equal(t, "1234567890fakefirebaseid0002", task.Assignee)
+	assert.Equal(t, due.AddDate(0, 0, 7).Unix(), task.DueAt.Unix())
+
+	AssertExistsAndLoadBean(t, &Task{ID: testTaskUID1, Assignee: "1234567890fakefirebaseid0002"})
+
+	history, err := task.GetHistory()
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, TaskActionDone, history[0].Action)
+	assert.Equal(t, "1234567890fakefirebaseid0001", history[0].UserUID)
+}
+
+func TestTask_DoneConcurrently(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// Two members read the task and press "Done"
+	task, err := GetTaskByUIDs(testGroupUID, testTaskUID1)
+	assert.NoError(t, err)
+	stale, err := GetTaskByUIDs(testGroupUID, testTaskUID1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, task.Done("1234567890fakefirebaseid0001"))
+	assert.True(t, IsErrVersionMismatch(stale.Done("1234567890fakefirebaseid0002")))
+
+	// The turn advanced only once
+	AssertExistsAndLoadBean(t, &Task{ID: testTaskUID1, Assignee: "1234567890fakefirebaseid0002"})
+	history, err := task.GetHistory()
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestTask_SkipAndSwap(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	task, err := GetTaskByUIDs(testGroupUID, testTaskUID1)
+	assert.NoError(t, err)
+	due := *task.DueAt
+
+	assert.NoError(t, task.Skip("1234567890fakefirebaseid0001"))
+	assert.Equal(t, "1234567890fakefirebaseid0002", task.Assignee)
+	assert.Equal(t, due.Unix(), task.DueAt.Unix())
+
+	assert.True(t, IsErrTaskNotInRotation(task.Swap("1234567890fakefirebaseid0003", "1234567890fakefirebaseid0002")))
+
+	assert.NoError(t, task.Swap("1234567890fakefirebaseid0001", "1234567890fakefirebaseid0002"))
+	assert.Equal(t, "1234567890fakefirebaseid0001", task.Assignee)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0002", "1234567890fakefirebaseid0001"}, task.Rotation)
+
+	history, err := task.GetHistory()
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+}
+
+func TestUser_LeaveGroupAdjustsRotation(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	u, err := GetUserByUID("1234567890fakefirebaseid0001")
+	assert.NoError(t, err)
+	assert.NoError(t, u.LeaveGroup())
+
+	task1, err := GetTaskByUIDs(testGroupUID, testTaskUID1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1234567890fakefirebaseid0002"}, task1.Rotation)
+	assert.Equal(t, "1234567890fakefirebaseid0002", task1.Assignee)
+
+	u2, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+	assert.NoError(t, u2.LeaveGroup())
+
+	task2, err := GetTaskByUIDs(testGroupUID, testTaskUID2)
+	assert.NoError(t, err)
+	assert.Empty(t, task2.Rotation)
+	assert.Empty(t, task2.Assignee)
+	assert.True(t, IsErrTaskNoAssignee(task2.Done("1234567890fakefirebaseid0002")))
+}
@@ -1,11 +1,24 @@
 package models
 
 import (
+	"github.com/go-xorm/core"
 	"gopkg.in/testfixtures.v2"
 )
 
 var fixtures *testfixtures.Context
 
+// FixturesHelper returns the testfixtures helper for the database of the engine
+func FixturesHelper() testfixtures.Helper {
+	switch x.Dialect().DBType() {
+	case core.MYSQL:
+		return &testfixtures.MySQL{}
+	case core.POSTGRES:
+		return &testfixtures.PostgreSQL{}
+	default:
+		return &testfixtures.SQLite{}
+	}
+}
+
 // InitFixtures initialize test fixtures for a test database
 func InitFixtures(helper testfixtures.Helper, dir string) (err error) {
 	testfixtures.SkipDatabaseNameCheck(true) // TODO
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"sync"
+
+	"github.com/go-xorm/xorm"
+)
+
+// withTx runs fn inside a database transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise.
+func withTx(fn func(sess *xorm.Session) error) error {
+	sess := x.NewSession()
+	defer sess.Close()
+
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	if err := fn(sess); err != nil {
+		sess.Rollback()
+		return err
+	}
+
+	return sess.Commit()
+}
+
+var (
+	failpointsMutex sync.RWMutex
+	failpoints      = make(map[string]error)
+)
+
+// EnableFailpoint makes multi-step operations fail with "err" when they reach
+// the failpoint "name". It is used by tests to check that transactions are
+// rolled back and must never be used in production.
+func EnableFailpoint(name string, err error) {
+	failpointsMutex.Lock()
+	defer failpointsMutex.Unlock()
+	failpoints[name] = err
+}
+
+// DisableFailpoints disables all failpoints.
+func DisableFailpoints() {
+	failpointsMutex.Lock()
+	defer failpointsMutex.Unlock()
+	failpoints = make(map[string]error)
+}
+
+// failpoint returns the error of the failpoint "name" if it is enabled.
+func failpoint(name string) error {
+	failpointsMutex.RLock()
+	defer failpointsMutex.RUnlock()
+	return failpoints[name]
+}
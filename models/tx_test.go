@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-openapi/swag"
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestFailpoint = errors.New("failpoint")
+
+func TestWithTx(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	uid := "1234567890fakefirebaseid0003"
+
+	// Rollback
+	err := withTx(func(sess *xorm.Session) error {
+		if _, err := sess.ID(uid).Cols(`group_uid`).Update(&User{GroupUID: testGroupUID}); err != nil {
+			return err
+		}
+		return errTestFailpoint
+	})
+	assert.Equal(t, errTestFailpoint, err)
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String(uid)}).(*User)
+	assert.Empty(t, u.GroupUID)
+
+	// Commit
+	err = withTx(func(sess *xorm.Session) error {
+		_, err := sess.ID(uid).Cols(`group_uid`).Update(&User{GroupUID: testGroupUID})
+		return err
+	})
+	assert.NoError(t, err)
+	u = AssertExistsAndLoadBean(t, &User{UID: swag.String(uid)}).(*User)
+	assert.Equal(t, testGroupUID, u.GroupUID)
+}
+
+func TestCreateBillForUserRollback(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	EnableFailpoint("CreateBillForUser", errTestFailpoint)
+	defer DisableFailpoints()
+
+	u, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+
+	_, err = CreateBillForUser(u, &Bill{BoughtItems: []string{"00112233-4455-6677-8899-000000000004"}})
+	assert.Equal(t, errTestFailpoint, err)
+
+	AssertCount(t, &Bill{}, 1)
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000004"}).(*ListItem)
+	assert.Empty(t, item.BillUID)
+}
+
+func TestUser_LeaveGroupRollback(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	EnableFailpoint("User.LeaveGroup", errTestFailpoint)
+	defer DisableFailpoints()
+
+	u, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+	assert.Equal(t, errTestFailpoint, u.LeaveGroup())
+	assert.Equal(t, testGroupUID, u.GroupUID)
+
+	// Nothing was changed
+	u = AssertExistsAndLoadBean(t, &User{UID: u.UID}).(*User)
+	assert.Equal(t, testGroupUID, u.GroupUID)
+	AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000004"})
+	task := AssertExistsAndLoadBean(t, &Task{ID: testTaskUID1}).(*Task)
+	assert.Contains(t, task.Rotation, *u.UID)
+}
+
+func TestUser_LeaveGroupKeepsOtherItems(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	u, err := GetUserByUID("1234567890fakefirebaseid0002")
+	assert.NoError(t, err)
+	assert.NoError(t, u.LeaveGroup())
+
+	// Unbilled purchases of the user are removed, other items are kept
+	AssertNotExistsBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000004"})
+	AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"})
+	AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000005"})
+}
+
+func TestBill_PayRollbackOnRecompute(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	EnableFailpoint("RecomputeGroupBalances", errTestFailpoint)
+	defer DisableFailpoints()
+
+	b, err := GetBillByUIDs(testGroupUID, testBillUID)
+	assert.NoError(t, err)
+	assert.Equal(t, errTestFailpoint, b.Pay("1234567890fakefirebaseid0002"))
+
+	// The payment isn't stored without its balances
+	b = AssertExistsAndLoadBean(t, &Bill{UID: testBillUID}).(*Bill)
+	assert.Equal(t, BillStatePartial, *b.State)
+	assert.Len(t, b.PayedBy, 1)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"os"
 	"testing"
 
 	"github.com/go-xorm/core"
@@ -9,21 +10,32 @@ import (
 	"gopkg.in/testfixtures.v2"
 )
 
-// CreateTestEngine create in-memory sqlite database for unit tests
+// CreateTestEngine create in-memory sqlite database for unit tests.
+// Set $WGPLANER_TEST_DB_DRIVER ("sqlite3", "mysql" or "postgres") and
+// $WGPLANER_TEST_DB_DSN to run the tests against another database.
+// All tables of that database are dropped!
 // Any package that calls this must import github.com/mattn/go-sqlite3
 func CreateTestEngine(fixturesDir string) error {
+	driver, dataSource := "sqlite3", "file::memory:?cache=shared"
+	if d := os.Getenv("WGPLANER_TEST_DB_DRIVER"); d != "" {
+		driver, dataSource = d, os.Getenv("WGPLANER_TEST_DB_DSN")
+	}
+
 	var err error
-	x, err = xorm.NewEngine("sqlite3", "file::memory:?cache=shared")
+	x, err = xorm.NewEngine(driver, dataSource)
 	if err != nil {
 		return err
 	}
 	x.SetMapper(core.GonicMapper{})
+	if err = x.DropTables(tables...); err != nil {
+		return err
+	}
 	if err = x.StoreEngine("InnoDB").Sync2(tables...); err != nil {
 		return err
 	}
 	x.ShowSQL(true)
 
-	return InitFixtures(&testfixtures.SQLite{}, fixturesDir)
+	return InitFixtures(FixturesHelper(), fixturesDir)
 }
 
 // PrepareTestDatabase load test fixtures into test database
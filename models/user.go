@@ -19,6 +19,7 @@ import (
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/go-openapi/validate"
+	"github.com/go-xorm/xorm"
 	"github.com/nfnt/resize"
 	"github.com/op/go-logging"
 )
@@ -51,6 +52,9 @@ type User struct {
 	// locale
 	Locale string `xorm:"VARCHAR(5)" json:"locale,omitempty"`
 
+	// mail types the user doesn't want to receive
+	MailOptOut []string `xorm:"TEXT" json:"mailOptOut,omitempty"`
+
 	// photo Url
 	PhotoURL strfmt.URI `xorm:"-" json:"photoUrl,omitempty"`
 
@@ -65,6 +69,10 @@ type User struct {
 	// updated at
 	// Read Only: true
 	UpdatedAt strfmt.DateTime `xorm:"updated" json:"updatedAt,omitempty"`
+
+	// version (incremented on every change, used as ETag)
+	// Read Only: true
+	Version int64 `xorm:"NOT NULL DEFAULT 1" json:"version,omitempty"`
 }
 
 // Validate validates this user
@@ -76,6 +84,9 @@ func (u *User) Validate(formats strfmt.Registry) error {
 	if err := u.validateFirebaseInstanceID(formats); err != nil {
 		res = append(res, err)
 	}
+	if err := u.validateMailOptOut(formats); err != nil {
+		res = append(res, err)
+	}
 	if err := u.validateUID(formats); err != nil {
 		res = append(res, err)
 	}
@@ -151,104 +162,210 @@ func (u *User) IsAdmin() bool {
 }
 
 func (u *User) LeaveGroup() error {
+	if err := withTx(u.leaveGroup); err != nil {
+		return err
+	}
+	u.GroupUID = ""
+	return nil
+}
+
+// leaveGroup removes the user and his unbilled purchases from his group.
+func (u *User) leaveGroup(sess *xorm.Session) error {
 	// Delete "bought" with no bill.
-	x.Where(``).
-		Where(`bought_by=?`, *u.UID).
-		And(`bill_uid IS NULL OR bill_uid=""`).
-		Delete(&ListItem{})
+	var boughtIDs []string
+	if err := sess.Table(new(ListItem)).
+		Where(`group_uid=?`, u.GroupUID).
+		And(`bought_by=?`, *u.UID).
+		And(`(bill_uid IS NULL OR bill_uid = ?)`, "").
+		Cols(`id`).
+		Find(&boughtIDs); err != nil {
+		return err
+	}
+	if _, err := sess.
+		Where(`group_uid=?`, u.GroupUID).
+		And(`bought_by=?`, *u.UID).
+		And(`(bill_uid IS NULL OR bill_uid = ?)`, "").
+		Delete(&ListItem{}); err != nil {
+		return err
+	}
+	if len(boughtIDs) > 0 {
+		if _, err := sess.In(`list_item_uid`, boughtIDs).Delete(new(Purchase)); err != nil {
+			return err
+		}
+	}
+	if err := recordChanges(sess, u.GroupUID, SyncTypeListItem, boughtIDs, true); err != nil {
+		return err
+	}
 
-	u.GroupUID = ""
+	// Remove the user from all chore rotations.
+	if err := removeUserFromTaskRotations(sess, u.GroupUID, *u.UID); err != nil {
+		return err
+	}
+
+	if err := failpoint("User.LeaveGroup"); err != nil {
+		return err
+	}
 
-	return UpdateUserCols(u, "group_uid")
+	if _, err := sess.ID(*u.UID).Cols(`group_uid`).Incr(`version`).Update(&User{GroupUID: ""}); err != nil {
+		return err
+	}
+	return recordMemberChange(sess, u.GroupUID, *u.UID, true)
 }
 
 func (u *User) JoinGroupWithCode(groupCode string) (*Group, error) {
 	groupLog.Debugf(`User "%s" joins a group with code "%s"`, *u.UID, groupCode)
 
-	var (
-		exists  bool
-		err     error
-		g       *Group
-		theCode *GroupCode
-	)
+	var groupUID strfmt.UUID
+
+	err := withTx(func(sess *xorm.Session) error {
+		// Check the code inside the transaction so that codes that
+		// were invalidated in the meantime can't be used.
+		// Codes that aren't shared group codes may be invite tokens.
+		theCode := new(GroupCode)
+		if has, err := sess.ID(groupCode).Get(theCode); err != nil {
+			return err
+		} else if !has {
+			if groupUID, err = useGroupInvite(sess, groupCode, *u.UID); err != nil {
+				return err
+			}
+		} else if !theCode.isUsableAt(time.Now()) {
+			return ErrGroupCodeNotExist{Code: groupCode}
+		} else if err = useGroupCode(sess, groupCode); err != nil {
+			return err
+		} else {
+			groupUID = *theCode.GroupUID
+		}
 
-	// Check the code and get the group uid
-	if exists, theCode = IsGroupCodeValid(groupCode); !exists {
-		return nil, ErrGroupCodeNotExist{Code: groupCode}
-	}
+		// Check group
+		if has, err := sess.ID(groupUID).Exist(new(Group)); err != nil {
+			return err
+		} else if !has {
+			return ErrGroupNotExist{UID: groupUID}
+		}
 
-	// Check group
-	if g, err = GetGroupByUID(*theCode.GroupUID); err != nil {
-		return nil, err
-	} else if g == nil {
-		return nil, ErrGroupNotExist{UID: *theCode.GroupUID}
-	}
+		if err := failpoint("User.JoinGroupWithCode"); err != nil {
+			return err
+		}
 
-	// user joins the group.
-	u.GroupUID = *theCode.GroupUID
-	if _, err := x.ID(*u.UID).Update(u); err != nil {
+		// user joins the group.
+		if _, err := sess.ID(*u.UID).Cols(`group_uid`).Incr(`version`).Update(&User{GroupUID: groupUID}); err != nil {
+			return err
+		}
+		if u.GroupUID != "" && u.GroupUID != groupUID {
+			if err := recordMemberChange(sess, u.GroupUID, *u.UID, true); err != nil {
+				return err
+			}
+		}
+		return recordMemberChange(sess, groupUID, *u.UID, false)
+	})
+	if err != nil {
 		return nil, err
 	}
+	u.GroupUID = groupUID
 
 	// get updated group
-	if g, err = GetGroupByUID(g.UID); err != nil {
-		return nil, err
-	}
-
-	return g, nil
+	return GetGroupByUID(groupUID)
 }
 
+// BuyListItemsByUIDs marks the given list items as bought by the user. Either
+// all items are bought or none: if some of them were bought in the meantime,
+// ErrListItemsAlreadyBought with their current state is returned.
 func (u *User) BuyListItemsByUIDs(itemUIDs []strfmt.UUID) error {
-	// TODO: Test if already bought
+	ids := make([]string, 0, len(itemUIDs))
+	seen := make(map[strfmt.UUID]bool, len(itemUIDs))
+	for _, id := range itemUIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, string(id))
+		}
+	}
 
-	// Check if items exist
-	count, errCount := x.Where(`group_uid=?`, u.GroupUID).
-		In(`id`, itemUIDs).
-		Count(new(ListItem))
+	err := withTx(func(sess *xorm.Session) error {
+		// Check if items exist
+		count, errCount := sess.Where(`group_uid=?`, u.GroupUID).
+			In(`id`, ids).
+			Count(new(ListItem))
 
-	if errCount != nil {
-		return errCount
-	} else if int(count) != len(itemUIDs) {
-		return ErrListItemNotExist{}
-	}
+		if errCount != nil {
+			return errCount
+		} else if int(count) != len(ids) {
+			return ErrListItemNotExist{}
+		}
 
-	_, err := x.Cols(`bought_by`, `bought_at`).
-		Where(`group_uid=?`, u.GroupUID).
-		In(`id`, itemUIDs).
-		Update(&ListItem{
-			BoughtAt: swag.Time(time.Now().UTC()),
-			BoughtBy: *u.UID,
-		})
+		// Only items that are still unbought are updated, so that
+		// concurrent purchases of the same item can't both succeed.
+		now := time.Now().UTC()
+		affected, err := sess.Cols(`bought_by`, `bought_at`).
+			Where(`group_uid=?`, u.GroupUID).
+			And(`bought_at IS NULL`).
+			In(`id`, ids).
+			Incr(`version`).
+			Update(&ListItem{
+				BoughtAt: &now,
+				BoughtBy: *u.UID,
+			})
+		if err != nil {
+			return err
+		} else if int(affected) != len(ids) {
+			return ErrListItemsAlreadyBought{GroupUID: u.GroupUID}
+		}
+
+		items := make([]*ListItem, 0, len(ids))
+		if err := sess.Where(`group_uid=?`, u.GroupUID).In(`id`, ids).Find(&items); err != nil {
+			return err
+		}
+		if err := createPurchases(sess, items, *u.UID, now); err != nil {
+			return err
+		}
+
+		return recordChanges(sess, u.GroupUID, SyncTypeListItem, ids, false)
+	})
+
+	if IsErrListItemsAlreadyBought(err) {
+		// The transaction was rolled back, so only the purchases of others are left.
+		return getAlreadyBoughtError(u.GroupUID, ids)
+	}
 	return err
 }
 
 // RevertListItemPurchaseByUID reverts the buying action for given list items.
 func (u *User) RevertListItemPurchaseByUID(itemUID strfmt.UUID) error {
-	var item ListItem
-	// Check if items exist
-	found, errCount := x.Where(`group_uid=?`, u.GroupUID).
-		And(`id=?`, itemUID).
-		Get(&item)
-
-	if errCount != nil {
-		return errCount
-
-	} else if !found {
-		return ErrListItemNotExist{ID: itemUID, GroupUID: u.GroupUID}
-	}
+	return withTx(func(sess *xorm.Session) error {
+		var item ListItem
+		// Check if items exist
+		found, errCount := sess.Where(`group_uid=?`, u.GroupUID).
+			And(`id=?`, itemUID).
+			Get(&item)
+
+		if errCount != nil {
+			return errCount
+
+		} else if !found {
+			return ErrListItemNotExist{ID: itemUID, GroupUID: u.GroupUID}
+		}
 
-	if item.BillUID != "" {
-		return ErrListItemHasBill{ID: itemUID, GroupUID: u.GroupUID}
-	}
+		if item.BillUID != "" {
+			return ErrListItemHasBill{ID: itemUID, GroupUID: u.GroupUID}
+		}
 
-	_, err := x.Cols(`bought_by`, `bought_at`).
-		Where(`group_uid=?`, u.GroupUID).
-		And(`id=?`, itemUID).
-		Update(&ListItem{
-			BoughtAt: nil,
-			BoughtBy: "",
-		})
-	return err
+		if _, err := sess.Cols(`bought_by`, `bought_at`).
+			Where(`group_uid=?`, u.GroupUID).
+			And(`id=?`, itemUID).
+			Incr(`version`).
+			Update(&ListItem{
+				BoughtAt: nil,
+				BoughtBy: "",
+			}); err != nil {
+			return err
+		}
+		if _, err := sess.Where(`list_item_uid=?`, itemUID).Delete(new(Purchase)); err != nil {
+			return err
+		}
+		if err := recordChange(sess, u.GroupUID, SyncTypeListItem, string(itemUID), false); err != nil {
+			return err
+		}
+		return recomputeGroupBalances(sess, u.GroupUID)
+	})
 }
 
 func IsValidUserIDFormat(uid string) bool {
@@ -289,6 +406,28 @@ func AreUsersExist(uids []string) (bool, error) {
 	return true, nil
 }
 
+// GetUsersByUIDs returns all existing users with one of the uids.
+func GetUsersByUIDs(uids []string) ([]*User, error) {
+	users := make([]*User, 0, len(uids))
+	if len(uids) == 0 {
+		return users, nil
+	}
+	err := x.In(`uid`, uids).Find(&users)
+	return users, err
+}
+
+// ClearFirebaseInstanceIDs removes the given firebase instance ids
+// from all users, e.g. because the devices are no longer registered.
+func ClearFirebaseInstanceIDs(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := x.In(`firebase_instance_id`, ids).
+		Cols(`firebase_instance_id`).
+		Update(&User{})
+	return err
+}
+
 func CreateUser(u *User) error {
 	// Validate using builder
 	userBuilder := UserBuilder{*u}
@@ -317,18 +456,66 @@ func GetUserByUID(uid string) (*User, error) {
 
 func UpdateUser(u *User) error {
 	u.DisplayName = swag.String(strings.TrimSpace(*u.DisplayName))
-	_, err := x.ID(*u.UID).AllCols().Update(u)
-	u.PhotoURL = strfmt.URI(GetUserImageURL(*u.UID))
-	return err
+	return UpdateUserColsIfVersion(u, 0)
 }
 
 func UpdateUserCols(u *User, cols ...string) error {
+	return UpdateUserColsIfVersion(u, 0, cols...)
+}
+
+// UpdateUserColsIfVersion updates the columns of the user if he still has
+// the given version. A version of 0 matches every version.
+func UpdateUserColsIfVersion(u *User, version int64, cols ...string) error {
 	u.DisplayName = swag.String(strings.TrimSpace(swag.StringValue(u.DisplayName)))
-	_, err := x.ID(*u.UID).Cols(cols...).Update(u)
+	err := withTx(func(sess *xorm.Session) error {
+		n, err := updateVersioned(sess.ID(*u.UID), u, version, cols...)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			if has, err := sess.ID(*u.UID).Exist(new(User)); err != nil {
+				return err
+			} else if !has {
+				return ErrUserNotExist{UID: *u.UID}
+			}
+			return ErrVersionMismatch{Type: "user", ID: *u.UID, Version: version}
+		}
+		return recordUserChange(sess, *u.UID)
+	})
 	u.PhotoURL = strfmt.URI(GetUserImageURL(*u.UID))
 	return err
 }
 
+// DeleteUser removes the user from their group and deletes the user. The
+// group is deleted as well if the user was its last member.
+func DeleteUser(u *User) error {
+	if u.GroupUID != "" {
+		g, err := GetGroupByUID(u.GroupUID)
+		if err != nil && !IsErrGroupNotExist(err) {
+			return err
+		}
+
+		if g != nil {
+			if err = g.Leave(u); err != nil {
+				return err
+			}
+			if len(g.Members) == 0 {
+				if err = DeleteGroup(g); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if _, err := x.ID(*u.UID).Delete(new(User)); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(path.Dir(GetUserImagePath(*u.UID))); err != nil {
+		userLog.Errorf(`Can't remove image directory of user "%s": %s`, *u.UID, err)
+	}
+	return nil
+}
+
 func GetUserImagePath(uid string) string {
 	return path.Join(
 		setting.AppWorkPath,
@@ -401,7 +588,7 @@ func (u *User) GetBoughtItems() (ShoppingList, error) {
 	// Check if items exist
 	err := x.AllCols().
 		Where(`bought_by=?`, *u.UID).
-		And(`bill_uid IS NULL OR bill_uid=""`).
+		And(`(bill_uid IS NULL OR bill_uid = ?)`, "").
 		Find(&items)
 
 	if err != nil {
@@ -467,6 +654,11 @@ func (u *UserBuilder) SetLocale(loc string) {
 	u.user.Locale = loc
 }
 
+// SetMailOptOut sets the mail types the user doesn't want to receive
+func (u *UserBuilder) SetMailOptOut(types []string) {
+	u.user.MailOptOut = types
+}
+
 // SetPhotoURL sets the user's PhotoURL
 func (u *UserBuilder) SetPhotoURL(uri strfmt.URI) {
 	u.user.PhotoURL = uri
@@ -3,6 +3,7 @@ package models
 import (
 	"testing"
 
+	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/swag"
 	"github.com/stretchr/testify/assert"
 )
@@ -65,6 +66,61 @@ func TestUser_LeaveGroup(t *testing.T) {
 	assert.Empty(t, userUpdated.GroupUID)
 }
 
+func TestUser_GetBoughtItems(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// All items bought by user 1 are billed
+	uid1 := "1234567890fakefirebaseid0001"
+	user1 := AssertExistsAndLoadBean(t, &User{UID: &uid1}).(*User)
+	items, err := user1.GetBoughtItems()
+	assert.NoError(t, err)
+	assert.Len(t, items.ListItems, 0)
+
+	uid2 := "1234567890fakefirebaseid0002"
+	user2 := AssertExistsAndLoadBean(t, &User{UID: &uid2}).(*User)
+	items, err = user2.GetBoughtItems()
+	assert.NoError(t, err)
+	if assert.Len(t, items.ListItems, 1) {
+		assert.Equal(t, "Eggs", *items.ListItems[0].Title)
+	}
+}
+
+func TestUser_BuyListItemsByUIDs(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	u := AssertExistsAndLoadBean(t, &User{UID: swag.String("1234567890fakefirebaseid0001")}).(*User)
+
+	// Eggs were bought by user 2, so nothing is bought
+	err := u.BuyListItemsByUIDs([]strfmt.UUID{
+		"00112233-4455-6677-8899-000000000002",
+		"00112233-4455-6677-8899-000000000004",
+	})
+	if assert.True(t, IsErrListItemsAlreadyBought(err)) {
+		items := err.(ErrListItemsAlreadyBought).Items
+		if assert.Len(t, items, 1) {
+			assert.EqualValues(t, "00112233-4455-6677-8899-000000000004", items[0].ID)
+			assert.Equal(t, "1234567890fakefirebaseid0002", items[0].BoughtBy)
+		}
+	}
+	item := AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	assert.Nil(t, item.BoughtAt)
+
+	// Duplicate ids are bought once
+	assert.NoError(t, u.BuyListItemsByUIDs([]strfmt.UUID{
+		"00112233-4455-6677-8899-000000000002",
+		"00112233-4455-6677-8899-000000000002",
+	}))
+	item = AssertExistsAndLoadBean(t, &ListItem{ID: "00112233-4455-6677-8899-000000000002"}).(*ListItem)
+	assert.Equal(t, *u.UID, item.BoughtBy)
+
+	// Buying twice fails
+	err = u.BuyListItemsByUIDs([]strfmt.UUID{"00112233-4455-6677-8899-000000000002"})
+	assert.True(t, IsErrListItemsAlreadyBought(err))
+
+	err = u.BuyListItemsByUIDs([]strfmt.UUID{"00112233-4455-6677-8899-000000000099"})
+	assert.True(t, IsErrListItemNotExist(err))
+}
+
 func TestGetUserByUID(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	validUserIDs := []string{
@@ -101,6 +157,31 @@ func TestAreUsersExist(t *testing.T) {
 	assert.False(t, exist2)
 }
 
+func TestGetUsersByUIDs(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	users, err := GetUsersByUIDs([]string{
+		"1234567890fakefirebaseid0001",
+		"1234567890fakefirebaseid0002",
+		"1234567890fakefirebaseid9999",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	users, err = GetUsersByUIDs(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestClearFirebaseInstanceIDs(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	assert.Equal(t, 3, GetCount(t, &User{FirebaseInstanceID: "1"}))
+
+	assert.NoError(t, ClearFirebaseInstanceIDs([]string{"1"}))
+	assert.Equal(t, 0, GetCount(t, &User{FirebaseInstanceID: "1"}))
+	AssertCount(t, &User{}, 4)
+}
+
 func TestIsUserExist(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	validUserIDs := []string{
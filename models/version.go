@@ -0,0 +1,48 @@
+package models
+
+import (
+	"github.com/go-xorm/xorm"
+)
+
+// List items, groups, users, bills and tasks have a version that is incremented
+// on every change. Clients use it to detect concurrent changes.
+
+// BeforeInsert is invoked from XORM before inserting this object.
+func (l *ListItem) BeforeInsert() {
+	l.Version = 1
+}
+
+// BeforeInsert is invoked from XORM before inserting this object.
+func (g *Group) BeforeInsert() {
+	g.Version = 1
+}
+
+// BeforeInsert is invoked from XORM before inserting this object.
+func (u *User) BeforeInsert() {
+	u.Version = 1
+}
+
+// BeforeInsert is invoked from XORM before inserting this object.
+func (m *Bill) BeforeInsert() {
+	m.Version = 1
+}
+
+// BeforeInsert is invoked from XORM before inserting this object.
+func (m *Task) BeforeInsert() {
+	m.Version = 1
+}
+
+// updateVersioned updates the columns (all columns if none are given) of the
+// rows matched by the session's conditions and increments their version. If
+// version isn't 0, only rows that still have this version are updated.
+func updateVersioned(sess *xorm.Session, bean interface{}, version int64, cols ...string) (int64, error) {
+	if len(cols) == 0 {
+		sess.AllCols().Omit(`version`)
+	} else {
+		sess.Cols(cols...)
+	}
+	if version != 0 {
+		sess.And(`version=?`, version)
+	}
+	return sess.Incr(`version`).Update(bean)
+}
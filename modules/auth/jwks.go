@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultKeyMaxAge is the time keys are cached if their source doesn't say otherwise.
+	DefaultKeyMaxAge = time.Hour
+	// minKeyRefresh limits how often the keys are fetched again.
+	minKeyRefresh = time.Minute
+	// maxKeySetSize is the maximum size of a fetched JWKS document.
+	maxKeySetSize = 1 << 20
+)
+
+// KeyProvider returns the public key for a key id or nil if it does not exist.
+type KeyProvider interface {
+	Key(kid string) *rsa.PublicKey
+}
+
+// JSONWebKey is a single RSA public key of a JWKS document.
+// See https://tools.ietf.org/html/rfc7517
+type JSONWebKey struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// KeySet contains the public keys that are used to verify ID tokens.
+// The keys are indexed by their key id ("kid").
+type KeySet struct {
+	keys map[string]*rsa.PublicKey
+}
+
+// NewKeySet returns a key set for the given public keys.
+func NewKeySet(keys map[string]*rsa.PublicKey) *KeySet {
+	ks := &KeySet{keys: make(map[string]*rsa.PublicKey, len(keys))}
+	for kid, key := range keys {
+		ks.keys[kid] = key
+	}
+	return ks
+}
+
+// LoadKeySetFile reads a JWKS file from disk.
+func LoadKeySetFile(filePath string) (*KeySet, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(data)
+}
+
+// ParseKeySet parses a JWKS document. Only RSA keys are supported.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JWKS document: %v", err)
+	}
+
+	ks := &KeySet{keys: make(map[string]*rsa.PublicKey, len(doc.Keys))}
+
+	for _, jwk := range doc.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		if jwk.KeyID == "" {
+			return nil, fmt.Errorf("JWKS key without key id")
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %v", jwk.KeyID, err)
+		}
+		ks.keys[jwk.KeyID] = key
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("JWKS document does not contain any RSA keys")
+	}
+
+	return ks, nil
+}
+
+// Key returns the public key for the given key id or nil if it does not exist.
+func (ks *KeySet) Key(kid string) *rsa.PublicKey {
+	if ks == nil {
+		return nil
+	}
+	return ks.keys[kid]
+}
+
+// Len returns the number of keys in the set.
+func (ks *KeySet) Len() int {
+	if ks == nil {
+		return 0
+	}
+	return len(ks.keys)
+}
+
+// MarshalKeySet creates a JWKS document for the given public keys.
+func MarshalKeySet(keys map[string]*rsa.PublicKey) ([]byte, error) {
+	var doc struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+
+	for kid, key := range keys {
+		doc.Keys = append(doc.Keys, JSONWebKey{
+			KeyID:     kid,
+			KeyType:   "RSA",
+			Algorithm: "RS256",
+			Use:       "sig",
+			N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+
+	return json.Marshal(doc)
+}
+
+// KeySetFetcher loads a key set and returns how long it may be cached.
+type KeySetFetcher func() (*KeySet, time.Duration, error)
+
+// FetchKeySetURL returns a fetcher for a JWKS document on a web server.
+// The keys are cached as long as the "Cache-Control" header allows.
+func FetchKeySetURL(client *http.Client, url string) KeySetFetcher {
+	return func() (*KeySet, time.Duration, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, 0, fmt.Errorf("fetching JWKS failed: %s", resp.Status)
+		}
+
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+		if err != nil {
+			return nil, 0, err
+		}
+		ks, err := ParseKeySet(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		return ks, cacheMaxAge(resp.Header.Get("Cache-Control")), nil
+	}
+}
+
+// FetchKeySetFile returns a fetcher for a JWKS file. The keys are cached
+// for DefaultKeyMaxAge.
+func FetchKeySetFile(filePath string) KeySetFetcher {
+	return func() (*KeySet, time.Duration, error) {
+		ks, err := LoadKeySetFile(filePath)
+		return ks, DefaultKeyMaxAge, err
+	}
+}
+
+// cacheMaxAge returns the "max-age" of a Cache-Control header.
+func cacheMaxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return DefaultKeyMaxAge
+}
+
+// CachedKeySet caches the keys of a fetcher. The keys are fetched again
+// once they expired and when a token uses an unknown key id, but at most
+// once a minute. If fetching fails, the previous keys are kept.
+type CachedKeySet struct {
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+	// ErrorLog is called if the keys can't be fetched again.
+	ErrorLog func(err error)
+
+	fetch     KeySetFetcher
+	mutex     sync.Mutex
+	keys      *KeySet
+	fetchedAt time.Time
+	expiresAt time.Time
+}
+
+// NewCachedKeySet fetches the keys and returns a cache for them.
+func NewCachedKeySet(fetch KeySetFetcher) (*CachedKeySet, error) {
+	c := &CachedKeySet{fetch: fetch}
+	if err := c.refresh(c.now()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Key implements KeyProvider.
+func (c *CachedKeySet) Key(kid string) *rsa.PublicKey {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	if !now.Before(c.expiresAt) {
+		c.refreshLogged(now)
+	}
+
+	// The keys might have been rotated
+	key := c.keys.Key(kid)
+	if key == nil && now.Sub(c.fetchedAt) >= minKeyRefresh {
+		c.refreshLogged(now)
+		key = c.keys.Key(kid)
+	}
+	return key
+}
+
+func (c *CachedKeySet) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *CachedKeySet) refreshLogged(now time.Time) {
+	if err := c.refresh(now); err != nil && c.ErrorLog != nil {
+		c.ErrorLog(err)
+	}
+}
+
+// refresh fetches the keys. The caller has to hold the mutex.
+func (c *CachedKeySet) refresh(now time.Time) error {
+	c.fetchedAt = now
+	// Don't try again on every request if fetching fails
+	c.expiresAt = now.Add(minKeyRefresh)
+
+	keys, maxAge, err := c.fetch()
+	if err != nil {
+		return err
+	}
+	if maxAge < minKeyRefresh {
+		maxAge = minKeyRefresh
+	}
+	c.keys = keys
+	c.expiresAt = now.Add(maxAge)
+	return nil
+}
+
+func (jwk *JSONWebKey) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %v", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %v", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
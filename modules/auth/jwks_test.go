@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheMaxAge(t *testing.T) {
+	assert.Equal(t, DefaultKeyMaxAge, cacheMaxAge(""))
+	assert.Equal(t, 6*time.Hour, cacheMaxAge("public, max-age=21600, must-revalidate"))
+	assert.Equal(t, time.Duration(0), cacheMaxAge("no-cache"))
+	assert.Equal(t, DefaultKeyMaxAge, cacheMaxAge("max-age=abc"))
+}
+
+func TestCachedKeySet(t *testing.T) {
+	var (
+		oldKey  = createTestKey(t)
+		newKey  = createTestKey(t)
+		keys    = NewKeySet(map[string]*rsa.PublicKey{"old": &oldKey.PublicKey})
+		fetched = 0
+		fail    = false
+	)
+
+	c, err := NewCachedKeySet(func() (*KeySet, time.Duration, error) {
+		fetched++
+		if fail {
+			return nil, 0, errors.New("unavailable")
+		}
+		return keys, 2 * time.Hour, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetched)
+
+	now := time.Now().Add(time.Second)
+	c.Now = func() time.Time { return now }
+
+	// Cached
+	assert.Equal(t, &oldKey.PublicKey, c.Key("old"))
+	assert.Equal(t, 1, fetched)
+
+	// Unknown key ids are fetched again at most once a minute
+	keys = NewKeySet(map[string]*rsa.PublicKey{"new": &newKey.PublicKey})
+	assert.Nil(t, c.Key("new"))
+	assert.Equal(t, 1, fetched)
+
+	now = now.Add(minKeyRefresh)
+	assert.Equal(t, &newKey.PublicKey, c.Key("new"))
+	assert.Equal(t, 2, fetched)
+
+	// Expired keys are fetched again, the previous keys are kept on errors
+	fail = true
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, &newKey.PublicKey, c.Key("new"))
+	assert.Equal(t, 3, fetched)
+	assert.Equal(t, &newKey.PublicKey, c.Key("new"))
+	assert.Equal(t, 3, fetched)
+}
+
+func TestFetchKeySetURL(t *testing.T) {
+	key := createTestKey(t)
+	data, err := MarshalKeySet(map[string]*rsa.PublicKey{testKeyID: &key.PublicKey})
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	ks, maxAge, err := FetchKeySetURL(server.Client(), server.URL)()
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, ks.Key(testKeyID))
+	assert.Equal(t, time.Hour, maxAge)
+}
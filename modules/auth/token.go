@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// FirebaseIssuerPrefix is prepended to the project id to get the expected "iss" claim.
+	FirebaseIssuerPrefix = "https://securetoken.google.com/"
+
+	algorithmRS256 = "RS256"
+	maxSubjectLen  = 128
+)
+
+// Claims contains the claims of a Firebase ID token that we care about.
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	AuthTime  int64  `json:"auth_time"`
+	Email     string `json:"email,omitempty"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+// Verifier verifies signed Firebase ID tokens.
+// See https://firebase.google.com/docs/auth/admin/verify-id-tokens
+type Verifier struct {
+	Keys      KeyProvider
+	ProjectID string
+
+	// Leeway is the allowed clock skew for "iat" and "exp".
+	Leeway time.Duration
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewVerifier returns a verifier for the given Firebase project.
+func NewVerifier(keys KeyProvider, projectID string) *Verifier {
+	return &Verifier{
+		Keys:      keys,
+		ProjectID: projectID,
+		Leeway:    time.Minute,
+	}
+}
+
+// Issuer returns the expected "iss" claim.
+func (v *Verifier) Issuer() string {
+	return FirebaseIssuerPrefix + v.ProjectID
+}
+
+// Verify checks the token's signature, issuer, audience and expiry.
+// It returns the token's claims if the token is valid.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenInvalid{"token must consist of three parts"}
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, ErrTokenInvalid{"invalid header: " + err.Error()}
+	}
+	if h.Algorithm != algorithmRS256 {
+		return nil, ErrTokenInvalid{fmt.Sprintf("unexpected algorithm %q", h.Algorithm)}
+	}
+	if h.KeyID == "" {
+		return nil, ErrTokenInvalid{`token has no "kid" header`}
+	}
+
+	key := v.Keys.Key(h.KeyID)
+	if key == nil {
+		return nil, ErrTokenInvalid{fmt.Sprintf("unknown key id %q", h.KeyID)}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenInvalid{"invalid signature encoding"}
+	}
+
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+		return nil, ErrTokenInvalid{"invalid signature"}
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrTokenInvalid{"invalid claims: " + err.Error()}
+	}
+
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (v *Verifier) validateClaims(c *Claims) error {
+	var now time.Time
+	if v.Now != nil {
+		now = v.Now()
+	} else {
+		now = time.Now()
+	}
+
+	if c.ExpiresAt == 0 || now.Add(-v.Leeway).After(time.Unix(c.ExpiresAt, 0)) {
+		return ErrTokenInvalid{"token is expired"}
+	}
+	if now.Add(v.Leeway).Before(time.Unix(c.IssuedAt, 0)) {
+		return ErrTokenInvalid{"token used before issued"}
+	}
+	if c.Audience != v.ProjectID {
+		return ErrTokenInvalid{fmt.Sprintf("incorrect audience %q", c.Audience)}
+	}
+	if c.Issuer != v.Issuer() {
+		return ErrTokenInvalid{fmt.Sprintf("incorrect issuer %q", c.Issuer)}
+	}
+	if c.Subject == "" || len(c.Subject) > maxSubjectLen {
+		return ErrTokenInvalid{`invalid "sub" claim`}
+	}
+	return nil
+}
+
+// Sign creates a RS256 signed token. It is used to create tokens for tests
+// and development setups that use a local key pair.
+func Sign(key *rsa.PrivateKey, kid string, claims *Claims) (string, error) {
+	h, err := json.Marshal(header{Algorithm: algorithmRS256, KeyID: kid, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." +
+		base64.RawURLEncoding.EncodeToString(c)
+
+	hashed := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ExtractToken removes an optional "Bearer " prefix from an Authorization header value.
+func ExtractToken(headerValue string) string {
+	const prefix = "bearer "
+	headerValue = strings.TrimSpace(headerValue)
+	if len(headerValue) > len(prefix) && strings.ToLower(headerValue[:len(prefix)]) == prefix {
+		return strings.TrimSpace(headerValue[len(prefix):])
+	}
+	return headerValue
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ErrTokenInvalid represents an "invalid ID token" kind of error.
+type ErrTokenInvalid struct {
+	Reason string
+}
+
+// IsErrTokenInvalid checks if an error is a ErrTokenInvalid.
+func IsErrTokenInvalid(err error) bool {
+	_, ok := err.(ErrTokenInvalid)
+	return ok
+}
+
+func (err ErrTokenInvalid) Error() string {
+	return fmt.Sprintf("invalid ID token [%s]", err.Reason)
+}
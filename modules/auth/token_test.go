@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testProjectID = "wgplaner-test"
+	testKeyID     = "test-key"
+	testUserID    = "1234567890fakefirebaseid0001"
+)
+
+func createTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return key
+}
+
+func createTestVerifier(t *testing.T, key *rsa.PrivateKey) *Verifier {
+	data, err := MarshalKeySet(map[string]*rsa.PublicKey{testKeyID: &key.PublicKey})
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "wgplaner-jwks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "jwks.json")
+	assert.NoError(t, ioutil.WriteFile(filePath, data, 0600))
+
+	keys, err := LoadKeySetFile(filePath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return NewVerifier(keys, testProjectID)
+}
+
+func validClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		Issuer:    FirebaseIssuerPrefix + testProjectID,
+		Audience:  testProjectID,
+		Subject:   testUserID,
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key := createTestKey(t)
+	v := createTestVerifier(t, key)
+
+	token, err := Sign(key, testKeyID, validClaims())
+	assert.NoError(t, err)
+
+	claims, err := v.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, testUserID, claims.Subject)
+}
+
+func TestVerifier_VerifyInvalidClaims(t *testing.T) {
+	key := createTestKey(t)
+	v := createTestVerifier(t, key)
+
+	expired := validClaims()
+	expired.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+
+	wrongAudience := validClaims()
+	wrongAudience.Audience = "other-project"
+
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = FirebaseIssuerPrefix + "other-project"
+
+	noSubject := validClaims()
+	noSubject.Subject = ""
+
+	issuedInFuture := validClaims()
+	issuedInFuture.IssuedAt = time.Now().Add(time.Hour).Unix()
+
+	for _, c := range []*Claims{expired, wrongAudience, wrongIssuer, noSubject, issuedInFuture} {
+		token, err := Sign(key, testKeyID, c)
+		assert.NoError(t, err)
+
+		_, err = v.Verify(token)
+		assert.True(t, IsErrTokenInvalid(err), "claims %+v should be rejected", c)
+	}
+}
+
+func TestVerifier_VerifyInvalidSignature(t *testing.T) {
+	key := createTestKey(t)
+	otherKey := createTestKey(t)
+	v := createTestVerifier(t, key)
+
+	// Signed with a key that is not part of the key set
+	token1, err := Sign(otherKey, testKeyID, validClaims())
+	assert.NoError(t, err)
+	_, err = v.Verify(token1)
+	assert.True(t, IsErrTokenInvalid(err))
+
+	// Unknown key id
+	token2, err := Sign(key, "unknown-key", validClaims())
+	assert.NoError(t, err)
+	_, err = v.Verify(token2)
+	assert.True(t, IsErrTokenInvalid(err))
+
+	// Modified payload
+	token3, err := Sign(key, testKeyID, validClaims())
+	assert.NoError(t, err)
+	parts := strings.Split(token3, ".")
+	token4, _ := Sign(key, testKeyID, &Claims{Subject: "someone else"})
+	parts[1] = strings.Split(token4, ".")[1]
+	_, err = v.Verify(strings.Join(parts, "."))
+	assert.True(t, IsErrTokenInvalid(err))
+
+	// Raw user ID
+	_, err = v.Verify(testUserID)
+	assert.True(t, IsErrTokenInvalid(err))
+}
+
+func TestParseKeySet(t *testing.T) {
+	_, err1 := ParseKeySet([]byte(`invalid`))
+	assert.Error(t, err1)
+
+	_, err2 := ParseKeySet([]byte(`{"keys": []}`))
+	assert.Error(t, err2)
+
+	_, err3 := ParseKeySet([]byte(`{"keys": [{"kid": "a", "kty": "RSA", "n": "AQAB", "e": "$$"}]}`))
+	assert.Error(t, err3)
+}
+
+func TestExtractToken(t *testing.T) {
+	assert.Equal(t, "abc.def.ghi", ExtractToken("Bearer abc.def.ghi"))
+	assert.Equal(t, "abc.def.ghi", ExtractToken("bearer  abc.def.ghi"))
+	assert.Equal(t, "abc.def.ghi", ExtractToken("abc.def.ghi"))
+	assert.Equal(t, "", ExtractToken(""))
+}
@@ -0,0 +1,162 @@
+// Package eventbus distributes change events to connected clients.
+// Every push update is published on the bus, so that clients without
+// Firebase Cloud Messaging can follow the changes of their group.
+package eventbus
+
+import (
+	"sync"
+)
+
+// TypeResync tells a client that events were lost and it has to reload all data.
+const TypeResync = "Resync"
+
+const (
+	// DefaultBufferSize is the number of events that are kept for resuming streams.
+	DefaultBufferSize = 1000
+	// subscriptionBufferSize is the number of events a subscriber may lag behind
+	// before it is dropped.
+	subscriptionBufferSize = 64
+)
+
+// Event is a change notification for a set of users.
+type Event struct {
+	ID      int64    `json:"id"`
+	Type    string   `json:"type"`
+	Updated []string `json:"updated"`
+
+	receivers []string
+}
+
+// IsFor returns true if the event was sent to the user.
+func (e *Event) IsFor(userID string) bool {
+	for _, r := range e.receivers {
+		if r == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives all events of one user.
+type Subscription struct {
+	// StartID is the ID of the last event before the subscription started.
+	StartID int64
+
+	userID string
+	events chan *Event
+	bus    *Bus
+}
+
+// Events returns the channel of new events. It's closed if the subscriber
+// lags too far behind or the subscription is closed.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.unsubscribe(s)
+}
+
+// Bus is an in-process publish/subscribe hub with a bounded replay buffer.
+type Bus struct {
+	mu            sync.Mutex
+	lastID        int64
+	bufferSize    int
+	buffer        []*Event
+	subscriptions map[*Subscription]struct{}
+}
+
+// Default is the bus all push updates are published on.
+var Default = New(DefaultBufferSize)
+
+// New creates a bus that keeps the last "bufferSize" events for resuming streams.
+func New(bufferSize int) *Bus {
+	if bufferSize < 1 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Bus{
+		bufferSize:    bufferSize,
+		buffer:        make([]*Event, 0, bufferSize),
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// LastID returns the ID of the last published event.
+func (b *Bus) LastID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Publish sends an event to all subscriptions of the receivers.
+func (b *Bus) Publish(eventType string, updated []string, receivers []string) *Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e := &Event{
+		ID:        b.lastID,
+		Type:      eventType,
+		Updated:   updated,
+		receivers: append([]string{}, receivers...),
+	}
+
+	if len(b.buffer) == b.bufferSize {
+		b.buffer = append(b.buffer[:0], b.buffer[1:]...)
+	}
+	b.buffer = append(b.buffer, e)
+
+	for s := range b.subscriptions {
+		if !e.IsFor(s.userID) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			// The client is too slow. It can resume using the last received ID.
+			b.unsubscribe(s)
+		}
+	}
+
+	return e
+}
+
+// Subscribe subscribes to the events of the user. If lastEventID is set,
+// the buffered events after it are returned as well. "complete" is false
+// if some of these events aren't buffered anymore.
+func (b *Bus) Subscribe(userID string, lastEventID int64) (sub *Subscription, replay []*Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{
+		StartID: b.lastID,
+		userID:  userID,
+		events:  make(chan *Event, subscriptionBufferSize),
+		bus:     b,
+	}
+	b.subscriptions[sub] = struct{}{}
+
+	if lastEventID <= 0 {
+		return sub, nil, true
+	}
+
+	oldestID := b.lastID - int64(len(b.buffer)) + 1
+	complete = lastEventID >= oldestID-1 && lastEventID <= b.lastID
+
+	for _, e := range b.buffer {
+		if e.ID > lastEventID && e.IsFor(userID) {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay, complete
+}
+
+func (b *Bus) unsubscribe(s *Subscription) {
+	if _, ok := b.subscriptions[s]; ok {
+		delete(b.subscriptions, s)
+		close(s.events)
+	}
+}
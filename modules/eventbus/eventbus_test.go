@@ -0,0 +1,83 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus_Publish(t *testing.T) {
+	bus := New(10)
+
+	sub1, replay, complete := bus.Subscribe("user1", 0)
+	assert.Empty(t, replay)
+	assert.True(t, complete)
+	defer sub1.Close()
+
+	sub2, _, _ := bus.Subscribe("user2", 0)
+	defer sub2.Close()
+
+	e := bus.Publish("Group-Data", []string{"group1"}, []string{"user1"})
+	assert.EqualValues(t, 1, e.ID)
+	assert.EqualValues(t, 1, bus.LastID())
+
+	select {
+	case received := <-sub1.Events():
+		assert.Equal(t, e, received)
+	default:
+		assert.Fail(t, "user1 did not receive the event")
+	}
+	assert.Len(t, sub2.Events(), 0)
+}
+
+func TestBus_SubscribeReplay(t *testing.T) {
+	bus := New(3)
+
+	for i := 0; i < 4; i++ {
+		bus.Publish("ShoppingList-Add", nil, []string{"user1", "user2"})
+	}
+	bus.Publish("User-Data", nil, []string{"user2"})
+
+	// Event 3 is still buffered
+	sub, replay, complete := bus.Subscribe("user1", 2)
+	sub.Close()
+	assert.True(t, complete)
+	if assert.Len(t, replay, 2) {
+		assert.EqualValues(t, 3, replay[0].ID)
+		assert.EqualValues(t, 4, replay[1].ID)
+	}
+	assert.EqualValues(t, 5, sub.StartID)
+
+	// Event 2 was dropped from the buffer
+	_, replay, complete = bus.Subscribe("user1", 1)
+	assert.False(t, complete)
+	assert.Len(t, replay, 2)
+
+	// Unknown IDs, e.g. after a server restart
+	_, replay, complete = bus.Subscribe("user1", 100)
+	assert.False(t, complete)
+	assert.Empty(t, replay)
+
+	// Up to date
+	_, replay, complete = bus.Subscribe("user2", 5)
+	assert.True(t, complete)
+	assert.Empty(t, replay)
+}
+
+func TestBus_SlowSubscriber(t *testing.T) {
+	bus := New(10)
+	sub, _, _ := bus.Subscribe("user1", 0)
+
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		bus.Publish("ShoppingList-Add", nil, []string{"user1"})
+	}
+
+	count := 0
+	for range sub.Events() {
+		count++
+	}
+	assert.Equal(t, subscriptionBufferSize, count)
+
+	// Closing a dropped subscription is a no-op
+	sub.Close()
+}
@@ -1,24 +1,17 @@
 package mailer
 
 import (
-	"context"
-
 	"github.com/wgplaner/wg_planer_server/models"
-	"github.com/wgplaner/wg_planer_server/modules/setting"
+	"github.com/wgplaner/wg_planer_server/modules/eventbus"
+	"github.com/wgplaner/wg_planer_server/modules/notification"
 
-	"github.com/acoshift/go-firebase-admin"
 	"github.com/op/go-logging"
 )
 
 var fireLog = logging.MustGetLogger("Fire")
 
-type (
-	PushUpdateType string
-	PushUpdateData struct {
-		Type    PushUpdateType
-		Updated []string
-	}
-)
+// PushUpdateType is the type of a push update.
+type PushUpdateType string
 
 // Push notification types
 const (
@@ -26,59 +19,38 @@ const (
 	PushUpdateGroupImage           = PushUpdateType("Group-Image")
 	PushUpdateGroupNewMember       = PushUpdateType("Group-NewMember")
 	PushUpdateGroupMemberLeft      = PushUpdateType("Group-MemberLeft")
+	PushUpdateGroupMemberRemoved   = PushUpdateType("Group-MemberRemoved")
+	PushUpdateGroupAdmins          = PushUpdateType("Group-Admins")
+	PushUpdateGroupDeleted         = PushUpdateType("Group-Deleted")
+	PushUpdateGroupRestored        = PushUpdateType("Group-Restored")
 	PushUserUpdate                 = PushUpdateType("User-Data")
 	PushUserUpdateImage            = PushUpdateType("User-Image")
 	PushShoppingListAdd            = PushUpdateType("ShoppingList-Add")
 	PushShoppingListUpdate         = PushUpdateType("ShoppingList-Update")
 	PushShoppingListBuy            = PushUpdateType("ShoppingList-Buy")
 	PushShoppingListRevertPurchase = PushUpdateType("ShoppingList-Revert-Purchase")
+	PushShoppingListDelete         = PushUpdateType("ShoppingList-Delete")
+	PushBillSent                   = PushUpdateType("Bill-Sent")
+	PushBillPayment                = PushUpdateType("Bill-Payment")
+	PushBillCancelled              = PushUpdateType("Bill-Cancelled")
+	PushTaskAssigned               = PushUpdateType("Task-Assigned")
 )
 
-func SendPushUpdateToUsers(users []*models.User, t PushUpdateType, data []string) error {
-	if setting.AppConfig.Auth.IgnoreFirebase {
-		return nil
-	}
-
-	var receiverIDs []string
+// SendPushUpdateToUsers publishes the update on the event bus and adds it
+// to the notification outbox. The outbox is delivered in the background.
+// Errors are handled by the outbox, see notification.Enqueue.
+func SendPushUpdateToUsers(users []*models.User, t PushUpdateType, data []string) {
+	userIDs := make([]string, 0, len(users))
 	for _, u := range users {
-		if u.FirebaseInstanceID == "" {
-			fireLog.Debugf(`Empty FirebaseInstanceID for user "%s"`, *u.UID)
-			continue
-		}
-		receiverIDs = append(receiverIDs, u.FirebaseInstanceID)
-	}
-
-	_, err := setting.FireBaseApp.FCM().SendToDevices(context.Background(), receiverIDs, firebase.Message{
-		Data: PushUpdateData{
-			Type:    t,
-			Updated: data,
-		},
-	})
-
-	if err != nil {
-		fireLog.Debug(`Error sending firebase update.`)
-		return err
+		userIDs = append(userIDs, *u.UID)
 	}
-
-	return nil
+	SendPushUpdateToUserIDs(userIDs, t, data)
 }
 
-func SendPushUpdateToUserIDs(receiverIDs []string, t PushUpdateType, data []string) error {
-	fireLog.Debug(`Send a firebase update data message to users (ids)`)
-
-	if setting.AppConfig.Auth.IgnoreFirebase {
-		return nil
-	}
-
-	users := make([]*models.User, 0, 10)
-
-	for _, id := range receiverIDs {
-		u, err := models.GetUserByUID(id)
-		if err != nil {
-			return err
-		}
-		users = append(users, u)
-	}
+// SendPushUpdateToUserIDs is like SendPushUpdateToUsers but takes user ids.
+func SendPushUpdateToUserIDs(receiverIDs []string, t PushUpdateType, data []string) {
+	fireLog.Debugf(`Send push update "%s" to %d users`, t, len(receiverIDs))
 
-	return SendPushUpdateToUsers(users, t, data)
+	eventbus.Default.Publish(string(t), data, receiverIDs)
+	notification.Enqueue(string(t), data, receiverIDs)
 }
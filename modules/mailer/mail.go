@@ -0,0 +1,171 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/op/go-logging"
+)
+
+var mailLog = logging.MustGetLogger("Mail")
+
+const (
+	// mailAttempts is the number of attempts to send a mail.
+	mailAttempts = 3
+	// mailRetryDelay is the delay before the first retry. It grows with each attempt.
+	mailRetryDelay = 5 * time.Second
+)
+
+// Message is an email with a plain text and an HTML body.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Bytes returns the message as multipart MIME message.
+func (m *Message) Bytes(from string, date time.Time) []byte {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+
+	writeMIMEPart(w, "text/plain", m.Text)
+	writeMIMEPart(w, "text/html", m.HTML)
+	w.Close()
+
+	return buf.Bytes()
+}
+
+func writeMIMEPart(w *multipart.Writer, contentType, body string) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	// Writing into a bytes.Buffer doesn't fail
+	part, _ := w.CreatePart(header)
+	qp := quotedprintable.NewWriter(part)
+	qp.Write([]byte(body))
+	qp.Close()
+}
+
+// sendSMTP sends the message using the SMTP server of the configuration.
+// The server only authenticates if an SMTP user is configured.
+func sendSMTP(m *Message) error {
+	cfg := setting.AppConfig.Mail
+
+	var auth smtp.Auth
+	if cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	return smtp.SendMail(
+		net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		auth,
+		cfg.SMTPIdentity,
+		m.To,
+		m.Bytes(cfg.SMTPIdentity, time.Now().UTC()),
+	)
+}
+
+var (
+	queueMutex sync.Mutex
+	queue      chan *Message
+	queueDone  chan struct{}
+)
+
+// StartMailQueue sends queued mails in the background until StopMailQueue
+// is called. Calling StartMailQueue twice has no effect.
+func StartMailQueue(size int) {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	if queue != nil {
+		return
+	}
+
+	queue = make(chan *Message, size)
+	queueDone = make(chan struct{})
+
+	go runMailQueue(queue, queueDone)
+
+	mailLog.Infof("Mail queue started (size: %d)", size)
+}
+
+// StopMailQueue sends the remaining mails and stops the queue.
+func StopMailQueue() {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	if queue == nil {
+		return
+	}
+
+	close(queue)
+	<-queueDone
+	queue, queueDone = nil, nil
+
+	mailLog.Info("Mail queue stopped")
+}
+
+func runMailQueue(queue <-chan *Message, done chan<- struct{}) {
+	defer close(done)
+
+	for m := range queue {
+		var err error
+		for attempt := 1; attempt <= mailAttempts; attempt++ {
+			if err = sendSMTP(m); err == nil {
+				break
+			}
+			if attempt < mailAttempts {
+				time.Sleep(time.Duration(attempt) * mailRetryDelay)
+			}
+		}
+		if err != nil {
+			mailLog.Errorf(`Giving up mail "%s" to %v: %s`, m.Subject, m.To, err)
+		} else {
+			mailLog.Debugf(`Sent mail "%s" to %v`, m.Subject, m.To)
+		}
+	}
+}
+
+// QueueMail adds the message to the mail queue. Mails are dropped if mails
+// are disabled, the queue isn't running or the queue is full.
+func QueueMail(m *Message) {
+	if !setting.AppConfig.Mail.Enabled {
+		mailLog.Debugf(`Mails are disabled. Dropping mail "%s"`, m.Subject)
+		return
+	}
+
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	if queue == nil {
+		mailLog.Warningf(`Mail queue isn't running. Dropping mail "%s"`, m.Subject)
+		return
+	}
+
+	select {
+	case queue <- m:
+	default:
+		mailLog.Errorf(`Mail queue is full. Dropping mail "%s"`, m.Subject)
+	}
+}
@@ -0,0 +1,313 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmlTemplate "html/template"
+	"os"
+	"path"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+)
+
+// mailGroupInvite is the template of group invites. Invites can't be
+// opted out of, so it's not one of the user's mail types.
+const mailGroupInvite = "group_invite"
+
+// defaultMailLocale is used if there are no templates for the user's locale.
+const defaultMailLocale = "en"
+
+// mailTemplateDir returns the directory of the templates for the locale, e.g. "de" or "de-AT".
+func mailTemplateDir(locale string) string {
+	base := path.Join(setting.AppWorkPath, "views/mail")
+
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang != "" {
+		if fi, err := os.Stat(path.Join(base, lang)); err == nil && fi.IsDir() {
+			return path.Join(base, lang)
+		}
+	}
+	return path.Join(base, defaultMailLocale)
+}
+
+// renderMail renders the templates "<name>.txt" and "<name>.html" of the locale.
+// The text template defines the subject in a "subject" template.
+func renderMail(name, locale string, data interface{}) (*Message, error) {
+	dir := mailTemplateDir(locale)
+
+	textTmpl, err := textTemplate.ParseFiles(path.Join(dir, name+".txt"))
+	if err != nil {
+		return nil, err
+	}
+	htmlTmpl, err := htmlTemplate.ParseFiles(path.Join(dir, name+".html"))
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, text, html bytes.Buffer
+	if err = textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err = textTmpl.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err = htmlTmpl.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// queueMailTo renders the template for the user's locale and queues the mail.
+func queueMailTo(to strfmt.Email, locale, name string, data interface{}) error {
+	m, err := renderMail(name, locale, data)
+	if err != nil {
+		mailLog.Errorf(`Can't render mail "%s": %s`, name, err)
+		return err
+	}
+	m.To = []string{string(to)}
+	QueueMail(m)
+	return nil
+}
+
+// formatAmount formats an amount in cents, e.g. "12.50 €".
+func formatAmount(cents int64, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s%d.%02d %s", sign, cents/100, cents%100, currency))
+}
+
+// formatDueDate formats the due date of a bill without time.
+func formatDueDate(b *models.Bill) string {
+	if t, err := b.DueTime(); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return b.DueDate
+}
+
+// getDisplayName returns the user's display name or the uid if the user doesn't exist.
+func getDisplayName(uid string) string {
+	if u, err := models.GetUserByUID(uid); err == nil {
+		return swag.StringValue(u.DisplayName)
+	}
+	return uid
+}
+
+type billMailData struct {
+	Name    string
+	Creator string
+	Group   string
+	Sum     string
+	DueDate string
+	Items   []string
+}
+
+func newBillMailData(b *models.Bill, g *models.Group, u *models.User, creator string) *billMailData {
+	data := &billMailData{
+		Name:    swag.StringValue(u.DisplayName),
+		Creator: creator,
+		Group:   swag.StringValue(g.DisplayName),
+		Sum:     formatAmount(b.Sum, g.Currency),
+		DueDate: formatDueDate(b),
+	}
+	for _, item := range b.BoughtListItems {
+		data.Items = append(data.Items, swag.StringValue(item.Title))
+	}
+	return data
+}
+
+// sendBillMails queues the mail for all recipients of the bill that didn't pay yet
+// and didn't opt out of the mail type.
+func sendBillMails(b *models.Bill, g *models.Group, mailType string) error {
+	users, err := models.GetUsersByUIDs(b.SentTo)
+	if err != nil {
+		return err
+	}
+
+	creator := getDisplayName(swag.StringValue(b.CreatedBy))
+	for _, u := range users {
+		if b.IsPaidBy(*u.UID) || !u.WantsMail(mailType) {
+			continue
+		}
+		if err = queueMailTo(u.Email, u.Locale, mailType, newBillMailData(b, g, u, creator)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendBillSentMails tells the recipients of a bill that it was sent to them.
+func SendBillSentMails(b *models.Bill, g *models.Group) error {
+	if !setting.AppConfig.Mail.Enabled {
+		return nil
+	}
+	return sendBillMails(b, g, models.MailTypeBillSent)
+}
+
+// SendPaymentReminders reminds the recipients of bills that are due soon
+// to pay their share. It returns the number of reminded bills.
+func SendPaymentReminders(now time.Time) (int, error) {
+	cfg := setting.AppConfig.Mail
+	if !cfg.Enabled {
+		return 0, nil
+	}
+
+	bills, err := models.GetBillsForPaymentReminder(now, now.Add(cfg.ReminderBeforeDuration))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, b := range bills {
+		g, err := models.GetGroupByUID(b.GroupUID)
+		if err != nil {
+			return i, err
+		}
+		if err = sendBillMails(b, g, models.MailTypePaymentReminder); err != nil {
+			return i, err
+		}
+		if err = b.MarkReminderSent(now); err != nil {
+			return i, err
+		}
+	}
+	return len(bills), nil
+}
+
+type digestBill struct {
+	Creator string
+	Sum     string
+	DueDate string
+}
+
+type digestMailData struct {
+	Name        string
+	Group       string
+	Since       string
+	OpenItems   []string
+	BoughtItems []string
+	OpenBills   []digestBill
+}
+
+// SendGroupDigests sends a summary of the shopping list and the open bills to the
+// members of all groups whose last digest is older than the digest interval.
+// It returns the number of groups. The first digest of a group is sent one
+// interval after the group was first seen.
+func SendGroupDigests(now time.Time) (int, error) {
+	cfg := setting.AppConfig.Mail
+	if !cfg.Enabled || cfg.DigestIntervalDuration <= 0 {
+		return 0, nil
+	}
+
+	groups, err := models.GetGroupsForDigest(now.Add(-cfg.DigestIntervalDuration))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, g := range groups {
+		if g.DigestSentAt != nil {
+			if err = sendGroupDigest(g, *g.DigestSentAt); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+		if err = g.MarkDigestSent(now); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func sendGroupDigest(g *models.Group, since time.Time) error {
+	members, err := models.GetUsersByUIDs(g.Members)
+	if err != nil {
+		return err
+	}
+	openItems, err := g.GetActiveShoppingListItems()
+	if err != nil {
+		return err
+	}
+	boughtItems, err := g.GetItemsBoughtSince(since)
+	if err != nil {
+		return err
+	}
+
+	data := digestMailData{
+		Group: swag.StringValue(g.DisplayName),
+		Since: since.Format("2006-01-02"),
+	}
+	for _, item := range openItems {
+		data.OpenItems = append(data.OpenItems, swag.StringValue(item.Title))
+	}
+	for _, item := range boughtItems {
+		data.BoughtItems = append(data.BoughtItems, swag.StringValue(item.Title))
+	}
+
+	for _, u := range members {
+		if !u.WantsMail(models.MailTypeGroupDigest) {
+			continue
+		}
+
+		bills, err := g.GetOpenBillsForUser(*u.UID)
+		if err != nil {
+			return err
+		}
+
+		userData := data
+		userData.Name = swag.StringValue(u.DisplayName)
+		userData.OpenBills = nil
+		for _, b := range bills {
+			userData.OpenBills = append(userData.OpenBills, digestBill{
+				Creator: getDisplayName(swag.StringValue(b.CreatedBy)),
+				Sum:     formatAmount(b.Sum, g.Currency),
+				DueDate: formatDueDate(b),
+			})
+		}
+
+		// Nothing happened in the group
+		if len(userData.OpenItems)+len(userData.BoughtItems)+len(userData.OpenBills) == 0 {
+			continue
+		}
+
+		if err = queueMailTo(u.Email, u.Locale, models.MailTypeGroupDigest, &userData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type inviteMailData struct {
+	Group   string
+	Inviter string
+	Code    string
+	JoinURL string
+}
+
+// SendGroupInviteMail invites the owner of the email address to the group.
+// The mail contains the code for joining the group.
+func SendGroupInviteMail(email strfmt.Email, locale string, g *models.Group, inviter *models.User, code string) error {
+	if !setting.AppConfig.Mail.Enabled {
+		return nil
+	}
+	return queueMailTo(email, locale, mailGroupInvite, &inviteMailData{
+		Group:   swag.StringValue(g.DisplayName),
+		Inviter: swag.StringValue(inviter.DisplayName),
+		Code:    code,
+		JoinURL: setting.AppConfig.Mail.BaseURL + "/group/join/" + code,
+	})
+}
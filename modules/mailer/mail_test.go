@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage_Bytes(t *testing.T) {
+	m := &Message{
+		To:      []string{"john@example.com"},
+		Subject: "Neue Rechnung über 2.70 €",
+		Text:    "Hallo John,",
+		HTML:    "<p>Hallo John,</p>",
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(m.Bytes("info@example.com", time.Now())))
+	assert.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, m.Subject, subject)
+	assert.Equal(t, "john@example.com", msg.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for _, expected := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		part, err := r.NextPart()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expected.contentType, part.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(part)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.body, string(body))
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "2.70 €", formatAmount(270, "€"))
+	assert.Equal(t, "0.05 €", formatAmount(5, "€"))
+	assert.Equal(t, "-12.00", formatAmount(-1200, ""))
+}
+
+func TestRenderMail(t *testing.T) {
+	setting.AppWorkPath = "../.."
+
+	data := &inviteMailData{
+		Group:   "Group 2",
+		Inviter: "John Doe",
+		Code:    "ABCDEFGHIJKL",
+		JoinURL: "https://example.com/group/join/ABCDEFGHIJKL",
+	}
+
+	m, err := renderMail(mailGroupInvite, "DE", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe lädt dich in Group 2 ein", m.Subject)
+	assert.Contains(t, m.Text, "ABCDEFGHIJKL")
+	assert.Contains(t, m.HTML, `href="https://example.com/group/join/ABCDEFGHIJKL"`)
+
+	// Unknown locales fall back to English
+	m, err = renderMail(mailGroupInvite, "fr-FR", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe invites you to Group 2", m.Subject)
+}
@@ -0,0 +1,374 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/setting"
+)
+
+// Options configures the delivery of notifications.
+type Options struct {
+	// Workers is the number of notifications that are delivered concurrently.
+	Workers int
+	// BatchSize is the maximum number of devices per message.
+	BatchSize int
+	// MaxAttempts is the number of attempts before a notification is dead.
+	MaxAttempts int
+	// Backoff is the delay before the first retry. It's doubled
+	// for each further retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// PollInterval is the interval for checking the outbox.
+	PollInterval time.Duration
+}
+
+// DefaultOptions are used for all options that aren't set.
+var DefaultOptions = Options{
+	Workers:      4,
+	BatchSize:    500, // FCM allows up to 1000 devices per message
+	MaxAttempts:  8,
+	Backoff:      10 * time.Second,
+	MaxBackoff:   time.Hour,
+	PollInterval: 5 * time.Second,
+}
+
+const (
+	// claimDuration is the time a worker has to deliver a notification
+	// before it is delivered again.
+	claimDuration = 2 * time.Minute
+	// sendTimeout is the timeout for sending one message.
+	sendTimeout = 30 * time.Second
+	// dueLimit is the maximum number of notifications per pass.
+	dueLimit = 100
+	// maxHeld is the maximum number of push updates that are kept in
+	// memory while the outbox can't be written.
+	maxHeld = 1000
+)
+
+var errPartialDelivery = errors.New("delivery failed for some devices")
+
+var (
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+	wake  = make(chan struct{}, 1)
+)
+
+// heldUpdate is a push update that couldn't be added to the outbox yet.
+type heldUpdate struct {
+	typ       string
+	data      []string
+	receivers []string
+}
+
+var (
+	heldMutex sync.Mutex
+	held      []heldUpdate
+)
+
+// NewNotifier returns the notifier selected in the configuration.
+func NewNotifier() Notifier {
+	if setting.AppConfig.Auth.IgnoreFirebase || setting.AppConfig.Notification.Notifier == setting.NotifierLog {
+		return LogNotifier{}
+	}
+	return &FCMNotifier{App: setting.FireBaseApp}
+}
+
+// NewOptions returns the options of the configuration.
+func NewOptions() Options {
+	cfg := setting.AppConfig.Notification
+	return Options{
+		Workers:      cfg.Workers,
+		BatchSize:    cfg.BatchSize,
+		MaxAttempts:  cfg.MaxAttempts,
+		Backoff:      cfg.BackoffDuration,
+		MaxBackoff:   cfg.MaxBackoffDuration,
+		PollInterval: cfg.PollIntervalDuration,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = DefaultOptions.Workers
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultOptions.BatchSize
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultOptions.MaxAttempts
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultOptions.Backoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultOptions.MaxBackoff
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultOptions.PollInterval
+	}
+	return o
+}
+
+// Enqueue adds a push update for the users to the outbox. If the outbox
+// can't be written, the update is kept in memory and added to the outbox
+// before the next delivery.
+func Enqueue(t string, data, receivers []string) {
+	if len(receivers) == 0 {
+		return
+	}
+	if _, err := models.CreateNotification(t, data, receivers); err != nil {
+		notifyLog.Errorf(`Can't add push update "%s" to the outbox: %s`, t, err)
+		hold(t, data, receivers)
+	}
+	Wake()
+}
+
+// hold keeps a push update in memory until it's added to the outbox.
+func hold(t string, data, receivers []string) {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+
+	if len(held) >= maxHeld {
+		notifyLog.Errorf(`Dropping push update "%s": too many updates are waiting for the outbox`, t)
+		return
+	}
+	held = append(held, heldUpdate{typ: t, data: data, receivers: receivers})
+}
+
+// flushHeld adds the held push updates to the outbox, oldest first.
+func flushHeld() error {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+
+	for len(held) > 0 {
+		h := held[0]
+		if _, err := models.CreateNotification(h.typ, h.data, h.receivers); err != nil {
+			return err
+		}
+		held = held[1:]
+	}
+	held = nil
+	return nil
+}
+
+// Wake lets the workers check the outbox immediately.
+func Wake() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start delivers the notifications of the outbox in the background
+// until Stop is called. Calling Start twice has no effect.
+func Start(n Notifier, opts Options) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if stop != nil {
+		return
+	}
+
+	opts = opts.withDefaults()
+	stop = make(chan struct{})
+	done = make(chan struct{})
+
+	go run(n, opts, stop, done)
+
+	notifyLog.Infof("Notification workers started (%d workers)", opts.Workers)
+}
+
+// Stop stops the workers and waits until running deliveries have finished.
+func Stop() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	stop, done = nil, nil
+
+	notifyLog.Info("Notification workers stopped")
+}
+
+func run(n Notifier, opts Options, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		count, err := ProcessDue(n, opts, time.Now().UTC())
+		if err != nil {
+			notifyLog.Error("Error delivering notifications: ", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		// There might be more due notifications
+		if count == dueLimit {
+			continue
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// ProcessDue delivers the due notifications of the outbox using
+// "opts.Workers" workers. It returns the number of processed notifications.
+func ProcessDue(n Notifier, opts Options, now time.Time) (int, error) {
+	opts = opts.withDefaults()
+
+	if err := flushHeld(); err != nil {
+		return 0, err
+	}
+
+	due, err := models.GetDueNotifications(now, dueLimit)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+
+	var (
+		jobs      = make(chan *models.Notification)
+		wg        sync.WaitGroup
+		processed int32
+	)
+
+	for i := 0; i < opts.Workers && i < len(due); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for nt := range jobs {
+				if ok, err := nt.Claim(now, now.Add(claimDuration)); err != nil {
+					notifyLog.Errorf("Can't claim notification %d: %s", nt.ID, err)
+					continue
+				} else if !ok {
+					continue
+				}
+				deliver(n, opts, nt, now)
+				atomic.AddInt32(&processed, 1)
+			}
+		}()
+	}
+
+	for _, nt := range due {
+		jobs <- nt
+	}
+	close(jobs)
+	wg.Wait()
+
+	return int(processed), nil
+}
+
+// deliver sends the notification to the devices of its receivers in batches.
+// Unregistered devices are removed from the users, failed receivers are retried
+// with exponential backoff until the notification is dead.
+func deliver(n Notifier, opts Options, nt *models.Notification, now time.Time) {
+	users, err := models.GetUsersByUIDs(nt.Receivers)
+	if err != nil {
+		retry(opts, nt, nt.Receivers, err, now)
+		return
+	}
+
+	// Several users may use the same device
+	owners := make(map[string][]string, len(users))
+	tokens := make([]string, 0, len(users))
+	for _, u := range users {
+		if u.FirebaseInstanceID == "" {
+			notifyLog.Debugf(`Empty FirebaseInstanceID for user "%s"`, *u.UID)
+			continue
+		}
+		if _, ok := owners[u.FirebaseInstanceID]; !ok {
+			tokens = append(tokens, u.FirebaseInstanceID)
+		}
+		owners[u.FirebaseInstanceID] = append(owners[u.FirebaseInstanceID], *u.UID)
+	}
+
+	var (
+		unregistered []string
+		failed       []string
+		lastErr      error
+	)
+	for start := 0; start < len(tokens); start += opts.BatchSize {
+		end := start + opts.BatchSize
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		batch := tokens[start:end]
+
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		result, err := n.Send(ctx, &Message{Type: nt.Type, Data: nt.Data, Tokens: batch})
+		cancel()
+
+		if err != nil {
+			lastErr = err
+			for _, t := range batch {
+				failed = append(failed, owners[t]...)
+			}
+			continue
+		}
+
+		unregistered = append(unregistered, result.Unregistered...)
+		if len(result.Failed) > 0 {
+			lastErr = errPartialDelivery
+			for _, t := range result.Failed {
+				failed = append(failed, owners[t]...)
+			}
+		}
+	}
+
+	if len(unregistered) > 0 {
+		notifyLog.Infof("Removing %d unregistered devices", len(unregistered))
+		if err := models.ClearFirebaseInstanceIDs(unregistered); err != nil {
+			notifyLog.Error("Can't remove unregistered devices: ", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		retry(opts, nt, failed, lastErr, now)
+	} else if err := nt.MarkSent(); err != nil {
+		notifyLog.Errorf("Can't mark notification %d as sent: %s", nt.ID, err)
+	}
+}
+
+func retry(opts Options, nt *models.Notification, receivers []string, cause error, now time.Time) {
+	var err error
+	if nt.Attempts+1 >= opts.MaxAttempts {
+		notifyLog.Warningf("Giving up notification %d after %d attempts: %s", nt.ID, nt.Attempts+1, cause)
+		err = nt.MarkDead(receivers, cause)
+	} else {
+		err = nt.Retry(receivers, cause, now.Add(backoff(opts, nt.Attempts)))
+	}
+	if err != nil {
+		notifyLog.Errorf("Can't update notification %d: %s", nt.ID, err)
+	}
+}
+
+// backoff returns the delay before the next attempt.
+func backoff(opts Options, attempts int) time.Duration {
+	d := opts.Backoff
+	for i := 0; i < attempts && d < opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > opts.MaxBackoff {
+		d = opts.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	opts := Options{Backoff: 10 * time.Second, MaxBackoff: time.Minute}
+
+	assert.Equal(t, 10*time.Second, backoff(opts, 0))
+	assert.Equal(t, 20*time.Second, backoff(opts, 1))
+	assert.Equal(t, 40*time.Second, backoff(opts, 2))
+	assert.Equal(t, time.Minute, backoff(opts, 3))
+	assert.Equal(t, time.Minute, backoff(opts, 100))
+}
+
+func TestOptions_WithDefaults(t *testing.T) {
+	assert.Equal(t, DefaultOptions, Options{}.withDefaults())
+
+	opts := Options{Workers: 1, MaxAttempts: 2}.withDefaults()
+	assert.Equal(t, 1, opts.Workers)
+	assert.Equal(t, 2, opts.MaxAttempts)
+	assert.Equal(t, DefaultOptions.BatchSize, opts.BatchSize)
+}
+
+func TestHold(t *testing.T) {
+	defer func() { held = nil }()
+
+	for i := 0; i <= maxHeld; i++ {
+		hold("User-Data", []string{"a"}, []string{"a"})
+	}
+	assert.Len(t, held, maxHeld)
+}
@@ -0,0 +1,120 @@
+// Package notification delivers push updates from the outbox to the
+// users' devices.
+package notification
+
+import (
+	"context"
+	"sync"
+
+	"github.com/acoshift/go-firebase-admin"
+	"github.com/op/go-logging"
+)
+
+var notifyLog = logging.MustGetLogger("Notification")
+
+// Message is a push update for a batch of devices.
+type Message struct {
+	Type string
+	Data []string
+	// Tokens are the firebase instance ids of the devices.
+	Tokens []string
+}
+
+// Result describes the outcome of a delivery per device.
+type Result struct {
+	// Unregistered tokens are permanently invalid and must not be used again.
+	Unregistered []string
+	// Failed tokens may be retried.
+	Failed []string
+}
+
+// Notifier delivers messages to devices. An error means that the whole
+// message failed and may be retried.
+type Notifier interface {
+	Send(ctx context.Context, msg *Message) (*Result, error)
+}
+
+// pushUpdateData is the payload of the data messages the clients expect.
+type pushUpdateData struct {
+	Type    string
+	Updated []string
+}
+
+// FCMNotifier sends messages using Firebase Cloud Messaging.
+type FCMNotifier struct {
+	App *firebase.App
+}
+
+// Send implements Notifier.
+func (n *FCMNotifier) Send(ctx context.Context, msg *Message) (*Result, error) {
+	resp, err := n.App.FCM().SendToDevices(ctx, msg.Tokens, firebase.Message{
+		Data: pushUpdateData{
+			Type:    msg.Type,
+			Updated: msg.Data,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for i, r := range resp.Results {
+		if i >= len(msg.Tokens) || r.Error == nil {
+			continue
+		}
+		switch r.Error {
+		case firebase.ErrNotRegistered, firebase.ErrInvalidRegistration, firebase.ErrMismatchSenderID:
+			result.Unregistered = append(result.Unregistered, msg.Tokens[i])
+		default:
+			notifyLog.Debugf("FCM error for device: %s", r.Error)
+			result.Failed = append(result.Failed, msg.Tokens[i])
+		}
+	}
+	return result, nil
+}
+
+// LogNotifier only logs messages, e.g. if firebase is disabled.
+type LogNotifier struct{}
+
+// Send implements Notifier.
+func (LogNotifier) Send(_ context.Context, msg *Message) (*Result, error) {
+	notifyLog.Infof(`Push update "%s" %v to %d devices`, msg.Type, msg.Data, len(msg.Tokens))
+	return &Result{}, nil
+}
+
+// MemoryNotifier records all messages. It's meant for tests.
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []*Message
+
+	// Unregistered tokens are reported as unregistered.
+	Unregistered map[string]bool
+	// Err is returned for every message if set.
+	Err error
+}
+
+// Send implements Notifier.
+func (n *MemoryNotifier) Send(_ context.Context, msg *Message) (*Result, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.Err != nil {
+		return nil, n.Err
+	}
+
+	result := &Result{}
+	for _, t := range msg.Tokens {
+		if n.Unregistered[t] {
+			result.Unregistered = append(result.Unregistered, t)
+		}
+	}
+	n.messages = append(n.messages, msg)
+	return result, nil
+}
+
+// Messages returns all sent messages.
+func (n *MemoryNotifier) Messages() []*Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Message{}, n.messages...)
+}
@@ -0,0 +1,176 @@
+// Package ratelimit throttles clients with token buckets and locks out
+// clients after repeated failures (e.g. guessing group codes).
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle entries are removed.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket rate limiter with one bucket per key.
+// Each bucket holds up to "burst" tokens and is refilled with "perMinute"
+// tokens per minute. Every request takes one token.
+type Limiter struct {
+	mu        sync.Mutex
+	rate      float64 // tokens per second
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+// NewLimiter creates a limiter that allows "perMinute" requests per minute
+// and key with bursts of up to "burst" requests.
+func NewLimiter(perMinute, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token of the key's bucket. If the bucket is empty, it
+// returns false and the time until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, sweepInterval
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep removes buckets that are full again, so that the map doesn't grow
+// with every client that was ever seen.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// Lockout locks keys out for a while after "maxFailures" failures. Failures
+// are forgotten if a key had no failure for the lockout duration.
+type Lockout struct {
+	mu          sync.Mutex
+	maxFailures int
+	duration    time.Duration
+	entries     map[string]*failures
+	lastSweep   time.Time
+
+	now func() time.Time
+}
+
+// NewLockout creates a lockout that locks keys out for "duration" after
+// "maxFailures" failures.
+func NewLockout(maxFailures int, duration time.Duration) *Lockout {
+	return &Lockout{
+		maxFailures: maxFailures,
+		duration:    duration,
+		entries:     make(map[string]*failures),
+		now:         time.Now,
+	}
+}
+
+// Locked returns true and the remaining time if the key is locked out.
+func (l *Lockout) Locked(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	if f, ok := l.entries[key]; ok && now.Before(f.lockedUntil) {
+		return true, f.lockedUntil.Sub(now)
+	}
+	return false, 0
+}
+
+// Fail records a failure of the key. It returns true if the key is
+// locked out now.
+func (l *Lockout) Fail(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	f, ok := l.entries[key]
+	if !ok || now.Sub(f.last) >= l.duration {
+		f = &failures{}
+		l.entries[key] = f
+	}
+	f.count++
+	f.last = now
+
+	if f.count >= l.maxFailures {
+		f.count = 0
+		f.lockedUntil = now.Add(l.duration)
+		return true
+	}
+	return false
+}
+
+// Reset forgets the failures of the key, e.g. after a success.
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if f, ok := l.entries[key]; ok && !l.now().Before(f.lockedUntil) {
+		delete(l.entries, key)
+	}
+}
+
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, f := range l.entries {
+		if now.Sub(f.last) >= l.duration && !now.Before(f.lockedUntil) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter(60, 2)
+	l.now = clock.Now
+
+	ok, _ := l.Allow("user1")
+	assert.True(t, ok)
+	ok, _ = l.Allow("user1")
+	assert.True(t, ok)
+
+	// The burst is used up
+	ok, retryAfter := l.Allow("user1")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// Other keys have their own bucket
+	ok, _ = l.Allow("user2")
+	assert.True(t, ok)
+
+	// One token per second is refilled
+	clock.Add(time.Second)
+	ok, _ = l.Allow("user1")
+	assert.True(t, ok)
+	ok, _ = l.Allow("user1")
+	assert.False(t, ok)
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter(60, 2)
+	l.now = clock.Now
+
+	l.Allow("user1")
+	clock.Add(2 * sweepInterval)
+	l.Allow("user2")
+
+	// The bucket of user1 is full again and was removed
+	assert.Len(t, l.buckets, 1)
+	assert.Contains(t, l.buckets, "user2")
+}
+
+func TestLockout(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLockout(3, 10*time.Minute)
+	l.now = clock.Now
+
+	assert.False(t, l.Fail("ip1"))
+	assert.False(t, l.Fail("ip1"))
+	locked, _ := l.Locked("ip1")
+	assert.False(t, locked)
+
+	assert.True(t, l.Fail("ip1"))
+	locked, retryAfter := l.Locked("ip1")
+	assert.True(t, locked)
+	assert.Equal(t, 10*time.Minute, retryAfter)
+
+	// A success doesn't lift the lockout
+	l.Reset("ip1")
+	locked, _ = l.Locked("ip1")
+	assert.True(t, locked)
+
+	clock.Add(10 * time.Minute)
+	locked, _ = l.Locked("ip1")
+	assert.False(t, locked)
+}
+
+func TestLockout_Reset(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLockout(2, time.Minute)
+	l.now = clock.Now
+
+	assert.False(t, l.Fail("ip1"))
+	l.Reset("ip1")
+	assert.False(t, l.Fail("ip1"))
+
+	// Old failures are forgotten
+	clock.Add(time.Minute)
+	assert.False(t, l.Fail("ip1"))
+	assert.True(t, l.Fail("ip1"))
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/models"
+	"github.com/wgplaner/wg_planer_server/modules/mailer"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/op/go-logging"
+)
+
+var schedLog = logging.MustGetLogger("Scheduler")
+
+// sentNotificationRetention is the time delivered notifications are kept in the outbox.
+const sentNotificationRetention = 7 * 24 * time.Hour
+
+var (
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+)
+
+// Start runs AddDueListItems, purges deleted groups, cleans up the
+// notification outbox and expired idempotency keys and sends payment
+// reminders and group digests every "interval" in the background until
+// Stop is called.
+// Calling Start twice has no effect.
+func Start(interval time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if stop != nil {
+		return
+	}
+
+	stop = make(chan struct{})
+	done = make(chan struct{})
+
+	go run(interval, stop, done)
+
+	schedLog.Infof("Scheduler started (interval: %s)", interval)
+}
+
+// Stop stops the scheduler and waits until a running check has finished.
+func Stop() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	stop, done = nil, nil
+
+	schedLog.Info("Scheduler stopped")
+}
+
+func run(interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := AddDueListItems(time.Now().UTC()); err != nil {
+			schedLog.Error("Error adding items of recurring templates: ", err)
+		}
+		if n, err := models.PurgeDeletedGroups(time.Now().UTC()); err != nil {
+			schedLog.Error("Error purging deleted groups: ", err)
+		} else if n > 0 {
+			schedLog.Infof("Purged %d deleted groups", n)
+		}
+		if n, err := models.DeleteSentNotifications(time.Now().UTC().Add(-sentNotificationRetention)); err != nil {
+			schedLog.Error("Error deleting sent notifications: ", err)
+		} else if n > 0 {
+			schedLog.Debugf("Deleted %d sent notifications", n)
+		}
+		if n, err := models.DeleteExpiredIdempotencyKeys(time.Now().UTC().Add(-models.IdempotencyKeyTTL)); err != nil {
+			schedLog.Error("Error deleting expired idempotency keys: ", err)
+		} else if n > 0 {
+			schedLog.Debugf("Deleted %d expired idempotency keys", n)
+		}
+		if n, err := mailer.SendPaymentReminders(time.Now().UTC()); err != nil {
+			schedLog.Error("Error sending payment reminders: ", err)
+		} else if n > 0 {
+			schedLog.Infof("Sent payment reminders for %d bills", n)
+		}
+		if n, err := mailer.SendGroupDigests(time.Now().UTC()); err != nil {
+			schedLog.Error("Error sending group digests: ", err)
+		} else if n > 0 {
+			schedLog.Infof("Sent digests of %d groups", n)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AddDueListItems adds items of all due templates to the shopping lists
+// and notifies the group members. It returns the new items.
+func AddDueListItems(now time.Time) ([]*models.ListItem, error) {
+	templates, err := models.GetDueListItemTemplates(now)
+	if err != nil {
+		return nil, err
+	}
+
+	added := make([]*models.ListItem, 0, len(templates))
+	addedByGroup := make(map[strfmt.UUID][]string)
+
+	for _, t := range templates {
+		item, err := t.AddDueListItem(now)
+		if err != nil {
+			schedLog.Errorf(`Can't add item of template "%s": %s`, t.ID, err)
+			continue
+		}
+		if item == nil {
+			schedLog.Debugf(`Skip template "%s": there is still an unbought item`, t.ID)
+			continue
+		}
+
+		added = append(added, item)
+		addedByGroup[item.GroupUID] = append(addedByGroup[item.GroupUID], string(item.ID))
+	}
+
+	for guid, itemIDs := range addedByGroup {
+		members, err := models.GetGroupMemberUIDs(guid)
+		if err != nil {
+			schedLog.Errorf(`Can't get members of group "%s": %s`, guid, err)
+			continue
+		}
+		mailer.SendPushUpdateToUserIDs(members, mailer.PushShoppingListAdd, itemIDs)
+	}
+
+	return added, nil
+}
@@ -0,0 +1,40 @@
+package setting
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// MysqlDataSource returns the DSN for github.com/go-sql-driver/mysql.
+func (c *databaseConfig) MysqlDataSource() string {
+	params := url.Values{
+		"charset":   {"utf8mb4"},
+		"parseTime": {"true"},
+	}
+	if c.MysqlTLS != "" {
+		params.Set("tls", c.MysqlTLS)
+	}
+
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?%s",
+		c.MysqlUser, c.MysqlPassword,
+		net.JoinHostPort(c.MysqlServer, strconv.Itoa(c.MysqlPort)),
+		c.MysqlDatabaseName, params.Encode())
+}
+
+// PostgresDataSource returns the connection URL for github.com/lib/pq.
+func (c *databaseConfig) PostgresDataSource() string {
+	params := url.Values{}
+	if c.PostgresSSLMode != "" {
+		params.Set("sslmode", c.PostgresSSLMode)
+	}
+
+	return (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.PostgresUser, c.PostgresPassword),
+		Host:     net.JoinHostPort(c.PostgresHost, strconv.Itoa(c.PostgresPort)),
+		Path:     "/" + c.PostgresDBName,
+		RawQuery: params.Encode(),
+	}).String()
+}
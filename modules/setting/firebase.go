@@ -3,33 +3,83 @@ package setting
 import (
 	"context"
 	"log"
+	"net/http"
+	"os"
 	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/wgplaner/wg_planer_server/modules/auth"
 
 	"github.com/acoshift/go-firebase-admin"
-	"github.com/wgplaner/wg_planer_server/modules/base"
 	"google.golang.org/api/option"
 )
 
 var FireBaseApp *firebase.App
 
 func CreateFirebaseConnection() *firebase.App {
-	var (
-		fireBaseApp *firebase.App
-		err         error
-	)
+	fireBaseApp, err := NewFirebaseApp()
+	if err != nil {
+		log.Fatalln("[Firebase] Creation using key failed: ", err)
+		return nil
+	}
+	return fireBaseApp
+}
 
+// NewFirebaseApp creates a firebase app using "config/serviceAccountKey.json".
+func NewFirebaseApp() (*firebase.App, error) {
 	keyfilePath := path.Join(AppWorkPath, "config/serviceAccountKey.json")
-	base.FileMustExist(keyfilePath)
+	if _, err := os.Stat(keyfilePath); err != nil {
+		return nil, err
+	}
 
-	fireBaseApp, err = firebase.InitializeApp(context.Background(), firebase.AppOptions{
+	return firebase.InitializeApp(context.Background(), firebase.AppOptions{
 		ProjectID: AppConfig.Auth.FirebaseProjectID,
 		APIKey:    AppConfig.Auth.FirebaseServerKey,
 	}, option.WithCredentialsFile(keyfilePath))
+}
+
+var TokenVerifier *auth.Verifier
+
+// jwksTimeout is the timeout for fetching the JWKS.
+const jwksTimeout = 10 * time.Second
 
+// CreateTokenVerifier loads the JWKS and returns a verifier for Firebase ID tokens.
+// The keys are refreshed when they expire or a token uses an unknown key id.
+// Returns nil if raw user ids are accepted instead of ID tokens.
+func CreateTokenVerifier() *auth.Verifier {
+	verifier, err := NewTokenVerifier()
 	if err != nil {
-		log.Fatalln("[Firebase] Creation using key failed")
+		log.Fatalln("[Firebase] Loading JWKS failed: ", err)
 		return nil
 	}
+	return verifier
+}
 
-	return fireBaseApp
+// NewTokenVerifier is like CreateTokenVerifier but returns an error instead of exiting.
+func NewTokenVerifier() (*auth.Verifier, error) {
+	if AppConfig.Auth.InsecureRawUID {
+		return nil, nil
+	}
+
+	var fetch auth.KeySetFetcher
+	if AppConfig.Auth.JWKSURL != "" {
+		fetch = auth.FetchKeySetURL(&http.Client{Timeout: jwksTimeout}, AppConfig.Auth.JWKSURL)
+	} else {
+		keyfilePath := AppConfig.Auth.JWKSFile
+		if !filepath.IsAbs(keyfilePath) {
+			keyfilePath = path.Join(AppWorkPath, keyfilePath)
+		}
+		fetch = auth.FetchKeySetFile(keyfilePath)
+	}
+
+	keys, err := auth.NewCachedKeySet(fetch)
+	if err != nil {
+		return nil, err
+	}
+	keys.ErrorLog = func(err error) {
+		settingLog.Error("[Firebase] Refreshing JWKS failed: ", err)
+	}
+
+	return auth.NewVerifier(keys, AppConfig.Auth.FirebaseProjectID), nil
 }
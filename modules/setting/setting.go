@@ -22,8 +22,19 @@ import (
 var settingLog = logging.MustGetLogger("Config")
 
 const (
-	DriverSQLite = "sqlite"
-	DriverMySQL  = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+)
+
+// Rounding modes for remainder cents when splitting an item's price.
+const (
+	// RoundingPayer lets the buyer of an item pay the remainder cents.
+	RoundingPayer = "payer"
+	// RoundingFirst assigns the remainder cents to the first users in "requestedFor".
+	RoundingFirst = "first"
+	// RoundingSpread rotates the users that get the remainder cents from item to item.
+	RoundingSpread = "spread"
 )
 
 type serverConfig struct {
@@ -34,6 +45,13 @@ type authConfig struct {
 	IgnoreFirebase    bool   `toml:"ignore_firebase"`
 	FirebaseProjectID string `toml:"firebase_project_id"`
 	FirebaseServerKey string `toml:"firebase_server_key"`
+	// JWKSFile contains the public keys used to verify Firebase ID tokens.
+	JWKSFile string `toml:"jwks_file"`
+	// JWKSURL is used instead of JWKSFile if set.
+	JWKSURL string `toml:"jwks_url"`
+	// InsecureRawUID accepts the raw user id as "Authorization" header.
+	// Never enable this in production!
+	InsecureRawUID bool `toml:"insecure_raw_uid"`
 }
 
 type dataConfig struct {
@@ -46,12 +64,38 @@ type dataConfig struct {
 type databaseConfig struct {
 	Driver            string
 	LogSQL            bool   `toml:"log_sql"`
+	AutoMigrate       bool   `toml:"auto_migrate"`
 	SqliteFile        string `toml:"sqlite_file"`
 	MysqlServer       string `toml:"mysql_server"`
 	MysqlPort         int    `toml:"mysql_port"`
 	MysqlUser         string `toml:"mysql_user"`
 	MysqlPassword     string `toml:"mysql_password"`
 	MysqlDatabaseName string `toml:"mysql_db_name"`
+	// MysqlTLS is one of "false", "true", "skip-verify" or "preferred".
+	MysqlTLS string `toml:"mysql_tls"`
+
+	PostgresHost     string `toml:"postgres_host"`
+	PostgresPort     int    `toml:"postgres_port"`
+	PostgresUser     string `toml:"postgres_user"`
+	PostgresPassword string `toml:"postgres_password"`
+	PostgresDBName   string `toml:"postgres_db_name"`
+	// PostgresSSLMode is one of "disable", "require", "verify-ca" or "verify-full".
+	PostgresSSLMode string `toml:"postgres_sslmode"`
+
+	// MaxOpenConns limits the open connections (0 is unlimited).
+	MaxOpenConns int `toml:"max_open_conns"`
+	// MaxIdleConns limits the idle connections (0 keeps the default of 2).
+	MaxIdleConns int `toml:"max_idle_conns"`
+	// ConnMaxLifetime is a duration string like "1h" (0 reuses connections forever).
+	ConnMaxLifetime string `toml:"conn_max_lifetime"`
+	// ConnMaxLifetimeDuration is the parsed ConnMaxLifetime.
+	ConnMaxLifetimeDuration time.Duration `toml:"-"`
+	// ConnectRetries is the number of retries if the database isn't reachable at startup.
+	ConnectRetries int `toml:"connect_retries"`
+	// ConnectBackoff is the duration to wait before the first retry. It's doubled for each retry.
+	ConnectBackoff string `toml:"connect_backoff"`
+	// ConnectBackoffDuration is the parsed ConnectBackoff.
+	ConnectBackoffDuration time.Duration `toml:"-"`
 }
 
 type mailConfig struct {
@@ -61,14 +105,117 @@ type mailConfig struct {
 	SMTPIdentity string `toml:"smtp_identity"`
 	SMTPUser     string `toml:"smtp_user"`
 	SMTPPassword string `toml:"smtp_password"`
+
+	// Enabled sends email notifications using the SMTP server.
+	Enabled bool `toml:"enabled"`
+	// BaseURL is the public URL of the server that is used for links in mails.
+	BaseURL string `toml:"base_url"`
+	// QueueSize is the number of mails that can wait for delivery.
+	QueueSize int `toml:"queue_size"`
+	// ReminderBefore is a duration string like "24h". Recipients of a bill
+	// that haven't paid yet are reminded this long before its due date.
+	ReminderBefore string `toml:"reminder_before"`
+	// DigestInterval is a duration string like "168h" for the group digest.
+	// "0" disables the digest.
+	DigestInterval string `toml:"digest_interval"`
+
+	// Parsed durations
+	ReminderBeforeDuration time.Duration `toml:"-"`
+	DigestIntervalDuration time.Duration `toml:"-"`
+}
+
+type ledgerConfig struct {
+	Rounding string `toml:"rounding"`
+}
+
+type schedulerConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Interval is a duration string like "1m" or "30s".
+	Interval string `toml:"interval"`
+	// IntervalDuration is the parsed Interval.
+	IntervalDuration time.Duration `toml:"-"`
+}
+
+type groupConfig struct {
+	// DeletionGracePeriod is a duration string like "72h". Deleted groups
+	// can be restored during this period. Empty or "0" deletes immediately.
+	DeletionGracePeriod string `toml:"deletion_grace_period"`
+	// DeletionGracePeriodDuration is the parsed DeletionGracePeriod.
+	DeletionGracePeriodDuration time.Duration `toml:"-"`
+}
+
+type eventsConfig struct {
+	// BufferSize is the number of events that are kept for resuming event streams.
+	BufferSize int `toml:"buffer_size"`
+	// KeepAlive is a duration string like "30s". Idle event streams
+	// receive a comment after this duration.
+	KeepAlive string `toml:"keepalive"`
+	// KeepAliveDuration is the parsed KeepAlive.
+	KeepAliveDuration time.Duration `toml:"-"`
+}
+
+// Notifiers for push updates
+const (
+	// NotifierFCM sends push updates using Firebase Cloud Messaging.
+	NotifierFCM = "fcm"
+	// NotifierLog only logs push updates.
+	NotifierLog = "log"
+)
+
+type notificationConfig struct {
+	// Notifier is one of "fcm" or "log". "log" is used if firebase is ignored.
+	Notifier string `toml:"notifier"`
+	// Workers is the number of notifications delivered concurrently.
+	Workers int `toml:"workers"`
+	// BatchSize is the maximum number of devices per message (at most 1000).
+	BatchSize int `toml:"batch_size"`
+	// MaxAttempts is the number of delivery attempts before a notification is given up.
+	MaxAttempts int `toml:"max_attempts"`
+	// Backoff is a duration string like "10s" before the first retry. It's doubled
+	// for each further retry up to MaxBackoff.
+	Backoff    string `toml:"backoff"`
+	MaxBackoff string `toml:"max_backoff"`
+	// PollInterval is a duration string like "5s" for checking the outbox.
+	PollInterval string `toml:"poll_interval"`
+
+	// Parsed durations
+	BackoffDuration      time.Duration `toml:"-"`
+	MaxBackoffDuration   time.Duration `toml:"-"`
+	PollIntervalDuration time.Duration `toml:"-"`
+}
+
+type rateLimitConfig struct {
+	Enabled bool `toml:"enabled"`
+	// RequestsPerMinute and Burst limit the requests of each user
+	// (or client IP for requests without a valid ID token).
+	RequestsPerMinute int `toml:"requests_per_minute"`
+	Burst             int `toml:"burst"`
+	// JoinPerMinute and JoinBurst limit attempts to join a group with a code.
+	JoinPerMinute int `toml:"join_per_minute"`
+	JoinBurst     int `toml:"join_burst"`
+	// JoinMaxFailures invalid codes lock the user and client IP out for JoinLockout.
+	JoinMaxFailures int    `toml:"join_max_failures"`
+	JoinLockout     string `toml:"join_lockout"`
+	// TrustProxyHeaders takes the client IP from "X-Forwarded-For".
+	// Only enable it behind a reverse proxy that sets the header.
+	TrustProxyHeaders bool `toml:"trust_proxy_headers"`
+
+	// JoinLockoutDuration is the parsed JoinLockout.
+	JoinLockoutDuration time.Duration `toml:"-"`
 }
 
 type appConfigType struct {
-	Server   serverConfig
-	Auth     authConfig
-	Data     dataConfig
-	Database databaseConfig
-	Mail     mailConfig
+	Server       serverConfig
+	Auth         authConfig
+	Data         dataConfig
+	Database     databaseConfig
+	Mail         mailConfig
+	Ledger       ledgerConfig
+	Scheduler    schedulerConfig
+	Group        groupConfig
+	Events       eventsConfig
+	Notification notificationConfig
+	RateLimit    rateLimitConfig `toml:"rate_limit"`
 }
 
 var (
@@ -96,13 +243,21 @@ func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
 }
 
-func NewConfigContext() {
-	AppConfig = &appConfigType{}
+// ConfigPath returns the path of the configuration file.
+func ConfigPath() string {
+	// Path is relative to executable.
+	return path.Join(AppWorkPath, "config/config.toml")
+}
 
-	configPath := path.Join(AppWorkPath, "config/config.toml")
+// LoadConfig loads the configuration file into AppConfig without validating it.
+func LoadConfig() error {
+	AppConfig = &appConfigType{}
+	_, err := toml.DecodeFile(ConfigPath(), AppConfig)
+	return err
+}
 
-	// Path is relative to executable.
-	if _, err := toml.DecodeFile(configPath, AppConfig); err != nil {
+func NewConfigContext() {
+	if err := LoadConfig(); err != nil {
 		settingLog.Fatal("Error loading configuration! ", err)
 		return
 	}
@@ -112,6 +267,7 @@ func NewConfigContext() {
 	settingLog.Info("Configuration successfully loaded!")
 
 	FireBaseApp = CreateFirebaseConnection()
+	TokenVerifier = CreateTokenVerifier()
 
 	if AppConfig.Mail.SendTestMail {
 		SendTestMail()
@@ -162,27 +318,51 @@ func getWorkPath(appPath string) string {
 	return strings.Replace(workPath, "\\", "/", -1)
 }
 
+var configValidators = []struct {
+	name     string
+	validate func() []string
+}{
+	{"server", validateServerConfig},
+	{"auth", validateAuthConfig},
+	{"data", validateDataConfig},
+	{"database", validateDriverConfig},
+	{"mail", validateMailConfig},
+	{"ledger", validateLedgerConfig},
+	{"scheduler", validateSchedulerConfig},
+	{"group", validateGroupConfig},
+	{"events", validateEventsConfig},
+	{"notification", validateNotificationConfig},
+	{"rate_limit", validateRateLimitConfig},
+}
+
+// CheckConfiguration validates the loaded configuration and returns all problems.
+func CheckConfiguration() []string {
+	var problems []string
+	for _, v := range configValidators {
+		if e := v.validate(); len(e) > 0 {
+			problems = append(problems, "[Config] Error with "+v.name+" config:\n"+strings.Join(e, "\n"))
+		}
+	}
+	return problems
+}
+
 func validateConfiguration() {
-	validateServerConfig()
-	validateAuthConfig()
-	validateDataConfig()
-	validateDriverConfig()
-	validateMailConfig()
+	if problems := CheckConfiguration(); len(problems) > 0 {
+		settingLog.Fatal(strings.Join(problems, "\n"))
+	}
 }
 
-func validateServerConfig() {
+func validateServerConfig() []string {
 	var e []string
 
 	if AppConfig.Server.Port < 80 {
 		e = append(e, "[Config] Port number is not valid (must be > 80)")
 	}
 
-	if len(e) > 0 {
-		settingLog.Fatal("[Config] Error with server config:\n" + strings.Join(e, "\n"))
-	}
+	return e
 }
 
-func validateDataConfig() {
+func validateDataConfig() []string {
 	var e []string
 
 	if AppConfig.Data.UserImageDir == "" {
@@ -201,12 +381,10 @@ func validateDataConfig() {
 		e = append(e, "[Config][Data] 'user_image_dir' is not a directory!")
 	}
 
-	if len(e) > 0 {
-		settingLog.Fatal("[Config] Error with data config:\n" + strings.Join(e, "\n"))
-	}
+	return e
 }
 
-func validateAuthConfig() {
+func validateAuthConfig() []string {
 	var e []string
 
 	if !AppConfig.Auth.IgnoreFirebase {
@@ -218,12 +396,21 @@ func validateAuthConfig() {
 		}
 	}
 
-	if len(e) > 0 {
-		settingLog.Fatal("[Config] Error with auth config:\n" + strings.Join(e, "\n"))
+	if AppConfig.Auth.InsecureRawUID {
+		settingLog.Warning("[Config] 'insecure_raw_uid' is enabled! Anyone who knows a user id can act as that user!")
+	} else {
+		if AppConfig.Auth.FirebaseProjectID == "" {
+			e = append(e, "[Config] Firebase Project ID is required to verify ID tokens")
+		}
+		if AppConfig.Auth.JWKSFile == "" && AppConfig.Auth.JWKSURL == "" {
+			e = append(e, "[Config] 'jwks_file' or 'jwks_url' is required to verify ID tokens")
+		}
 	}
+
+	return e
 }
 
-func validateDriverConfig() {
+func validateDriverConfig() []string {
 	var e []string
 
 	switch AppConfig.Database.Driver {
@@ -240,6 +427,34 @@ func validateDriverConfig() {
 		if AppConfig.Database.MysqlDatabaseName == "" {
 			e = append(e, "[Config][MySQL] Databasename is empty!")
 		}
+		switch AppConfig.Database.MysqlTLS {
+		case "":
+			AppConfig.Database.MysqlTLS = "false"
+		case "false", "true", "skip-verify", "preferred":
+		default:
+			e = append(e, "[Config][MySQL] 'mysql_tls' must be one of 'false', 'true', 'skip-verify' or 'preferred'!")
+		}
+
+	case DriverPostgres:
+		if AppConfig.Database.PostgresHost == "" {
+			e = append(e, "[Config][PostgreSQL] Host is empty!")
+		}
+		if AppConfig.Database.PostgresPort == 0 {
+			e = append(e, "[Config][PostgreSQL] Port is empty!")
+		}
+		if AppConfig.Database.PostgresUser == "" {
+			e = append(e, "[Config][PostgreSQL] User is empty!")
+		}
+		if AppConfig.Database.PostgresDBName == "" {
+			e = append(e, "[Config][PostgreSQL] Databasename is empty!")
+		}
+		switch AppConfig.Database.PostgresSSLMode {
+		case "":
+			AppConfig.Database.PostgresSSLMode = "disable"
+		case "disable", "require", "verify-ca", "verify-full":
+		default:
+			e = append(e, "[Config][PostgreSQL] 'postgres_sslmode' must be one of 'disable', 'require', 'verify-ca' or 'verify-full'!")
+		}
 
 	case DriverSQLite:
 		if AppConfig.Database.SqliteFile == "" {
@@ -250,19 +465,261 @@ func validateDriverConfig() {
 		e = append(e, "[Driver] Drivername is not valid!")
 	}
 
-	if len(e) > 0 {
-		settingLog.Fatal("[Config] Error with database config:\n" + strings.Join(e, "\n"))
+	if AppConfig.Database.MaxOpenConns < 0 {
+		e = append(e, "[Config][Database] 'max_open_conns' must not be negative!")
+	}
+	if AppConfig.Database.MaxIdleConns < 0 {
+		e = append(e, "[Config][Database] 'max_idle_conns' must not be negative!")
+	}
+	if AppConfig.Database.ConnectRetries < 0 {
+		e = append(e, "[Config][Database] 'connect_retries' must not be negative!")
+	}
+
+	if AppConfig.Database.ConnMaxLifetime == "" {
+		AppConfig.Database.ConnMaxLifetime = "0"
+	}
+	if d, err := time.ParseDuration(AppConfig.Database.ConnMaxLifetime); err != nil {
+		e = append(e, "[Config][Database] 'conn_max_lifetime' is not a valid duration! "+err.Error())
+	} else if d < 0 {
+		e = append(e, "[Config][Database] 'conn_max_lifetime' must not be negative!")
+	} else {
+		AppConfig.Database.ConnMaxLifetimeDuration = d
+	}
+
+	if AppConfig.Database.ConnectBackoff == "" {
+		AppConfig.Database.ConnectBackoff = "1s"
 	}
+	if d, err := time.ParseDuration(AppConfig.Database.ConnectBackoff); err != nil {
+		e = append(e, "[Config][Database] 'connect_backoff' is not a valid duration! "+err.Error())
+	} else if d <= 0 {
+		e = append(e, "[Config][Database] 'connect_backoff' must be positive!")
+	} else {
+		AppConfig.Database.ConnectBackoffDuration = d
+	}
+
+	return e
 }
 
-func validateMailConfig() {
+func validateMailConfig() []string {
 	var e []string
 
-	if !base.IntInSlice(AppConfig.Mail.SMTPPort, []int{25, 465, 587}) {
+	cfg := &AppConfig.Mail
+
+	if !base.IntInSlice(cfg.SMTPPort, []int{25, 465, 587}) {
 		mailLog.Warning("SMTP Port is not a default port!")
 	}
 
-	if len(e) > 0 {
-		settingLog.Fatal("[Config] Error with mail config:\n" + strings.Join(e, "\n"))
+	if cfg.Enabled {
+		if cfg.SMTPHost == "" {
+			e = append(e, "[Config][Mail] 'smtp_host' is required if mails are enabled!")
+		}
+		if cfg.SMTPIdentity == "" {
+			e = append(e, "[Config][Mail] 'smtp_identity' is required if mails are enabled!")
+		}
+	}
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.wgplaner.ameyering.de"
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 100
+	} else if cfg.QueueSize < 0 {
+		e = append(e, "[Config][Mail] 'queue_size' must not be negative!")
+	}
+
+	if cfg.ReminderBefore == "" {
+		cfg.ReminderBefore = "24h"
+	}
+
+	if d, err := time.ParseDuration(cfg.ReminderBefore); err != nil {
+		e = append(e, "[Config][Mail] 'reminder_before' is not a valid duration! "+err.Error())
+	} else if d <= 0 {
+		e = append(e, "[Config][Mail] 'reminder_before' must be positive!")
+	} else {
+		cfg.ReminderBeforeDuration = d
+	}
+
+	if cfg.DigestInterval == "" {
+		cfg.DigestInterval = "168h"
+	}
+
+	if d, err := time.ParseDuration(cfg.DigestInterval); err != nil {
+		e = append(e, "[Config][Mail] 'digest_interval' is not a valid duration! "+err.Error())
+	} else if d < 0 {
+		e = append(e, "[Config][Mail] 'digest_interval' must not be negative!")
+	} else {
+		cfg.DigestIntervalDuration = d
+	}
+
+	return e
+}
+
+func validateLedgerConfig() []string {
+	var e []string
+
+	switch AppConfig.Ledger.Rounding {
+	case "":
+		AppConfig.Ledger.Rounding = RoundingPayer
+	case RoundingPayer, RoundingFirst, RoundingSpread:
+	default:
+		e = append(e, "[Config][Ledger] 'rounding' must be one of 'payer', 'first' or 'spread'!")
+	}
+
+	return e
+}
+
+func validateSchedulerConfig() []string {
+	var e []string
+
+	if AppConfig.Scheduler.Interval == "" {
+		AppConfig.Scheduler.Interval = "1m"
+	}
+
+	if d, err := time.ParseDuration(AppConfig.Scheduler.Interval); err != nil {
+		e = append(e, "[Config][Scheduler] 'interval' is not a valid duration! "+err.Error())
+	} else if d < time.Second {
+		e = append(e, "[Config][Scheduler] 'interval' must be at least one second!")
+	} else {
+		AppConfig.Scheduler.IntervalDuration = d
+	}
+
+	return e
+}
+
+func validateGroupConfig() []string {
+	var e []string
+
+	if AppConfig.Group.DeletionGracePeriod == "" {
+		AppConfig.Group.DeletionGracePeriod = "0"
+	}
+
+	if d, err := time.ParseDuration(AppConfig.Group.DeletionGracePeriod); err != nil {
+		e = append(e, "[Config][Group] 'deletion_grace_period' is not a valid duration! "+err.Error())
+	} else if d < 0 {
+		e = append(e, "[Config][Group] 'deletion_grace_period' must not be negative!")
+	} else {
+		AppConfig.Group.DeletionGracePeriodDuration = d
+	}
+
+	return e
+}
+
+func validateEventsConfig() []string {
+	var e []string
+
+	if AppConfig.Events.BufferSize == 0 {
+		AppConfig.Events.BufferSize = 1000
+	} else if AppConfig.Events.BufferSize < 0 {
+		e = append(e, "[Config][Events] 'buffer_size' must not be negative!")
+	}
+
+	if AppConfig.Events.KeepAlive == "" {
+		AppConfig.Events.KeepAlive = "30s"
+	}
+
+	if d, err := time.ParseDuration(AppConfig.Events.KeepAlive); err != nil {
+		e = append(e, "[Config][Events] 'keepalive' is not a valid duration! "+err.Error())
+	} else if d < time.Second {
+		e = append(e, "[Config][Events] 'keepalive' must be at least one second!")
+	} else {
+		AppConfig.Events.KeepAliveDuration = d
+	}
+
+	return e
+}
+
+func validateNotificationConfig() []string {
+	var e []string
+	cfg := &AppConfig.Notification
+
+	switch cfg.Notifier {
+	case "":
+		cfg.Notifier = NotifierFCM
+	case NotifierFCM, NotifierLog:
+	default:
+		e = append(e, "[Config][Notification] 'notifier' must be one of 'fcm' or 'log'!")
+	}
+
+	if cfg.Workers == 0 {
+		cfg.Workers = 4
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.Workers < 0 {
+		e = append(e, "[Config][Notification] 'workers' must be positive!")
+	}
+	if cfg.BatchSize < 0 || cfg.BatchSize > 1000 {
+		e = append(e, "[Config][Notification] 'batch_size' must be between 1 and 1000!")
+	}
+	if cfg.MaxAttempts < 0 {
+		e = append(e, "[Config][Notification] 'max_attempts' must be positive!")
+	}
+
+	durations := []struct {
+		name     string
+		value    *string
+		def      string
+		duration *time.Duration
+	}{
+		{"backoff", &cfg.Backoff, "10s", &cfg.BackoffDuration},
+		{"max_backoff", &cfg.MaxBackoff, "1h", &cfg.MaxBackoffDuration},
+		{"poll_interval", &cfg.PollInterval, "5s", &cfg.PollIntervalDuration},
+	}
+	for _, d := range durations {
+		if *d.value == "" {
+			*d.value = d.def
+		}
+		if parsed, err := time.ParseDuration(*d.value); err != nil {
+			e = append(e, "[Config][Notification] '"+d.name+"' is not a valid duration! "+err.Error())
+		} else if parsed <= 0 {
+			e = append(e, "[Config][Notification] '"+d.name+"' must be positive!")
+		} else {
+			*d.duration = parsed
+		}
+	}
+
+	return e
+}
+
+func validateRateLimitConfig() []string {
+	var e []string
+	cfg := &AppConfig.RateLimit
+
+	limits := []struct {
+		name  string
+		value *int
+		def   int
+	}{
+		{"requests_per_minute", &cfg.RequestsPerMinute, 120},
+		{"burst", &cfg.Burst, 30},
+		{"join_per_minute", &cfg.JoinPerMinute, 5},
+		{"join_burst", &cfg.JoinBurst, 5},
+		{"join_max_failures", &cfg.JoinMaxFailures, 10},
 	}
+	for _, l := range limits {
+		if *l.value == 0 {
+			*l.value = l.def
+		} else if *l.value < 0 {
+			e = append(e, "[Config][RateLimit] '"+l.name+"' must be positive!")
+		}
+	}
+
+	if cfg.JoinLockout == "" {
+		cfg.JoinLockout = "15m"
+	}
+	if d, err := time.ParseDuration(cfg.JoinLockout); err != nil {
+		e = append(e, "[Config][RateLimit] 'join_lockout' is not a valid duration! "+err.Error())
+	} else if d <= 0 {
+		e = append(e, "[Config][RateLimit] 'join_lockout' must be positive!")
+	} else {
+		cfg.JoinLockoutDuration = d
+	}
+
+	return e
 }